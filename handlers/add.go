@@ -5,7 +5,10 @@ import (
 	"database/sql"
 	"fmt"
 	"net/http"
+	"strings"
 
+	"fknsrs.biz/p/sorm/qsorm"
+	sb "fknsrs.biz/p/sqlbuilder"
 	"github.com/monoculum/formam"
 
 	"fknsrs.biz/p/ytmusic/internal/ctxdb"
@@ -14,15 +17,94 @@ import (
 	"fknsrs.biz/p/ytmusic/internal/httputil"
 	"fknsrs.biz/p/ytmusic/internal/jobqueue"
 	"fknsrs.biz/p/ytmusic/internal/queuenames"
+	"fknsrs.biz/p/ytmusic/internal/ytdirect"
 	"fknsrs.biz/p/ytmusic/internal/ytutil"
+	"fknsrs.biz/p/ytmusic/models"
 )
 
+type addSearchResult struct {
+	ytdirect.SearchResult
+	InLibrary bool
+}
+
 func Add(rw http.ResponseWriter, r *http.Request) {
-	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_add", map[string]interface{}{}); err != nil {
+	query := r.URL.Query().Get("q")
+	filter := ytutil.IDType(r.URL.Query().Get("type"))
+	continuation := r.URL.Query().Get("continuation")
+
+	data := map[string]interface{}{
+		"SearchQuery": query,
+		"SearchType":  filter,
+	}
+
+	if query != "" || continuation != "" {
+		page, err := ytdirect.Search(r.Context(), query, filter, continuation)
+		if err != nil {
+			httputil.RedirectWithError(rw, r, "/add", "Could not search YouTube: "+err.Error())
+			return
+		}
+
+		results, err := markSearchResultsInLibrary(r.Context(), page.Results)
+		if err != nil {
+			panic(err)
+		}
+
+		data["SearchResults"] = results
+		data["SearchContinuation"] = page.Continuation
+		data["ReturnTo"] = r.URL.RequestURI()
+	}
+
+	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_add", data); err != nil {
 		panic(err)
 	}
 }
 
+func markSearchResultsInLibrary(ctx context.Context, results []ytdirect.SearchResult) ([]addSearchResult, error) {
+	idsByType := make(map[ytutil.IDType][]string)
+	for _, result := range results {
+		idsByType[result.Type] = append(idsByType[result.Type], result.ID)
+	}
+
+	inLibrary := make(map[string]bool)
+
+	if ids := idsByType[ytutil.ChannelID]; len(ids) > 0 {
+		var channels []models.Channel
+		if err := qsorm.FindWhere(ctx, ctxdb.GetDB(ctx), &channels, sb.In(models.ChannelTable.C("ExternalID"), sb.BindAllStringsAsExpr(ids...)...), nil, nil); err != nil {
+			return nil, err
+		}
+		for _, channel := range channels {
+			inLibrary[channel.ExternalID] = true
+		}
+	}
+
+	if ids := idsByType[ytutil.PlaylistID]; len(ids) > 0 {
+		var playlists []models.Playlist
+		if err := qsorm.FindWhere(ctx, ctxdb.GetDB(ctx), &playlists, sb.In(models.PlaylistTable.C("ExternalID"), sb.BindAllStringsAsExpr(ids...)...), nil, nil); err != nil {
+			return nil, err
+		}
+		for _, playlist := range playlists {
+			inLibrary[playlist.ExternalID] = true
+		}
+	}
+
+	if ids := idsByType[ytutil.VideoID]; len(ids) > 0 {
+		var videos []models.Video
+		if err := qsorm.FindWhere(ctx, ctxdb.GetDB(ctx), &videos, sb.In(models.VideoTable.C("ExternalID"), sb.BindAllStringsAsExpr(ids...)...), nil, nil); err != nil {
+			return nil, err
+		}
+		for _, video := range videos {
+			inLibrary[video.ExternalID] = true
+		}
+	}
+
+	var annotated []addSearchResult
+	for _, result := range results {
+		annotated = append(annotated, addSearchResult{SearchResult: result, InLibrary: inLibrary[result.ID]})
+	}
+
+	return annotated, nil
+}
+
 func AddAction(rw http.ResponseWriter, r *http.Request) {
 	if err := r.ParseForm(); err != nil {
 		panic(err)
@@ -30,16 +112,43 @@ func AddAction(rw http.ResponseWriter, r *http.Request) {
 
 	var input struct {
 		URLsOrIDs string `formam:"urls_or_ids"`
+		AddExtras bool   `formam:"add_extras"`
+		Type      string `formam:"type"`
+		ID        string `formam:"id"`
+		ReturnTo  string `formam:"return_to"`
 	}
 
 	if err := formam.Decode(r.PostForm, &input); err != nil {
 		panic(err)
 	}
 
-	ids, err := ytutil.ExtractAndIdentifyIDs(input.URLsOrIDs, false)
-	if err != nil {
-		httputil.RedirectWithError(rw, r, "/", "Could not extract IDs from input: "+err.Error())
-		return
+	var ids []ytutil.ID
+
+	// search results already know what they are, so they skip the guessing
+	// that free text needs; some real IDs look like words or aren't shaped
+	// like the usual ones
+	if input.ID != "" {
+		switch t := ytutil.IDType(input.Type); t {
+		case ytutil.ChannelID, ytutil.PlaylistID, ytutil.VideoID:
+			ids = append(ids, ytutil.ID{Type: t, Value: input.ID})
+		default:
+			httputil.RedirectWithError(rw, r, "/", fmt.Sprintf("Unrecognised ID type %q", input.Type))
+			return
+		}
+	} else {
+		found, err := ytutil.ExtractAndIdentifyIDs(input.URLsOrIDs, true)
+		if err != nil {
+			httputil.RedirectWithError(rw, r, "/", "Could not extract IDs from input: "+err.Error())
+			return
+		}
+
+		for _, id := range found {
+			if id.Extra && !input.AddExtras {
+				continue
+			}
+
+			ids = append(ids, id)
+		}
 	}
 
 	if len(ids) == 0 {
@@ -52,7 +161,7 @@ func AddAction(rw http.ResponseWriter, r *http.Request) {
 			var queueName string
 
 			switch id.Type {
-			case ytutil.ChannelID:
+			case ytutil.ChannelID, ytutil.ChannelRef:
 				queueName = queuenames.ChannelUpdateMetadata
 			case ytutil.PlaylistID:
 				queueName = queuenames.PlaylistUpdateMetadata
@@ -75,5 +184,12 @@ func AddAction(rw http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
-	httputil.RedirectWithSuccess(rw, r, "/add", fmt.Sprintf("%d items will be added or updated soon.", len(ids)))
+	// the one-click buttons on search results send people back to the same
+	// page of results
+	returnTo := "/add"
+	if strings.HasPrefix(input.ReturnTo, "/add?") {
+		returnTo = input.ReturnTo
+	}
+
+	httputil.RedirectWithSuccess(rw, r, returnTo, fmt.Sprintf("%d items will be added or updated soon.", len(ids)))
 }
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 
@@ -70,13 +71,55 @@ func Channel(rw http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
+	var record models.Channel
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &record, "where id = ?", channel.ChannelID); err != nil {
+		panic(err)
+	}
+
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_channel", map[string]interface{}{
-		"Channel":   channel,
-		"Playlists": playlists,
-		"Videos":    videos,
+		"Channel":         channel,
+		"Playlists":       playlists,
+		"Videos":          videos,
+		"Revisions":       findRevisions(r, models.RevisionObjectChannel, channel.ChannelExternalID),
+		"DownloadProfile": makeDownloadProfileForm(r, "/channels/"+channel.ChannelExternalID+"/download-profile", record.DownloadProfile, ""),
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func ChannelDownloadProfileAction(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var channel models.Channel
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &channel, "where external_id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	profile, err := parseDownloadProfile(r)
+	if err != nil {
+		httputil.RedirectWithError(rw, r, "/channels/"+channel.ExternalID, err.Error())
+		return
+	}
+
+	var queued int
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "update channels set download_profile = ? where id = ?", profile, channel.ID); err != nil {
+			return err
+		}
+
+		n, err := enqueueMissingDownloads(ctx, tx, "v.channel_external_id = ?", channel.ExternalID)
+		queued = n
+		return err
 	}); err != nil {
 		panic(err)
 	}
+
+	httputil.RedirectWithSuccess(rw, r, "/channels/"+channel.ExternalID, downloadProfileMessage(profile, queued))
 }
 
 func ChannelAudio(rw http.ResponseWriter, r *http.Request) {
@@ -98,8 +141,9 @@ func ChannelAudio(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_channel_audio", map[string]interface{}{
-		"Channel": channel,
-		"Videos":  videos,
+		"Channel":  channel,
+		"Videos":   videos,
+		"Playable": findPlayableForSearch(r, videos),
 	}); err != nil {
 		panic(err)
 	}
@@ -123,11 +167,13 @@ func ChannelAudioZip(rw http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
+	p := findPlayableForSearch(r, videos)
+
 	rw.Header().Set("content-type", "application/x-zip")
 	rw.Header().Set("content-disposition", "attachment;filename="+channel.ChannelTitle+".zip")
 	rw.WriteHeader(http.StatusOK)
 
-	if err := archiver.VideoSearchZipAudio(r.Context(), rw, videos); err != nil {
+	if err := archiver.VideoSearchZipAudio(r.Context(), rw, videos, p.Audio, p.Chapters); err != nil {
 		panic(err)
 	}
 }
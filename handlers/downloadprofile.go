@@ -0,0 +1,106 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/http"
+
+	"github.com/monoculum/formam"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxconfig"
+	"fknsrs.biz/p/ytmusic/internal/ctxjobqueue"
+	"fknsrs.biz/p/ytmusic/internal/jobqueue"
+	"fknsrs.biz/p/ytmusic/internal/queuenames"
+	"fknsrs.biz/p/ytmusic/models"
+)
+
+// downloadProfileForm is what "shared_download_profile" needs to draw the
+// form. Default is the profile that applies when Profile is empty.
+type downloadProfileForm struct {
+	Action   string
+	Profile  string
+	Default  string
+	Profiles []string
+}
+
+func makeDownloadProfileForm(r *http.Request, action, profile, parentProfile string) downloadProfileForm {
+	def := parentProfile
+	if def == "" {
+		def = ctxconfig.GetConfig(r.Context()).DownloadProfile
+	}
+
+	return downloadProfileForm{
+		Action:   action,
+		Profile:  profile,
+		Default:  def,
+		Profiles: models.DownloadProfiles,
+	}
+}
+
+func parseDownloadProfile(r *http.Request) (string, error) {
+	if err := r.ParseForm(); err != nil {
+		return "", err
+	}
+
+	var input struct {
+		DownloadProfile string `formam:"download_profile"`
+	}
+
+	if err := formam.Decode(r.PostForm, &input); err != nil {
+		return "", err
+	}
+
+	if input.DownloadProfile != "" && !models.IsDownloadProfile(input.DownloadProfile) {
+		return "", fmt.Errorf("unrecognised download profile %q", input.DownloadProfile)
+	}
+
+	return input.DownloadProfile, nil
+}
+
+// enqueueMissingDownloads queues a download for each video that doesn't have
+// its full video yet. The download job works out the profile for itself, and
+// does nothing if the audio it would fetch is already there.
+func enqueueMissingDownloads(ctx context.Context, tx *sql.Tx, query string, args ...interface{}) (int, error) {
+	rows, err := tx.QueryContext(ctx, "select v.external_id from videos v where v.downloaded_at is null and v.availability = 'available' and "+query, args...)
+	if err != nil {
+		return 0, fmt.Errorf("enqueueMissingDownloads: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return 0, fmt.Errorf("enqueueMissingDownloads: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, fmt.Errorf("enqueueMissingDownloads: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+			QueueName: queuenames.VideoDownload,
+			Payload:   id,
+		}); err != nil {
+			return 0, fmt.Errorf("enqueueMissingDownloads: %w", err)
+		}
+	}
+
+	return len(ids), nil
+}
+
+func downloadProfileMessage(profile string, queued int) string {
+	message := "Videos will be downloaded using the default profile."
+	if profile != "" {
+		message = fmt.Sprintf("Videos will be downloaded using the %s profile.", profile)
+	}
+
+	if queued > 0 {
+		message += fmt.Sprintf(" %d downloads were queued.", queued)
+	}
+
+	return message
+}
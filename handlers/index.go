@@ -71,6 +71,7 @@ func Index(rw http.ResponseWriter, r *http.Request) {
 		"Channels":  channels,
 		"Playlists": playlists,
 		"Videos":    videos,
+		"Captions":  findCaptions(r, q),
 	}); err != nil {
 		panic(err)
 	}
@@ -0,0 +1,183 @@
+package handlers
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"strconv"
+	"time"
+
+	"fknsrs.biz/p/sorm"
+	"github.com/gorilla/mux"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxdb"
+	"fknsrs.biz/p/ytmusic/internal/ctxjobqueue"
+	"fknsrs.biz/p/ytmusic/internal/ctxtemplate"
+	"fknsrs.biz/p/ytmusic/internal/httputil"
+	"fknsrs.biz/p/ytmusic/internal/jobqueue"
+	"fknsrs.biz/p/ytmusic/internal/queuenames"
+	"fknsrs.biz/p/ytmusic/models"
+)
+
+// libraryIssueGroup is the issues of one kind that the last library_verify
+// run found.
+type libraryIssueGroup struct {
+	Kind   string
+	Issues []models.LibraryIssue
+}
+
+// Library shows what the last library_verify run found wrong with the files
+// in the data directory.
+func Library(rw http.ResponseWriter, r *http.Request) {
+	var verification *models.LibraryVerification
+
+	var v models.LibraryVerification
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &v, "order by id desc"); err != nil {
+		if err != sql.ErrNoRows {
+			panic(err)
+		}
+	} else {
+		verification = &v
+	}
+
+	var issues []models.LibraryIssue
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &issues, "order by directory asc, file asc"); err != nil {
+		panic(err)
+	}
+
+	var groups []libraryIssueGroup
+	var requeueable int
+	for _, kind := range models.LibraryIssueKinds {
+		g := libraryIssueGroup{Kind: kind}
+		for _, i := range issues {
+			if i.Kind != kind {
+				continue
+			}
+
+			g.Issues = append(g.Issues, i)
+			if i.CanRequeue() {
+				requeueable++
+			}
+		}
+
+		if len(g.Issues) > 0 {
+			groups = append(groups, g)
+		}
+	}
+
+	var pending int
+	if err := ctxdb.GetDB(r.Context()).QueryRowContext(r.Context(), "select count(*) from jobs where queue_name = ? and finished_at is null", queuenames.LibraryVerify).Scan(&pending); err != nil {
+		panic(err)
+	}
+
+	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_library", map[string]interface{}{
+		"Verification": verification,
+		"Groups":       groups,
+		"Requeueable":  requeueable,
+		"Pending":      pending > 0,
+	}); err != nil {
+		panic(err)
+	}
+}
+
+func LibraryVerifyAction(rw http.ResponseWriter, r *http.Request) {
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		return ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+			QueueName: queuenames.LibraryVerify,
+		})
+	}); err != nil {
+		panic(err)
+	}
+
+	httputil.RedirectWithSuccess(rw, r, "/library", "The library will be checked soon.")
+}
+
+func LibraryRequeueAction(rw http.ResponseWriter, r *http.Request) {
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		var issues []models.LibraryIssue
+		if err := sorm.FindWhere(ctx, tx, &issues, "where queue_name != '' and requeued_at is null order by id asc"); err != nil {
+			return err
+		}
+
+		return requeueLibraryIssues(ctx, tx, issues)
+	}); err != nil {
+		panic(err)
+	}
+
+	httputil.RedirectWithSuccess(rw, r, "/library", "The files will be made again soon.")
+}
+
+func LibraryIssueRequeueAction(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	id, err := strconv.Atoi(vars["id"])
+	if err != nil {
+		httputil.NotFound(rw, r)
+		return
+	}
+
+	var issue models.LibraryIssue
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &issue, "where id = ?", id); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	if !issue.CanRequeue() {
+		httputil.RedirectWithError(rw, r, "/library", "There's nothing to requeue for "+issue.Path()+".")
+		return
+	}
+
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		return requeueLibraryIssues(ctx, tx, []models.LibraryIssue{issue})
+	}); err != nil {
+		panic(err)
+	}
+
+	httputil.RedirectWithSuccess(rw, r, "/library", issue.Path()+" will be made again soon.")
+}
+
+// requeueLibraryIssues queues the jobs that make the issues' files again,
+// once each however many of the files a job makes.
+func requeueLibraryIssues(ctx context.Context, tx *sql.Tx, issues []models.LibraryIssue) error {
+	queued := make(map[string]bool)
+
+	for _, issue := range issues {
+		if !issue.CanRequeue() {
+			continue
+		}
+
+		if key := issue.QueueName + " " + issue.Payload; !queued[key] {
+			queued[key] = true
+
+			// downloads are skipped for videos that have been downloaded
+			// already, so that has to be forgotten first
+			if issue.QueueName == queuenames.VideoDownload {
+				column := "downloaded_at"
+				if issue.Directory == "audio" {
+					column = "audio_extracted_at"
+				}
+
+				if _, err := tx.ExecContext(ctx, "update videos set "+column+" = null where external_id = ?", issue.VideoExternalID); err != nil {
+					return err
+				}
+			}
+
+			if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+				QueueName: issue.QueueName,
+				Payload:   issue.Payload,
+			}); err != nil {
+				return err
+			}
+		}
+
+		if _, err := tx.ExecContext(ctx, "update library_issues set requeued_at = ? where id = ?", time.Now(), issue.ID); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
 	"strconv"
@@ -69,19 +70,75 @@ func Playlist(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	var videos []models.VideoInPlaylist
-	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? order by playlist_video_position asc", playlist.PlaylistID); err != nil {
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? and playlist_video_removed_at is null order by playlist_video_position asc", playlist.PlaylistID); err != nil {
 		panic(err)
 	}
 
+	var removedVideos []models.VideoInPlaylist
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &removedVideos, "where playlist_id = ? and playlist_video_removed_at is not null order by playlist_video_removed_at desc", playlist.PlaylistID); err != nil {
+		panic(err)
+	}
+
+	var record models.Playlist
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &record, "where id = ?", playlist.PlaylistID); err != nil {
+		panic(err)
+	}
+
+	// an empty profile falls back to the channel's before the library's
+	var channelRecord models.Channel
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &channelRecord, "where external_id = ?", playlist.ChannelExternalID); err != nil {
+		if err != sql.ErrNoRows {
+			panic(err)
+		}
+	}
+
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_playlist", map[string]interface{}{
-		"Playlist": playlist,
-		"Channel":  channel,
-		"Videos":   videos,
+		"Playlist":        playlist,
+		"Channel":         channel,
+		"Videos":          videos,
+		"RemovedVideos":   removedVideos,
+		"Revisions":       findRevisions(r, models.RevisionObjectPlaylist, playlist.PlaylistExternalID),
+		"DownloadProfile": makeDownloadProfileForm(r, "/playlists/"+playlist.PlaylistExternalID+"/download-profile", record.DownloadProfile, channelRecord.DownloadProfile),
 	}); err != nil {
 		panic(err)
 	}
 }
 
+func PlaylistDownloadProfileAction(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var playlist models.Playlist
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &playlist, "where external_id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	profile, err := parseDownloadProfile(r)
+	if err != nil {
+		httputil.RedirectWithError(rw, r, "/playlists/"+playlist.ExternalID, err.Error())
+		return
+	}
+
+	var queued int
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		if _, err := tx.ExecContext(ctx, "update playlists set download_profile = ? where id = ?", profile, playlist.ID); err != nil {
+			return err
+		}
+
+		n, err := enqueueMissingDownloads(ctx, tx, "v.external_id in (select video_external_id from playlist_videos where playlist_external_id = ? and removed_at is null)", playlist.ExternalID)
+		queued = n
+		return err
+	}); err != nil {
+		panic(err)
+	}
+
+	httputil.RedirectWithSuccess(rw, r, "/playlists/"+playlist.ExternalID, downloadProfileMessage(profile, queued))
+}
+
 func PlaylistAudio(rw http.ResponseWriter, r *http.Request) {
 	vars := mux.Vars(r)
 
@@ -103,7 +160,7 @@ func PlaylistAudio(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	var videos []models.VideoInPlaylist
-	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? order by playlist_video_position asc", playlist.PlaylistID); err != nil {
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? and playlist_video_removed_at is null order by playlist_video_position asc", playlist.PlaylistID); err != nil {
 		panic(err)
 	}
 
@@ -111,6 +168,7 @@ func PlaylistAudio(rw http.ResponseWriter, r *http.Request) {
 		"Playlist": playlist,
 		"Channel":  channel,
 		"Videos":   videos,
+		"Playable": findPlayableForPlaylist(r, videos),
 	}); err != nil {
 		panic(err)
 	}
@@ -130,15 +188,17 @@ func PlaylistAudioZip(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	var videos []models.VideoInPlaylist
-	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? order by playlist_video_position asc", playlist.PlaylistID); err != nil {
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? and playlist_video_removed_at is null order by playlist_video_position asc", playlist.PlaylistID); err != nil {
 		panic(err)
 	}
 
+	p := findPlayableForPlaylist(r, videos)
+
 	rw.Header().Set("content-type", "application/x-zip")
 	rw.Header().Set("content-disposition", "attachment;filename="+playlist.ChannelTitle+" - "+playlist.PlaylistTitle+".zip")
 	rw.WriteHeader(http.StatusOK)
 
-	if err := archiver.VideoInPlaylistZipAudio(r.Context(), rw, videos); err != nil {
+	if err := archiver.VideoInPlaylistZipAudio(r.Context(), rw, videos, p.Audio, p.Chapters); err != nil {
 		panic(err)
 	}
 }
@@ -169,7 +229,7 @@ func PlaylistVideo(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	var videos []models.VideoInPlaylist
-	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? order by playlist_video_position asc", playlist.PlaylistID); err != nil {
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videos, "where playlist_id = ? and playlist_video_removed_at is null order by playlist_video_position asc", playlist.PlaylistID); err != nil {
 		panic(err)
 	}
 
@@ -183,12 +243,30 @@ func PlaylistVideo(rw http.ResponseWriter, r *http.Request) {
 		nextVideo = &videos[int(index)+1]
 	}
 
+	var subtitles []models.VideoSubtitle
+	var chapters []models.VideoChapter
+	var audio []models.VideoAudio
+	var hls bool
+	if video != nil {
+		subtitles = findSubtitles(r, video.VideoExternalID)
+		chapters = findChapters(r, video.VideoExternalID)
+		audio = findVideoAudio(r, video.VideoExternalID)
+
+		for _, v := range findVideoRecords(r, []string{video.VideoExternalID}) {
+			hls = v.HLSPackagedAt != nil
+		}
+	}
+
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_playlist_video", map[string]interface{}{
 		"Playlist":  playlist,
 		"Channel":   channel,
 		"Videos":    videos,
 		"Video":     video,
 		"NextVideo": nextVideo,
+		"Subtitles": subtitles,
+		"Chapters":  chapters,
+		"Audio":     audio,
+		"HLS":       hls,
 	}); err != nil {
 		panic(err)
 	}
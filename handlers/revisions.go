@@ -0,0 +1,54 @@
+package handlers
+
+import (
+	"database/sql"
+	"net/http"
+
+	"fknsrs.biz/p/sorm"
+	"github.com/gorilla/mux"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxdb"
+	"fknsrs.biz/p/ytmusic/internal/ctxtemplate"
+	"fknsrs.biz/p/ytmusic/internal/httputil"
+	"fknsrs.biz/p/ytmusic/internal/textdiff"
+	"fknsrs.biz/p/ytmusic/models"
+)
+
+// objectURLPrefixes maps a revision's object type to where that object's
+// page lives.
+var objectURLPrefixes = map[string]string{
+	models.RevisionObjectChannel:  "/channels/",
+	models.RevisionObjectPlaylist: "/playlists/",
+	models.RevisionObjectVideo:    "/videos/",
+}
+
+func findRevisions(r *http.Request, objectType, externalID string) []models.Revision {
+	var revisions []models.Revision
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &revisions, "where object_type = ? and object_external_id = ? order by created_at desc, id desc", objectType, externalID); err != nil {
+		panic(err)
+	}
+
+	return revisions
+}
+
+func Revision(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var revision models.Revision
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &revision, "where id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_revision", map[string]interface{}{
+		"Revision":  revision,
+		"ObjectURL": objectURLPrefixes[revision.ObjectType] + revision.ObjectExternalID,
+		"Diff":      textdiff.Diff(revision.OldValue, revision.NewValue),
+	}); err != nil {
+		panic(err)
+	}
+}
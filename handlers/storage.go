@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"net/http"
+
+	"fknsrs.biz/p/sorm/qsorm"
+	sb "fknsrs.biz/p/sqlbuilder"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxdb"
+	"fknsrs.biz/p/ytmusic/internal/ctxtemplate"
+	"fknsrs.biz/p/ytmusic/models"
+)
+
+// Storage shows how much space the probed files take up, by data directory,
+// and which videos take up the most.
+func Storage(rw http.ResponseWriter, r *http.Request) {
+	rows, err := ctxdb.GetDB(r.Context()).QueryContext(r.Context(), "select directory, count(*), sum(size) from media_files group by directory order by directory asc")
+	if err != nil {
+		panic(err)
+	}
+	defer rows.Close()
+
+	var total models.MediaFileUsage
+	var usage []models.MediaFileUsage
+	for rows.Next() {
+		var u models.MediaFileUsage
+		if err := rows.Scan(&u.Directory, &u.Files, &u.Size); err != nil {
+			panic(err)
+		}
+
+		total.Files += u.Files
+		total.Size += u.Size
+
+		usage = append(usage, u)
+	}
+	if err := rows.Err(); err != nil {
+		panic(err)
+	}
+
+	var videos []models.VideoSearch
+	if err := qsorm.FindWhere(
+		r.Context(),
+		ctxdb.GetDB(r.Context()),
+		&videos,
+		sb.Gt(models.VideoSearchTable.C("VideoSize"), sb.Literal("0")),
+		[]sb.AsOrderingTerm{sb.OrderDesc(models.VideoSearchTable.C("VideoSize"))},
+		sb.OffsetLimit(nil, sb.Literal("50")),
+	); err != nil {
+		panic(err)
+	}
+
+	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_storage", map[string]interface{}{
+		"Usage":  usage,
+		"Total":  total,
+		"Videos": videos,
+	}); err != nil {
+		panic(err)
+	}
+}
@@ -1,8 +1,11 @@
 package handlers
 
 import (
+	"context"
 	"database/sql"
 	"net/http"
+	"sort"
+	"strconv"
 
 	"fknsrs.biz/p/sorm"
 	"fknsrs.biz/p/sorm/qsorm"
@@ -10,9 +13,14 @@ import (
 	"github.com/gorilla/mux"
 
 	"fknsrs.biz/p/ytmusic/internal/archiver"
+	"fknsrs.biz/p/ytmusic/internal/audioprofile"
+	"fknsrs.biz/p/ytmusic/internal/ctxconfig"
 	"fknsrs.biz/p/ytmusic/internal/ctxdb"
+	"fknsrs.biz/p/ytmusic/internal/ctxjobqueue"
 	"fknsrs.biz/p/ytmusic/internal/ctxtemplate"
 	"fknsrs.biz/p/ytmusic/internal/httputil"
+	"fknsrs.biz/p/ytmusic/internal/jobqueue"
+	"fknsrs.biz/p/ytmusic/internal/queuenames"
 	"fknsrs.biz/p/ytmusic/models"
 )
 
@@ -27,6 +35,16 @@ func Videos(rw http.ResponseWriter, r *http.Request) {
 		order = []sb.AsOrderingTerm{sb.OrderDesc(sb.Literal("rank"))}
 	}
 
+	// durations and sizes come from probing the files, so videos that
+	// haven't been probed sort as if they were empty
+	sortBy := r.URL.Query().Get("sort")
+	switch sortBy {
+	case "duration":
+		order = []sb.AsOrderingTerm{sb.OrderDesc(models.VideoSearchTable.C("VideoDurationMS"))}
+	case "size":
+		order = []sb.AsOrderingTerm{sb.OrderDesc(models.VideoSearchTable.C("VideoSize"))}
+	}
+
 	var videos []models.VideoSearch
 	if err := qsorm.FindWhere(
 		r.Context(),
@@ -40,8 +58,10 @@ func Videos(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_videos", map[string]interface{}{
-		"Q":      q,
-		"Videos": videos,
+		"Q":        q,
+		"Sort":     sortBy,
+		"Videos":   videos,
+		"Captions": findCaptions(r, q),
 	}); err != nil {
 		panic(err)
 	}
@@ -71,8 +91,9 @@ func VideosAudio(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_videos_audio", map[string]interface{}{
-		"Q":      q,
-		"Videos": videos,
+		"Q":        q,
+		"Videos":   videos,
+		"Playable": findPlayableForSearch(r, videos),
 	}); err != nil {
 		panic(err)
 	}
@@ -101,13 +122,293 @@ func VideosAudioZip(rw http.ResponseWriter, r *http.Request) {
 		panic(err)
 	}
 
+	p := findPlayableForSearch(r, videos)
+
 	rw.Header().Set("content-type", "application/x-zip")
 	rw.Header().Set("content-disposition", "attachment;filename=Audio.zip")
 	rw.WriteHeader(http.StatusOK)
 
-	if err := archiver.VideoSearchZipAudio(r.Context(), rw, videos); err != nil {
+	if err := archiver.VideoSearchZipAudio(r.Context(), rw, videos, p.Audio, p.Chapters); err != nil {
+		panic(err)
+	}
+}
+
+func findSubtitles(r *http.Request, videoExternalID string) []models.VideoSubtitle {
+	var subtitles []models.VideoSubtitle
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &subtitles, "where video_external_id = ? order by automatic asc, language asc", videoExternalID); err != nil {
+		panic(err)
+	}
+
+	return subtitles
+}
+
+// findCaptions looks for caption cues matching q, so that a video can be
+// found by something said or sung in it.
+func findCaptions(r *http.Request, q string) []models.CaptionSearch {
+	if q == "" {
+		return nil
+	}
+
+	var captions []models.CaptionSearch
+	if err := qsorm.FindWhere(
+		r.Context(),
+		ctxdb.GetDB(r.Context()),
+		&captions,
+		sb.BinaryOperator("match", sb.Literal("caption_search"), sb.Bind(q)),
+		// fts5 ranks better matches lower
+		[]sb.AsOrderingTerm{sb.OrderAsc(sb.Literal("rank"))},
+		sb.OffsetLimit(nil, sb.Literal("100")),
+	); err != nil {
+		panic(err)
+	}
+
+	return captions
+}
+
+func findChapters(r *http.Request, videoExternalID string) []models.VideoChapter {
+	var chapters []models.VideoChapter
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &chapters, "where video_external_id = ? order by position asc", videoExternalID); err != nil {
+		panic(err)
+	}
+
+	return chapters
+}
+
+// findSplitChapters finds the chapters of each video whose audio has been
+// split into tracks, so that players and exports can use those instead of
+// the whole thing. A video that's only partly split is left out.
+func findSplitChapters(r *http.Request, videoExternalIDs []string) map[string][]models.VideoChapter {
+	if len(videoExternalIDs) == 0 {
+		return nil
+	}
+
+	var chapters []models.VideoChapter
+	if err := qsorm.FindWhere(
+		r.Context(),
+		ctxdb.GetDB(r.Context()),
+		&chapters,
+		sb.In(models.VideoChapterTable.C("VideoExternalID"), sb.BindAllStringsAsExpr(videoExternalIDs...)...),
+		[]sb.AsOrderingTerm{sb.OrderAsc(models.VideoChapterTable.C("Position"))},
+		nil,
+	); err != nil {
+		panic(err)
+	}
+
+	m := make(map[string][]models.VideoChapter)
+	unsplit := make(map[string]bool)
+
+	for _, c := range chapters {
+		if c.AudioSplitAt == nil {
+			unsplit[c.VideoExternalID] = true
+		}
+
+		m[c.VideoExternalID] = append(m[c.VideoExternalID], c)
+	}
+
+	for id := range unsplit {
+		delete(m, id)
+	}
+
+	return m
+}
+
+// playable is what the audio players and zip files need for a list of
+// videos: which audio profile to use, each video's file for it, and the
+// chapters of videos that have been split. Chapters are cut from the default
+// profile, so they're only used when that's the one being played. Gain is
+// the ReplayGain adjustment, in dB, for videos whose loudness is known; it's
+// left empty for normalized audio, which doesn't need one. Waveform says
+// which videos have waveform peaks to draw.
+type playable struct {
+	Profile  string
+	Profiles []string
+	Audio    map[string]models.VideoAudio
+	Chapters map[string][]models.VideoChapter
+	Gain     map[string]float64
+	Waveform map[string]bool
+}
+
+func findPlayable(r *http.Request, videoExternalIDs []string) playable {
+	cfg := ctxconfig.GetConfig(r.Context())
+
+	profiles := cfg.AudioProfiles.Names()
+	if cfg.NormalizeAudio {
+		profiles = append(profiles, audioprofile.Normalized)
+	}
+
+	p := playable{Profiles: profiles}
+	if len(profiles) > 0 {
+		p.Profile = profiles[0]
+	}
+
+	if q := r.URL.Query().Get("profile"); q != "" {
+		p.Profile = q
+	}
+
+	p.Audio = findAudio(r, p.Profile, videoExternalIDs)
+
+	if len(profiles) > 0 && p.Profile == profiles[0] {
+		p.Chapters = findSplitChapters(r, videoExternalIDs)
+	}
+
+	p.Gain = make(map[string]float64)
+	p.Waveform = make(map[string]bool)
+
+	for _, v := range findVideoRecords(r, videoExternalIDs) {
+		if l := v.Loudness(); l != nil && p.Profile != audioprofile.Normalized {
+			p.Gain[v.ExternalID] = l.Gain()
+		}
+
+		p.Waveform[v.ExternalID] = v.WaveformUpdatedAt != nil
+	}
+
+	return p
+}
+
+// findVideoRecords finds the rows behind a list of videos, for what the
+// search views don't include.
+func findVideoRecords(r *http.Request, videoExternalIDs []string) []models.Video {
+	if len(videoExternalIDs) == 0 {
+		return nil
+	}
+
+	var videos []models.Video
+	if err := qsorm.FindWhere(
+		r.Context(),
+		ctxdb.GetDB(r.Context()),
+		&videos,
+		sb.In(models.VideoTable.C("ExternalID"), sb.BindAllStringsAsExpr(videoExternalIDs...)...),
+		nil,
+		nil,
+	); err != nil {
+		panic(err)
+	}
+
+	return videos
+}
+
+func findPlayableForSearch(r *http.Request, videos []models.VideoSearch) playable {
+	var ids []string
+	for _, v := range videos {
+		ids = append(ids, v.VideoExternalID)
+	}
+
+	return findPlayable(r, ids)
+}
+
+func findPlayableForPlaylist(r *http.Request, videos []models.VideoInPlaylist) playable {
+	var ids []string
+	for _, v := range videos {
+		ids = append(ids, v.VideoExternalID)
+	}
+
+	return findPlayable(r, ids)
+}
+
+// findAudio finds each video's audio file for one profile.
+func findAudio(r *http.Request, profile string, videoExternalIDs []string) map[string]models.VideoAudio {
+	if len(videoExternalIDs) == 0 {
+		return nil
+	}
+
+	var audio []models.VideoAudio
+	if err := qsorm.FindWhere(
+		r.Context(),
+		ctxdb.GetDB(r.Context()),
+		&audio,
+		sb.And(
+			sb.Eq(models.VideoAudioTable.C("Profile"), sb.Bind(profile)),
+			sb.In(models.VideoAudioTable.C("VideoExternalID"), sb.BindAllStringsAsExpr(videoExternalIDs...)...),
+		),
+		nil,
+		nil,
+	); err != nil {
+		panic(err)
+	}
+
+	m := make(map[string]models.VideoAudio)
+	for _, a := range audio {
+		m[a.VideoExternalID] = a
+	}
+
+	return m
+}
+
+// findRenditions finds a video's transcoded renditions, in the order they
+// are configured. Renditions that aren't configured any more come last.
+func findRenditions(r *http.Request, videoExternalID string) []models.VideoRendition {
+	var renditions []models.VideoRendition
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &renditions, "where video_external_id = ? order by name asc", videoExternalID); err != nil {
+		panic(err)
+	}
+
+	order := make(map[string]int)
+	for i, rendition := range ctxconfig.GetConfig(r.Context()).Renditions {
+		order[rendition.Name] = i + 1
+	}
+
+	sort.SliceStable(renditions, func(i, j int) bool {
+		a, b := order[renditions[i].Name], order[renditions[j].Name]
+		if a == 0 || b == 0 {
+			return a > b
+		}
+		return a < b
+	})
+
+	return renditions
+}
+
+// findMediaFiles finds what was probed from a video's files.
+func findMediaFiles(r *http.Request, videoExternalID string) []models.MediaFile {
+	var files []models.MediaFile
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &files, "where video_external_id = ? order by directory desc, file asc", videoExternalID); err != nil {
+		panic(err)
+	}
+
+	return files
+}
+
+// missingRenditions finds the configured renditions that a video hasn't
+// been transcoded to yet.
+func missingRenditions(r *http.Request, renditions []models.VideoRendition) []string {
+	have := make(map[string]bool)
+	for _, rendition := range renditions {
+		have[rendition.Name] = true
+	}
+
+	var missing []string
+	for _, rendition := range ctxconfig.GetConfig(r.Context()).Renditions {
+		if !have[rendition.Name] {
+			missing = append(missing, rendition.Name)
+		}
+	}
+
+	return missing
+}
+
+// findVideoAudio finds all of a video's audio files, in the order the
+// profiles are configured. Files from profiles that aren't configured any
+// more, and the original download, come last.
+func findVideoAudio(r *http.Request, videoExternalID string) []models.VideoAudio {
+	var audio []models.VideoAudio
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &audio, "where video_external_id = ? order by profile asc", videoExternalID); err != nil {
 		panic(err)
 	}
+
+	order := make(map[string]int)
+	for i, name := range ctxconfig.GetConfig(r.Context()).AudioProfiles.Names() {
+		order[name] = i + 1
+	}
+
+	sort.SliceStable(audio, func(i, j int) bool {
+		a, b := order[audio[i].Profile], order[audio[j].Profile]
+		if a == 0 || b == 0 {
+			return a > b
+		}
+		return a < b
+	})
+
+	return audio
 }
 
 func Video(rw http.ResponseWriter, r *http.Request) {
@@ -131,17 +432,131 @@ func Video(rw http.ResponseWriter, r *http.Request) {
 	}
 
 	var videoInPlaylists []models.VideoInPlaylist
-	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videoInPlaylists, "where video_id = ? or video_external_id = ?", video.VideoID, video.VideoExternalID); err != nil {
+	if err := sorm.FindWhere(r.Context(), ctxdb.GetDB(r.Context()), &videoInPlaylists, "where (video_id = ? or video_external_id = ?) and playlist_video_removed_at is null", video.VideoID, video.VideoExternalID); err != nil {
 		if err != sql.ErrNoRows {
 			panic(err)
 		}
 	}
 
+	// loudness, waveforms, and streams aren't in the search view
+	var record models.Video
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &record, "where external_id = ?", video.VideoExternalID); err != nil {
+		if err != sql.ErrNoRows {
+			panic(err)
+		}
+	}
+
+	renditions := findRenditions(r, video.VideoExternalID)
+
+	// t is where to start playing, in seconds, for links from caption search
+	// results
+	start, _ := strconv.Atoi(r.URL.Query().Get("t"))
+
 	if err := ctxtemplate.ExecuteTemplateIntoResponse(r, rw, "page_video", map[string]interface{}{
-		"Video":            video,
-		"Channel":          channel,
-		"VideoInPlaylists": videoInPlaylists,
+		"Start":             start,
+		"Video":             video,
+		"Channel":           channel,
+		"VideoInPlaylists":  videoInPlaylists,
+		"Revisions":         findRevisions(r, models.RevisionObjectVideo, video.VideoExternalID),
+		"Subtitles":         findSubtitles(r, video.VideoExternalID),
+		"Chapters":          findChapters(r, video.VideoExternalID),
+		"Audio":             findVideoAudio(r, video.VideoExternalID),
+		"Loudness":          record.Loudness(),
+		"Waveform":          record.WaveformUpdatedAt != nil,
+		"HLS":               record.HLSPackagedAt != nil,
+		"Storyboard":        record.StoryboardMadeAt != nil,
+		"Renditions":        renditions,
+		"MissingRenditions": missingRenditions(r, renditions),
+		"MediaFiles":        findMediaFiles(r, video.VideoExternalID),
+	}); err != nil {
+		panic(err)
+	}
+}
+
+// VideoWaveform serves the waveform peaks of a video's audio, for players
+// to draw.
+func VideoWaveform(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var video models.Video
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &video, "where external_id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	if video.WaveformUpdatedAt == nil {
+		httputil.NotFound(rw, r)
+		return
+	}
+
+	rw.Header().Set("content-type", "application/json")
+
+	http.ServeFile(rw, r, ctxconfig.GetConfig(r.Context()).DataFile("waveforms", video.ExternalID+".json"))
+}
+
+func VideoSplitChaptersAction(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var video models.Video
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &video, "where external_id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		return ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+			QueueName: queuenames.VideoSplitChapters,
+			Payload:   video.ExternalID,
+		})
 	}); err != nil {
 		panic(err)
 	}
+
+	httputil.RedirectWithSuccess(rw, r, "/videos/"+video.ExternalID, "The audio will be split into tracks soon.")
+}
+
+func VideoTranscodeAction(rw http.ResponseWriter, r *http.Request) {
+	vars := mux.Vars(r)
+
+	var video models.Video
+	if err := sorm.FindFirstWhere(r.Context(), ctxdb.GetDB(r.Context()), &video, "where external_id = ?", vars["id"]); err != nil {
+		if err == sql.ErrNoRows {
+			httputil.NotFound(rw, r)
+			return
+		}
+
+		panic(err)
+	}
+
+	if video.DownloadedAt == nil {
+		httputil.RedirectWithError(rw, r, "/videos/"+video.ExternalID, "The video has not been downloaded yet.")
+		return
+	}
+
+	missing := missingRenditions(r, findRenditions(r, video.ExternalID))
+
+	if err := ctxdb.UsingTx(r.Context(), nil, func(ctx context.Context, tx *sql.Tx) error {
+		for _, name := range missing {
+			if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+				QueueName: queuenames.VideoTranscode,
+				Payload:   video.ExternalID + "?rendition=" + name,
+			}); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		panic(err)
+	}
+
+	httputil.RedirectWithSuccess(rw, r, "/videos/"+video.ExternalID, "The video will be transcoded soon.")
 }
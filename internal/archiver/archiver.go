@@ -6,30 +6,35 @@ import (
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 
 	"fknsrs.biz/p/ytmusic/internal/ctxconfig"
 	"fknsrs.biz/p/ytmusic/models"
 )
 
-func VideoSearchZipAudio(ctx context.Context, wr io.Writer, videos []models.VideoSearch) error {
+// VideoSearchZipAudio adds a file for each video's audio, or a file for each
+// of its chapters if the audio has been split up; audio and chapters are keyed
+// by the video's external ID, and videos without audio are left out.
+func VideoSearchZipAudio(ctx context.Context, wr io.Writer, videos []models.VideoSearch, audio map[string]models.VideoAudio, chapters map[string][]models.VideoChapter) error {
 	zw := zip.NewWriter(wr)
 
 	for _, video := range videos {
-		if video.VideoAudioExtractedAt == nil {
+		a, ok := audio[video.VideoExternalID]
+		if !ok {
 			continue
 		}
 
-		wr, err := zw.Create(fmt.Sprintf("%s - %s.mp3", video.ChannelTitle, video.VideoTitle))
-		if err != nil {
-			return err
-		}
+		if tracks := chapters[video.VideoExternalID]; len(tracks) > 0 {
+			for _, c := range tracks {
+				if err := addFile(zw, fmt.Sprintf("%s - %s - %02d - %s%s", video.ChannelTitle, video.VideoTitle, c.Position+1, c.Title, filepath.Ext(c.AudioFile)), ctxconfig.DataFile(ctx, "chapters", c.AudioFile)); err != nil {
+					return err
+				}
+			}
 
-		fd, err := os.Open(ctxconfig.DataFile(ctx, "audio", video.VideoExternalID+".mp3"))
-		if err != nil {
-			return err
+			continue
 		}
 
-		if _, err := io.Copy(wr, fd); err != nil {
+		if err := addFile(zw, fmt.Sprintf("%s - %s%s", video.ChannelTitle, video.VideoTitle, a.Extension()), ctxconfig.DataFile(ctx, "audio", a.File)); err != nil {
 			return err
 		}
 	}
@@ -41,25 +46,39 @@ func VideoSearchZipAudio(ctx context.Context, wr io.Writer, videos []models.Vide
 	return nil
 }
 
-func VideoInPlaylistZipAudio(ctx context.Context, wr io.Writer, videos []models.VideoInPlaylist) error {
+// VideoInPlaylistZipAudio numbers each file by its position in the playlist,
+// which keeps them in order and keeps a video that's in the playlist more than
+// once from overwriting itself. The playlist's artwork goes in as cover.jpg,
+// which is where most music players look for it. Videos that have been split
+// into chapters get a file per chapter, numbered after the video's position.
+func VideoInPlaylistZipAudio(ctx context.Context, wr io.Writer, videos []models.VideoInPlaylist, audio map[string]models.VideoAudio, chapters map[string][]models.VideoChapter) error {
 	zw := zip.NewWriter(wr)
 
+	width := len(fmt.Sprint(len(videos)))
+
+	if len(videos) > 0 && videos[0].PlaylistThumbnailUpdatedAt != nil {
+		if err := addFile(zw, "cover.jpg", ctxconfig.DataFile(ctx, "thumbnails", videos[0].PlaylistExternalID+".jpg")); err != nil {
+			return err
+		}
+	}
+
 	for _, video := range videos {
-		if video.VideoAudioExtractedAt == nil {
+		a, ok := audio[video.VideoExternalID]
+		if !ok {
 			continue
 		}
 
-		wr, err := zw.Create(fmt.Sprintf("%s - %s - %s.mp3", video.ChannelTitle, video.PlaylistTitle, video.VideoTitle))
-		if err != nil {
-			return err
-		}
+		if tracks := chapters[video.VideoExternalID]; len(tracks) > 0 {
+			for _, c := range tracks {
+				if err := addFile(zw, fmt.Sprintf("%s - %s - %0*d.%02d - %s%s", video.ChannelTitle, video.PlaylistTitle, width, video.PlaylistVideoPosition+1, c.Position+1, c.Title, filepath.Ext(c.AudioFile)), ctxconfig.DataFile(ctx, "chapters", c.AudioFile)); err != nil {
+					return err
+				}
+			}
 
-		fd, err := os.Open(ctxconfig.DataFile(ctx, "audio", video.VideoExternalID+".mp3"))
-		if err != nil {
-			return err
+			continue
 		}
 
-		if _, err := io.Copy(wr, fd); err != nil {
+		if err := addFile(zw, fmt.Sprintf("%s - %s - %0*d - %s%s", video.ChannelTitle, video.PlaylistTitle, width, video.PlaylistVideoPosition+1, video.VideoTitle, a.Extension()), ctxconfig.DataFile(ctx, "audio", a.File)); err != nil {
 			return err
 		}
 	}
@@ -70,3 +89,22 @@ func VideoInPlaylistZipAudio(ctx context.Context, wr io.Writer, videos []models.
 
 	return nil
 }
+
+func addFile(zw *zip.Writer, name, path string) error {
+	wr, err := zw.Create(name)
+	if err != nil {
+		return err
+	}
+
+	fd, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer fd.Close()
+
+	if _, err := io.Copy(wr, fd); err != nil {
+		return err
+	}
+
+	return nil
+}
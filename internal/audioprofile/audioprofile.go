@@ -0,0 +1,198 @@
+// Package audioprofile describes the audio files extracted for each video.
+// A profile is written as name:codec[:setting[:container]], e.g. "mp3:mp3:q2"
+// for VBR quality 2 or "opus:opus:128k" for a 128kbps bitrate. The container
+// defaults to the usual one for the codec.
+package audioprofile
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Original is the name used for audio that was downloaded as-is, without
+// the video. Profiles are encoded from it when there's no video to use.
+const Original = "original"
+
+// Normalized is the name used for audio that's been brought to a standard
+// loudness. It's encoded the same way as the default profile.
+const Normalized = "normalized"
+
+type codec struct {
+	encoder        string
+	container      string
+	defaultBitrate string
+	defaultQuality string
+	lossless       bool
+	qualityAllowed bool
+	// sampleRates are the only ones the encoder takes, highest first, if
+	// it's fussy about them
+	sampleRates []int
+}
+
+var codecs = map[string]codec{
+	"mp3":  {encoder: "libmp3lame", container: "mp3", defaultQuality: "2", qualityAllowed: true},
+	"opus": {encoder: "libopus", container: "opus", defaultBitrate: "128k", sampleRates: []int{48000, 24000, 16000, 12000, 8000}},
+	"aac":  {encoder: "aac", container: "m4a", defaultBitrate: "192k"},
+	"flac": {encoder: "flac", container: "flac", lossless: true},
+}
+
+var mimeTypes = map[string]string{
+	"mp3":  "audio/mpeg",
+	"m4a":  "audio/mp4",
+	"opus": "audio/ogg",
+	"ogg":  "audio/ogg",
+	"flac": "audio/flac",
+	"webm": "audio/webm",
+	"mka":  "audio/x-matroska",
+}
+
+var (
+	namePattern    = regexp.MustCompile(`^[a-z0-9_-]+$`)
+	bitratePattern = regexp.MustCompile(`^[0-9]+k$`)
+	qualityPattern = regexp.MustCompile(`^q[0-9]$`)
+)
+
+type Profile struct {
+	Name      string
+	Codec     string
+	Bitrate   string
+	Quality   string
+	Container string
+}
+
+func Parse(s string) (Profile, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 2 || len(parts) > 4 {
+		return Profile{}, fmt.Errorf("audioprofile.Parse: %q should look like name:codec[:setting[:container]]", s)
+	}
+
+	p := Profile{Name: parts[0], Codec: parts[1]}
+
+	if !namePattern.MatchString(p.Name) || p.Name == Original || p.Name == Normalized {
+		return Profile{}, fmt.Errorf("audioprofile.Parse: %q is not a usable profile name", p.Name)
+	}
+
+	c, ok := codecs[p.Codec]
+	if !ok {
+		return Profile{}, fmt.Errorf("audioprofile.Parse: unrecognised codec %q; valid options are aac, flac, mp3, opus", p.Codec)
+	}
+
+	if len(parts) > 2 && parts[2] != "" {
+		switch {
+		case c.lossless:
+			return Profile{}, fmt.Errorf("audioprofile.Parse: %s is lossless and doesn't take a bitrate or quality", p.Codec)
+		case bitratePattern.MatchString(parts[2]):
+			p.Bitrate = parts[2]
+		case qualityPattern.MatchString(parts[2]) && c.qualityAllowed:
+			p.Quality = parts[2][1:]
+		default:
+			return Profile{}, fmt.Errorf("audioprofile.Parse: %q is not a valid setting for %s", parts[2], p.Codec)
+		}
+	}
+
+	if len(parts) > 3 && parts[3] != "" {
+		if _, ok := mimeTypes[parts[3]]; !ok {
+			return Profile{}, fmt.Errorf("audioprofile.Parse: unrecognised container %q", parts[3])
+		}
+
+		p.Container = parts[3]
+	}
+
+	return p, nil
+}
+
+func (p Profile) String() string {
+	s := p.Name + ":" + p.Codec
+
+	switch {
+	case p.Bitrate != "":
+		s += ":" + p.Bitrate
+	case p.Quality != "":
+		s += ":q" + p.Quality
+	case p.Container != "":
+		s += ":"
+	}
+
+	if p.Container != "" {
+		s += ":" + p.Container
+	}
+
+	return s
+}
+
+// Extension is the file extension for the profile's container, without the
+// leading dot.
+func (p Profile) Extension() string {
+	if p.Container != "" {
+		return p.Container
+	}
+
+	return codecs[p.Codec].container
+}
+
+// File is where a video's audio for this profile lives, relative to the
+// audio data directory.
+func (p Profile) File(videoID string) string {
+	return p.Name + "/" + videoID + "." + p.Extension()
+}
+
+// FFmpegArgs are the ffmpeg output options that encode audio for this
+// profile.
+func (p Profile) FFmpegArgs() []string {
+	c := codecs[p.Codec]
+
+	args := []string{"-c:a", c.encoder}
+
+	switch {
+	case p.Bitrate != "":
+		args = append(args, "-b:a", p.Bitrate)
+	case p.Quality != "":
+		args = append(args, "-q:a", p.Quality)
+	case c.defaultBitrate != "":
+		args = append(args, "-b:a", c.defaultBitrate)
+	case c.defaultQuality != "":
+		args = append(args, "-q:a", c.defaultQuality)
+	}
+
+	return args
+}
+
+// SampleRate is the rate to encode audio at for this profile when the source
+// is at sourceRate: the same, if the encoder takes it, or otherwise the
+// highest one that it does.
+func (p Profile) SampleRate(sourceRate int) int {
+	rates := codecs[p.Codec].sampleRates
+	if len(rates) == 0 {
+		return sourceRate
+	}
+
+	for _, r := range rates {
+		if r == sourceRate {
+			return r
+		}
+	}
+
+	return rates[0]
+}
+
+// SupportsCoverArt reports whether ffmpeg can embed a picture in files with
+// this extension. It can't for Ogg or Matroska.
+func SupportsCoverArt(extension string) bool {
+	switch strings.TrimPrefix(extension, ".") {
+	case "mp3", "m4a", "flac":
+		return true
+	default:
+		return false
+	}
+}
+
+// MimeType guesses the type of an audio file from its extension, with or
+// without the leading dot.
+func MimeType(extension string) string {
+	if t, ok := mimeTypes[strings.TrimPrefix(extension, ".")]; ok {
+		return t
+	}
+
+	return "application/octet-stream"
+}
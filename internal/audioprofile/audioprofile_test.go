@@ -0,0 +1,83 @@
+package audioprofile
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	for _, tc := range []struct {
+		in   string
+		out  Profile
+		args []string
+		file string
+	}{
+		{"mp3:mp3:q2", Profile{Name: "mp3", Codec: "mp3", Quality: "2"}, []string{"-c:a", "libmp3lame", "-q:a", "2"}, "mp3/abc.mp3"},
+		{"mp3-320:mp3:320k", Profile{Name: "mp3-320", Codec: "mp3", Bitrate: "320k"}, []string{"-c:a", "libmp3lame", "-b:a", "320k"}, "mp3-320/abc.mp3"},
+		{"opus:opus", Profile{Name: "opus", Codec: "opus"}, []string{"-c:a", "libopus", "-b:a", "128k"}, "opus/abc.opus"},
+		{"opus96:opus:96k:webm", Profile{Name: "opus96", Codec: "opus", Bitrate: "96k", Container: "webm"}, []string{"-c:a", "libopus", "-b:a", "96k"}, "opus96/abc.webm"},
+		{"aac:aac", Profile{Name: "aac", Codec: "aac"}, []string{"-c:a", "aac", "-b:a", "192k"}, "aac/abc.m4a"},
+		{"flac:flac", Profile{Name: "flac", Codec: "flac"}, []string{"-c:a", "flac"}, "flac/abc.flac"},
+		{"flac:flac::mka", Profile{Name: "flac", Codec: "flac", Container: "mka"}, []string{"-c:a", "flac"}, "flac/abc.mka"},
+	} {
+		p, err := Parse(tc.in)
+		if a.NoError(err, tc.in) {
+			a.Equal(tc.out, p, tc.in)
+			a.Equal(tc.args, p.FFmpegArgs(), tc.in)
+			a.Equal(tc.file, p.File("abc"), tc.in)
+			a.Equal(tc.in, p.String(), tc.in)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	a := assert.New(t)
+
+	for _, in := range []string{
+		"",
+		"mp3",
+		"mp3:wav",
+		"original:mp3",
+		"normalized:mp3",
+		"Bad Name:mp3",
+		"opus:opus:q5",
+		"flac:flac:320k",
+		"mp3:mp3:fast",
+		"mp3:mp3:q2:wav",
+		"a:mp3:q2:mp3:extra",
+	} {
+		_, err := Parse(in)
+		a.Error(err, in)
+	}
+}
+
+func TestMimeType(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("audio/mpeg", MimeType(".mp3"))
+	a.Equal("audio/ogg", MimeType("opus"))
+	a.Equal("audio/mp4", MimeType(".m4a"))
+	a.Equal("application/octet-stream", MimeType(".xyz"))
+}
+
+func TestSupportsCoverArt(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(SupportsCoverArt(".mp3"))
+	a.True(SupportsCoverArt("m4a"))
+	a.True(SupportsCoverArt(".flac"))
+	a.False(SupportsCoverArt(".opus"))
+	a.False(SupportsCoverArt(".webm"))
+}
+
+func TestSampleRate(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal(44100, Profile{Codec: "mp3"}.SampleRate(44100))
+	a.Equal(48000, Profile{Codec: "aac"}.SampleRate(48000))
+	a.Equal(48000, Profile{Codec: "opus"}.SampleRate(44100))
+	a.Equal(24000, Profile{Codec: "opus"}.SampleRate(24000))
+}
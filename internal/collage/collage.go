@@ -0,0 +1,121 @@
+package collage
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"os"
+)
+
+// Make draws images into a size by size square. Four or more make a two by
+// two grid of the first four; with fewer, the first fills the whole square.
+// Each image is cropped to a square from its centre first, so 16:9 video
+// thumbnails don't end up squashed.
+func Make(images []image.Image, size int) *image.RGBA {
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+
+	switch {
+	case len(images) == 0:
+		return dst
+	case len(images) < 4:
+		scale(dst, dst.Bounds(), images[0], centreSquare(images[0].Bounds()))
+	default:
+		half := size / 2
+		for i, img := range images[:4] {
+			x, y := (i%2)*half, (i/2)*half
+			tile := image.Rect(x, y, x+half, y+half)
+			// the right and bottom tiles take up any odd pixel
+			if i%2 == 1 {
+				tile.Max.X = size
+			}
+			if i/2 == 1 {
+				tile.Max.Y = size
+			}
+
+			scale(dst, tile, img, centreSquare(img.Bounds()))
+		}
+	}
+
+	return dst
+}
+
+func centreSquare(r image.Rectangle) image.Rectangle {
+	if d := r.Dx() - r.Dy(); d > 0 {
+		return image.Rect(r.Min.X+d/2, r.Min.Y, r.Min.X+d/2+r.Dy(), r.Max.Y)
+	} else if d < 0 {
+		return image.Rect(r.Min.X, r.Min.Y-d/2, r.Max.X, r.Min.Y-d/2+r.Dx())
+	}
+
+	return r
+}
+
+// scale resamples the sr part of src into the dr part of dst, averaging every
+// source pixel that lands on each destination pixel. image/draw can only copy
+// at the same size, and this is plenty for thumbnails.
+func scale(dst *image.RGBA, dr image.Rectangle, src image.Image, sr image.Rectangle) {
+	if dr.Empty() || sr.Empty() {
+		return
+	}
+
+	for y := dr.Min.Y; y < dr.Max.Y; y++ {
+		y0 := sr.Min.Y + (y-dr.Min.Y)*sr.Dy()/dr.Dy()
+		y1 := sr.Min.Y + (y-dr.Min.Y+1)*sr.Dy()/dr.Dy()
+		if y1 == y0 {
+			y1 = y0 + 1
+		}
+
+		for x := dr.Min.X; x < dr.Max.X; x++ {
+			x0 := sr.Min.X + (x-dr.Min.X)*sr.Dx()/dr.Dx()
+			x1 := sr.Min.X + (x-dr.Min.X+1)*sr.Dx()/dr.Dx()
+			if x1 == x0 {
+				x1 = x0 + 1
+			}
+
+			var r, g, b, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, _ := src.At(sx, sy).RGBA()
+					r, g, b, n = r+uint64(cr), g+uint64(cg), b+uint64(cb), n+1
+				}
+			}
+
+			dst.Set(x, y, color.RGBA64{R: uint16(r / n), G: uint16(g / n), B: uint16(b / n), A: 0xffff})
+		}
+	}
+}
+
+// Load decodes the JPEG at path.
+func Load(path string) (image.Image, error) {
+	fd, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("collage.Load: %w", err)
+	}
+	defer fd.Close()
+
+	img, err := jpeg.Decode(fd)
+	if err != nil {
+		return nil, fmt.Errorf("collage.Load: %s: %w", path, err)
+	}
+
+	return img, nil
+}
+
+// Save writes img to path as a JPEG.
+func Save(path string, img image.Image) error {
+	fd, err := os.Create(path)
+	if err != nil {
+		return fmt.Errorf("collage.Save: %w", err)
+	}
+	defer fd.Close()
+
+	if err := jpeg.Encode(fd, img, &jpeg.Options{Quality: 90}); err != nil {
+		return fmt.Errorf("collage.Save: %w", err)
+	}
+
+	if err := fd.Close(); err != nil {
+		return fmt.Errorf("collage.Save: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,75 @@
+package collage
+
+import (
+	"image"
+	"image/color"
+	"image/draw"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+var (
+	red   = color.RGBA{0xff, 0, 0, 0xff}
+	green = color.RGBA{0, 0xff, 0, 0xff}
+	blue  = color.RGBA{0, 0, 0xff, 0xff}
+	white = color.RGBA{0xff, 0xff, 0xff, 0xff}
+)
+
+func solid(c color.Color, w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestMakeGrid(t *testing.T) {
+	a := assert.New(t)
+
+	img := Make([]image.Image{solid(red, 160, 90), solid(green, 90, 160), solid(blue, 50, 50), solid(white, 640, 360), solid(red, 1, 1)}, 101)
+
+	a.Equal(image.Rect(0, 0, 101, 101), img.Bounds())
+	a.Equal(red, img.RGBAAt(25, 25))
+	a.Equal(green, img.RGBAAt(75, 25))
+	a.Equal(blue, img.RGBAAt(25, 75))
+	a.Equal(white, img.RGBAAt(75, 75))
+	a.Equal(white, img.RGBAAt(100, 100))
+}
+
+func TestMakeSingle(t *testing.T) {
+	a := assert.New(t)
+
+	img := Make([]image.Image{solid(green, 10, 10), solid(red, 10, 10)}, 40)
+
+	a.Equal(green, img.RGBAAt(0, 0))
+	a.Equal(green, img.RGBAAt(39, 39))
+}
+
+func TestMakeCropsToCentre(t *testing.T) {
+	// a 16:9 frame with red bars either side of a blue square
+	src := image.NewRGBA(image.Rect(0, 0, 160, 90))
+	draw.Draw(src, src.Bounds(), image.NewUniform(red), image.Point{}, draw.Src)
+	draw.Draw(src, image.Rect(35, 0, 125, 90), image.NewUniform(blue), image.Point{}, draw.Src)
+
+	img := Make([]image.Image{src}, 30)
+
+	a := assert.New(t)
+	a.Equal(blue, img.RGBAAt(0, 0))
+	a.Equal(blue, img.RGBAAt(29, 15))
+}
+
+func TestSaveLoad(t *testing.T) {
+	a := assert.New(t)
+
+	path := filepath.Join(t.TempDir(), "cover.jpg")
+
+	a.NoError(Save(path, Make([]image.Image{solid(blue, 8, 8)}, 16)))
+
+	img, err := Load(path)
+	if a.NoError(err) {
+		a.Equal(image.Rect(0, 0, 16, 16), img.Bounds())
+	}
+
+	_, err = Load(filepath.Join(t.TempDir(), "missing.jpg"))
+	a.Error(err)
+}
@@ -7,6 +7,9 @@ import (
 	"time"
 
 	"github.com/sirupsen/logrus"
+
+	"fknsrs.biz/p/ytmusic/internal/audioprofile"
+	"fknsrs.biz/p/ytmusic/internal/rendition"
 )
 
 type LevelList []logrus.Level
@@ -56,6 +59,163 @@ func (a *LevelList) UnmarshalText(d []byte) error {
 	return nil
 }
 
+type StringList []string
+
+func (a StringList) MarshalText() ([]byte, error) {
+	if len(a) == 0 {
+		return []byte("-"), nil
+	}
+
+	return []byte(strings.Join(a, ",")), nil
+}
+
+func (a *StringList) UnmarshalText(d []byte) error {
+	if string(d) == "" || string(d) == "-" {
+		*a = StringList{}
+		return nil
+	}
+
+	var aa StringList
+
+	for _, e := range strings.Split(string(d), ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		aa = append(aa, e)
+	}
+
+	*a = aa
+
+	return nil
+}
+
+// AudioProfileList is written like a StringList of audio profiles. The first
+// profile is the default one, which the player and zip files use unless asked
+// for another.
+type AudioProfileList []audioprofile.Profile
+
+func (a AudioProfileList) MarshalText() ([]byte, error) {
+	if len(a) == 0 {
+		return []byte("-"), nil
+	}
+
+	var s []string
+	for _, p := range a {
+		s = append(s, p.String())
+	}
+
+	return []byte(strings.Join(s, ",")), nil
+}
+
+func (a *AudioProfileList) UnmarshalText(d []byte) error {
+	if string(d) == "" || string(d) == "-" {
+		*a = AudioProfileList{}
+		return nil
+	}
+
+	var aa AudioProfileList
+
+	for _, e := range strings.Split(string(d), ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		p, err := audioprofile.Parse(e)
+		if err != nil {
+			return fmt.Errorf("config.AudioProfileList.UnmarshalText: %w", err)
+		}
+
+		if _, ok := aa.Find(p.Name); ok {
+			return fmt.Errorf("config.AudioProfileList.UnmarshalText: profile %q is listed twice", p.Name)
+		}
+
+		aa = append(aa, p)
+	}
+
+	*a = aa
+
+	return nil
+}
+
+func (a AudioProfileList) Find(name string) (audioprofile.Profile, bool) {
+	for _, p := range a {
+		if p.Name == name {
+			return p, true
+		}
+	}
+
+	return audioprofile.Profile{}, false
+}
+
+func (a AudioProfileList) Names() []string {
+	var names []string
+	for _, p := range a {
+		names = append(names, p.Name)
+	}
+
+	return names
+}
+
+// RenditionList is written like a StringList of renditions.
+type RenditionList []rendition.Rendition
+
+func (l RenditionList) MarshalText() ([]byte, error) {
+	if len(l) == 0 {
+		return []byte("-"), nil
+	}
+
+	var s []string
+	for _, r := range l {
+		s = append(s, r.String())
+	}
+
+	return []byte(strings.Join(s, ",")), nil
+}
+
+func (l *RenditionList) UnmarshalText(d []byte) error {
+	if string(d) == "" || string(d) == "-" {
+		*l = RenditionList{}
+		return nil
+	}
+
+	var ll RenditionList
+
+	for _, e := range strings.Split(string(d), ",") {
+		e = strings.TrimSpace(e)
+		if e == "" {
+			continue
+		}
+
+		r, err := rendition.Parse(e)
+		if err != nil {
+			return fmt.Errorf("config.RenditionList.UnmarshalText: %w", err)
+		}
+
+		if _, ok := ll.Find(r.Name); ok {
+			return fmt.Errorf("config.RenditionList.UnmarshalText: rendition %q is listed twice", r.Name)
+		}
+
+		ll = append(ll, r)
+	}
+
+	*l = ll
+
+	return nil
+}
+
+func (l RenditionList) Find(name string) (rendition.Rendition, bool) {
+	for _, r := range l {
+		if r.Name == name {
+			return r, true
+		}
+	}
+
+	return rendition.Rendition{}, false
+}
+
 type LogQueries struct {
 	Enabled    bool
 	SlowerThan time.Duration
@@ -109,17 +269,26 @@ func (l *LogQueries) IsZero() bool {
 }
 
 type Config struct {
-	Config               string       `name:"config" toml:"config" yaml:"config" help:"Config file location."`
-	LogLevel             logrus.Level `name:"log_level" toml:"log_level" yaml:"log_level" help:"Global log level."`
-	LogDebugLevels       LevelList    `name:"log_debug_levels" toml:"log_debug_levels" yaml:"log_debug_levels" help:"Which log levels to include stack data on."`
-	LogQueries           LogQueries   `name:"log_queries" toml:"log_queries" yaml:"log_queries" help:"Log SQL queries."`
-	LogSORM              bool         `name:"log_sorm" toml:"log_sorm" yaml:"log_sorm" help:"Log SORM queries."`
-	ApplicationAddr      string       `name:"application_addr" toml:"application_addr" yaml:"application_addr" help:"Address to listen on for application server."`
-	ApplicationDatabase  string       `name:"application_database" toml:"application_database" yaml:"application_database" help:"Database location for application."`
-	ApplicationCachePath string       `name:"application_cache_path" toml:"application_cache_path" yaml:"application_cache_path" help:"Location for HTTP client cache."`
-	ApplicationDataPath  string       `name:"application_data_path" toml:"application_data_path" yaml:"application_data_path" help:"Location for downloaded and converted media."`
-	ApplicationMinify    bool         `name:"application_minify" toml:"application_minify" yaml:"application_minify" help:"Minify HTML/CSS/JS output."`
-	BackgroundWorkers    int          `name:"background_workers" toml:"background_workers" yaml:"background_workers" help:"How many background workers to run."`
+	Config               string           `name:"config" toml:"config" yaml:"config" help:"Config file location."`
+	LogLevel             logrus.Level     `name:"log_level" toml:"log_level" yaml:"log_level" help:"Global log level."`
+	LogDebugLevels       LevelList        `name:"log_debug_levels" toml:"log_debug_levels" yaml:"log_debug_levels" help:"Which log levels to include stack data on."`
+	LogQueries           LogQueries       `name:"log_queries" toml:"log_queries" yaml:"log_queries" help:"Log SQL queries."`
+	LogSORM              bool             `name:"log_sorm" toml:"log_sorm" yaml:"log_sorm" help:"Log SORM queries."`
+	ApplicationAddr      string           `name:"application_addr" toml:"application_addr" yaml:"application_addr" help:"Address to listen on for application server."`
+	ApplicationDatabase  string           `name:"application_database" toml:"application_database" yaml:"application_database" help:"Database location for application."`
+	ApplicationCachePath string           `name:"application_cache_path" toml:"application_cache_path" yaml:"application_cache_path" help:"Location for HTTP client cache."`
+	ApplicationDataPath  string           `name:"application_data_path" toml:"application_data_path" yaml:"application_data_path" help:"Location for downloaded and converted media."`
+	ApplicationMinify    bool             `name:"application_minify" toml:"application_minify" yaml:"application_minify" help:"Minify HTML/CSS/JS output."`
+	BackgroundWorkers    int              `name:"background_workers" toml:"background_workers" yaml:"background_workers" help:"How many background workers to run."`
+	MetadataChannel      StringList       `name:"metadata_channel" toml:"metadata_channel" yaml:"metadata_channel" help:"Metadata sources to try for channels, in order (ytdirect, ytdlp)."`
+	MetadataPlaylist     StringList       `name:"metadata_playlist" toml:"metadata_playlist" yaml:"metadata_playlist" help:"Metadata sources to try for playlists, in order (ytdirect, ytdlp)."`
+	MetadataVideo        StringList       `name:"metadata_video" toml:"metadata_video" yaml:"metadata_video" help:"Metadata sources to try for videos, in order (ytdirect, ytdlp)."`
+	SubtitleLanguages    StringList       `name:"subtitle_languages" toml:"subtitle_languages" yaml:"subtitle_languages" help:"Subtitle languages to download, in yt-dlp's --sub-langs syntax."`
+	SplitChapters        bool             `name:"split_chapters" toml:"split_chapters" yaml:"split_chapters" help:"Split the audio of videos with chapters into a file per chapter."`
+	AudioProfiles        AudioProfileList `name:"audio_profiles" toml:"audio_profiles" yaml:"audio_profiles" help:"Audio files to make for each video, as name:codec[:bitrate or qN[:container]] (codecs are aac, flac, mp3, opus). The first is the default."`
+	DownloadProfile      string           `name:"download_profile" toml:"download_profile" yaml:"download_profile" help:"How to download videos unless their channel or playlist says otherwise (video, audio)."`
+	NormalizeAudio       bool             `name:"normalize_audio" toml:"normalize_audio" yaml:"normalize_audio" help:"Also make a copy of each video's audio normalized to a standard loudness."`
+	Renditions           RenditionList    `name:"renditions" toml:"renditions" yaml:"renditions" help:"Smaller copies of each video to make for streaming, as name:height:codec[:crf[:preset]] (codecs are h264, h265)."`
 }
 
 func (c Config) DataFile(section, name string) string {
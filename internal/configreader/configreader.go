@@ -15,26 +15,30 @@ import (
 	"fknsrs.biz/p/ytmusic/internal/stringutil"
 )
 
-func Read(program string, arguments, environment []string, out interface{}) error {
+// Read fills out in from a config file, command-line flags and environment
+// variables, in that order. It returns the arguments left over after the
+// flags.
+func Read(program string, arguments, environment []string, out interface{}) ([]string, error) {
 	if _, _, err := getValueAndType(out); err != nil {
-		return fmt.Errorf("configreader.Read: could not get value and type: %w", err)
+		return nil, fmt.Errorf("configreader.Read: could not get value and type: %w", err)
 	}
 
 	if configPath, ok := getFromArgumentsOrEnvironmentOrObject(arguments, environment, out, "config"); ok && configPath != "" {
 		if err := readFile(configPath, out); err != nil {
-			return fmt.Errorf("configreader.Read: %w", err)
+			return nil, fmt.Errorf("configreader.Read: %w", err)
 		}
 	}
 
-	if err := readArguments(program, arguments, out); err != nil {
-		return fmt.Errorf("configreader.Read: could not read command-line flags: %w", err)
+	rest, err := readArguments(program, arguments, out)
+	if err != nil {
+		return nil, fmt.Errorf("configreader.Read: could not read command-line flags: %w", err)
 	}
 
 	if err := readEnvironment(program, environment, out); err != nil {
-		return fmt.Errorf("configreader.Read: could not read environment variables: %w", err)
+		return nil, fmt.Errorf("configreader.Read: could not read environment variables: %w", err)
 	}
 
-	return nil
+	return rest, nil
 }
 
 func getValueAndType(v interface{}) (reflect.Value, reflect.Type, error) {
@@ -186,16 +190,16 @@ func readFileTOML(filePath string, out interface{}) error {
 	return nil
 }
 
-func readArguments(program string, arguments []string, out interface{}) error {
+func readArguments(program string, arguments []string, out interface{}) ([]string, error) {
 	val, typ, err := getValueAndType(out)
 	if err != nil {
-		return fmt.Errorf("configreader.readArguments: could not get value and type: %w", err)
+		return nil, fmt.Errorf("configreader.readArguments: could not get value and type: %w", err)
 	}
 
 	flagSet := flag.NewFlagSet(program, flag.ContinueOnError)
 
 	flagSet.Usage = func() {
-		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS]\n", program)
+		fmt.Fprintf(os.Stderr, "Usage: %s [OPTIONS] [COMMAND]\n", program)
 		flagSet.PrintDefaults()
 		os.Exit(0)
 	}
@@ -219,15 +223,15 @@ func readArguments(program string, arguments []string, out interface{}) error {
 		case reflect.PointerTo(tf.Type).Implements(encodingTextType):
 			flagSet.TextVar(vf.Addr().Interface().(encoding.TextUnmarshaler), name, vf.Addr().Interface().(encoding.TextMarshaler), help)
 		default:
-			return fmt.Errorf("configreader.readArguments: could not define flag for parameter %s (%s) with type %s", tf.Name, name, tf.Type)
+			return nil, fmt.Errorf("configreader.readArguments: could not define flag for parameter %s (%s) with type %s", tf.Name, name, tf.Type)
 		}
 	}
 
 	if err := flagSet.Parse(arguments); err != nil {
-		return err
+		return nil, err
 	}
 
-	return nil
+	return flagSet.Args(), nil
 }
 
 func readEnvironment(program string, environment []string, out interface{}) error {
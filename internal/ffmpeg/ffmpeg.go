@@ -4,8 +4,12 @@ import (
 	"bufio"
 	"bytes"
 	"context"
+	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
 	"strings"
@@ -38,32 +42,30 @@ func MakeThumbnail(ctx context.Context, videoFile, imageFile string) (string, er
 
 type ProgressCallback func(progress int)
 
-func Transcode(ctx context.Context, inputFile, size, outputFile string) (string, error) {
-	return TranscodeWithProgress(ctx, inputFile, size, outputFile, nil)
+// Transcode encodes inputFile into outputFile. codecArgs are the output
+// options that scale the video and choose the encoders.
+func Transcode(ctx context.Context, inputFile, outputFile string, codecArgs []string) (string, error) {
+	return TranscodeWithProgress(ctx, inputFile, outputFile, codecArgs, nil)
 }
 
-func TranscodeWithProgress(ctx context.Context, inputFile, size, outputFile string, progressCallback ProgressCallback) (string, error) {
+func TranscodeWithProgress(ctx context.Context, inputFile, outputFile string, codecArgs []string, progressCallback ProgressCallback) (string, error) {
 	// First, get the duration of the input file for progress calculation
-	duration, err := getVideoDuration(ctx, inputFile)
+	info, _, err := Probe(ctx, inputFile)
 	if err != nil {
 		return "", fmt.Errorf("failed to get video duration: %w", err)
 	}
+	duration := info.Duration
 
-	cmd := exec.CommandContext(
-		ctx, "ffmpeg",
+	args := []string{
 		"-y",
 		"-progress", "pipe:1",
 		"-loglevel", "warning",
 		"-i", inputFile,
-		"-vf", "scale="+size,
-		"-c:v", "libx264",
-		"-crf", "23",
-		"-tune", "fastdecode",
-		"-preset", "veryslow",
-		"-c:a", "libmp3lame",
-		"-q:a", "2",
-		outputFile,
-	)
+	}
+	args = append(args, codecArgs...)
+	args = append(args, outputFile)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
 
 	if progressCallback == nil {
 		// Use the simple version without progress tracking
@@ -134,49 +136,598 @@ func TranscodeWithProgress(ctx context.Context, inputFile, size, outputFile stri
 	return output.String(), nil
 }
 
-// getVideoDuration extracts the duration of a video file using ffprobe
-func getVideoDuration(ctx context.Context, inputFile string) (time.Duration, error) {
+// MediaInfo is what ffprobe finds in a file. The video fields are empty for
+// audio files, and cover art embedded in an audio file doesn't count as
+// video. The profiles are ffprobe's names for them, like "High" or "LC", and
+// VideoLevel is the level as the codec writes it, so 4.1 is 41 for h264 and
+// 123 for hevc.
+type MediaInfo struct {
+	Format       string
+	Size         int64
+	Duration     time.Duration
+	BitRate      int
+	VideoCodec   string
+	VideoProfile string
+	VideoLevel   int
+	Width        int
+	Height       int
+	AudioCodec   string
+	AudioProfile string
+	SampleRate   int
+	Channels     int
+}
+
+// Check makes sure a file that was just made is whole: ffprobe found audio or
+// video in it, and it's as long as expected, give or take a second or one
+// percent, whichever is more. An expected duration of zero only checks that
+// it has some length at all.
+func (m MediaInfo) Check(expected time.Duration) error {
+	if m.VideoCodec == "" && m.AudioCodec == "" {
+		return fmt.Errorf("no audio or video in the file")
+	}
+
+	if m.Duration <= 0 {
+		return fmt.Errorf("the file has no duration")
+	}
+
+	if expected <= 0 {
+		return nil
+	}
+
+	tolerance := expected / 100
+	if tolerance < time.Second {
+		tolerance = time.Second
+	}
+
+	if d := m.Duration - expected; d < -tolerance || d > tolerance {
+		return fmt.Errorf("the file is %s long, but should be %s", m.Duration.Round(time.Millisecond), expected.Round(time.Millisecond))
+	}
+
+	return nil
+}
+
+// Probe inspects a file with ffprobe.
+func Probe(ctx context.Context, inputFile string) (*MediaInfo, string, error) {
 	cmd := exec.CommandContext(
 		ctx, "ffprobe",
-		"-v", "quiet",
-		"-show_entries", "format=duration",
-		"-of", "csv=p=0",
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
 		inputFile,
 	)
 
-	output, err := cmd.Output()
+	var stdout, stderr bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	if err := cmd.Run(); err != nil {
+		return nil, stderr.String(), fmt.Errorf("ffmpeg.Probe: %w", err)
+	}
+
+	info, err := parseProbe(stdout.Bytes())
 	if err != nil {
-		return 0, fmt.Errorf("failed to get duration: %w", err)
+		return nil, stderr.String(), fmt.Errorf("ffmpeg.Probe: %w", err)
+	}
+
+	return info, stderr.String(), nil
+}
+
+// parseProbe reads ffprobe's JSON output. ffprobe writes most numbers as
+// strings, and leaves out whatever it couldn't work out.
+func parseProbe(output []byte) (*MediaInfo, error) {
+	var m struct {
+		Format struct {
+			FormatName string `json:"format_name"`
+			Size       string `json:"size"`
+			Duration   string `json:"duration"`
+			BitRate    string `json:"bit_rate"`
+		} `json:"format"`
+		Streams []struct {
+			CodecType   string `json:"codec_type"`
+			CodecName   string `json:"codec_name"`
+			Profile     string `json:"profile"`
+			Level       int    `json:"level"`
+			Width       int    `json:"width"`
+			Height      int    `json:"height"`
+			SampleRate  string `json:"sample_rate"`
+			Channels    int    `json:"channels"`
+			Disposition struct {
+				AttachedPic int `json:"attached_pic"`
+			} `json:"disposition"`
+		} `json:"streams"`
+	}
+	if err := json.Unmarshal(output, &m); err != nil {
+		return nil, fmt.Errorf("couldn't parse ffprobe output: %w", err)
+	}
+
+	if m.Format.FormatName == "" {
+		return nil, fmt.Errorf("ffprobe didn't recognise the format")
+	}
+
+	info := MediaInfo{Format: m.Format.FormatName}
+
+	if m.Format.Size != "" {
+		v, err := strconv.ParseInt(m.Format.Size, 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse size: %w", err)
+		}
+		info.Size = v
+	}
+
+	if m.Format.Duration != "" {
+		v, err := strconv.ParseFloat(m.Format.Duration, 64)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse duration: %w", err)
+		}
+		info.Duration = time.Duration(v * float64(time.Second))
+	}
+
+	if m.Format.BitRate != "" {
+		v, err := strconv.Atoi(m.Format.BitRate)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse bit rate: %w", err)
+		}
+		info.BitRate = v
+	}
+
+	for _, stream := range m.Streams {
+		switch stream.CodecType {
+		case "video":
+			if info.VideoCodec != "" || stream.Disposition.AttachedPic != 0 {
+				continue
+			}
+
+			info.VideoCodec = stream.CodecName
+			info.VideoProfile = stream.Profile
+			info.VideoLevel = stream.Level
+			info.Width = stream.Width
+			info.Height = stream.Height
+		case "audio":
+			if info.AudioCodec != "" {
+				continue
+			}
+
+			info.AudioCodec = stream.CodecName
+			info.AudioProfile = stream.Profile
+			info.Channels = stream.Channels
+
+			if stream.SampleRate != "" {
+				v, err := strconv.Atoi(stream.SampleRate)
+				if err != nil {
+					return nil, fmt.Errorf("couldn't parse sample rate: %w", err)
+				}
+				info.SampleRate = v
+			}
+		}
+	}
+
+	return &info, nil
+}
+
+// CutAudio copies the part of audioFile from start to end into outputFile,
+// without re-encoding it. An end of zero means the rest of the file.
+func CutAudio(ctx context.Context, audioFile string, start, end time.Duration, outputFile string) (string, error) {
+	args := []string{
+		"-y",
+		"-loglevel", "warning",
+		"-i", audioFile,
+		"-ss", formatSeconds(start),
+	}
+	if end > 0 {
+		args = append(args, "-to", formatSeconds(end))
+	}
+	args = append(args, "-c", "copy", outputFile)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.CutAudio: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+func formatSeconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
+
+// ExtractAudio encodes the audio from inputFile, which can be a video or
+// another audio file, into audioFile. codecArgs are the output options that
+// choose and configure the encoder, e.g. "-c:a libmp3lame -q:a 2".
+func ExtractAudio(ctx context.Context, inputFile, audioFile string, codecArgs []string) (string, error) {
+	args := []string{
+		"-y",
+		"-loglevel", "warning",
+		"-i", inputFile,
+		"-vn",
+	}
+	args = append(args, codecArgs...)
+	args = append(args, audioFile)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.ExtractAudio: %w", err)
 	}
 
-	durationStr := strings.TrimSpace(string(output))
-	if durationSeconds, err := strconv.ParseFloat(durationStr, 64); err == nil {
-		return time.Duration(durationSeconds * float64(time.Second)), nil
+	return buf.String(), nil
+}
+
+// Tags are the details written into an audio file for music players. Empty
+// fields are left out, as is Track when it's zero. TrackGain and AlbumGain
+// become ReplayGain tags when they're set.
+type Tags struct {
+	Title     string
+	Artist    string
+	Album     string
+	Track     int
+	Date      string
+	TrackGain *Loudness
+	AlbumGain *Loudness
+}
+
+func (t Tags) args() []string {
+	var args []string
+
+	for _, e := range []struct{ key, value string }{
+		{"title", t.Title},
+		{"artist", t.Artist},
+		{"album", t.Album},
+		{"date", t.Date},
+	} {
+		if e.value != "" {
+			args = append(args, "-metadata", e.key+"="+e.value)
+		}
 	}
 
-	return 0, fmt.Errorf("failed to parse duration: %s", durationStr)
+	if t.Track > 0 {
+		args = append(args, "-metadata", "track="+strconv.Itoa(t.Track))
+	}
+
+	for _, e := range []struct {
+		key      string
+		loudness *Loudness
+	}{
+		{"REPLAYGAIN_TRACK", t.TrackGain},
+		{"REPLAYGAIN_ALBUM", t.AlbumGain},
+	} {
+		if e.loudness != nil {
+			args = append(args,
+				"-metadata", e.key+"_GAIN="+strconv.FormatFloat(e.loudness.Gain(), 'f', 2, 64)+" dB",
+				"-metadata", e.key+"_PEAK="+strconv.FormatFloat(e.loudness.Peak(), 'f', 6, 64),
+			)
+		}
+	}
+
+	return args
 }
 
-func ExtractAudio(ctx context.Context, videoFile, audioFile string) (string, error) {
+// WriteTags copies audioFile into outputFile with its tags replaced, and its
+// cover art with coverFile if that isn't empty. The audio is copied rather
+// than encoded again. outputFile has to have the same extension as
+// audioFile.
+func WriteTags(ctx context.Context, audioFile, coverFile string, tags Tags, outputFile string) (string, error) {
+	ext := filepath.Ext(audioFile)
+
+	args := []string{
+		"-y",
+		"-loglevel", "warning",
+		"-i", audioFile,
+	}
+	if coverFile != "" {
+		args = append(args, "-i", coverFile)
+	}
+
+	// only the audio comes across, which drops any old cover
+	args = append(args, "-map", "0:a", "-map_metadata", "-1")
+	if coverFile != "" {
+		args = append(args, "-map", "1:v", "-disposition:v", "attached_pic")
+	}
+	if ext == ".mp3" {
+		// the most widely understood version
+		args = append(args, "-id3v2_version", "3")
+	}
+	if ext == ".m4a" {
+		// otherwise keys mp4 doesn't know about, like ReplayGain's, are dropped
+		args = append(args, "-movflags", "use_metadata_tags")
+	}
+
+	args = append(args, "-c", "copy")
+	args = append(args, tags.args()...)
+	args = append(args, outputFile)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.WriteTags: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// ReferenceLoudness is the level, in LUFS, that ReplayGain 2.0 brings tracks
+// to. Normalized audio is made at the same level, so that it sounds the same
+// as audio played back with its gain applied.
+const ReferenceLoudness = -18.0
+
+// Loudness is what the first pass of ffmpeg's loudnorm filter measures.
+// Integrated and Range are in LUFS and LU, TruePeak is in dBTP.
+type Loudness struct {
+	Integrated float64
+	TruePeak   float64
+	Range      float64
+	Threshold  float64
+	Offset     float64
+}
+
+// Gain is how many dB to adjust the audio by to reach ReferenceLoudness.
+func (l Loudness) Gain() float64 {
+	return ReferenceLoudness - l.Integrated
+}
+
+// Peak is the true peak as a fraction of full scale, the way ReplayGain
+// writes it.
+func (l Loudness) Peak() float64 {
+	return math.Pow(10, l.TruePeak/20)
+}
+
+// normalizeFilter is the loudnorm filter for making audio at
+// ReferenceLoudness, without letting it peak above -1 dBTP.
+func normalizeFilter() string {
+	return fmt.Sprintf("loudnorm=I=%s:TP=-1:LRA=11", strconv.FormatFloat(ReferenceLoudness, 'f', -1, 64))
+}
+
+// MeasureLoudness runs the first pass of the loudnorm filter over the audio
+// in inputFile.
+func MeasureLoudness(ctx context.Context, inputFile string) (*Loudness, string, error) {
 	cmd := exec.CommandContext(
 		ctx, "ffmpeg",
+		"-hide_banner",
+		"-nostats",
+		"-i", inputFile,
+		"-vn",
+		"-af", normalizeFilter()+":print_format=json",
+		"-f", "null",
+		"-",
+	)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return nil, buf.String(), fmt.Errorf("ffmpeg.MeasureLoudness: %w", err)
+	}
+
+	l, err := parseLoudness(buf.String())
+	if err != nil {
+		return nil, buf.String(), fmt.Errorf("ffmpeg.MeasureLoudness: %w", err)
+	}
+
+	return l, buf.String(), nil
+}
+
+// parseLoudness finds the measurements that loudnorm prints as JSON at the
+// end of ffmpeg's output.
+func parseLoudness(output string) (*Loudness, error) {
+	start, end := strings.LastIndex(output, "{"), strings.LastIndex(output, "}")
+	if start == -1 || end < start {
+		return nil, fmt.Errorf("couldn't find loudnorm measurements in output")
+	}
+
+	var m struct {
+		InputI      string `json:"input_i"`
+		InputTP     string `json:"input_tp"`
+		InputLRA    string `json:"input_lra"`
+		InputThresh string `json:"input_thresh"`
+		Offset      string `json:"target_offset"`
+	}
+	if err := json.Unmarshal([]byte(output[start:end+1]), &m); err != nil {
+		return nil, fmt.Errorf("couldn't parse loudnorm measurements: %w", err)
+	}
+
+	var l Loudness
+	for _, e := range []struct {
+		name string
+		in   string
+		out  *float64
+	}{
+		{"input_i", m.InputI, &l.Integrated},
+		{"input_tp", m.InputTP, &l.TruePeak},
+		{"input_lra", m.InputLRA, &l.Range},
+		{"input_thresh", m.InputThresh, &l.Threshold},
+		{"target_offset", m.Offset, &l.Offset},
+	} {
+		v, err := strconv.ParseFloat(e.in, 64)
+		if err != nil {
+			return nil, fmt.Errorf("couldn't parse loudnorm %s: %w", e.name, err)
+		}
+		*e.out = v
+	}
+
+	// silence has no loudness to speak of
+	if math.IsInf(l.Integrated, 0) || math.IsInf(l.TruePeak, 0) {
+		return nil, fmt.Errorf("audio is silent")
+	}
+
+	return &l, nil
+}
+
+// Normalize runs the second pass of the loudnorm filter, using measurements
+// from MeasureLoudness, and encodes the result into audioFile with codecArgs
+// like ExtractAudio does. loudnorm works at 192kHz and its output stays that
+// way unless it's told otherwise, so sampleRate should be the input's, or
+// one the encoder takes if it doesn't take that.
+func Normalize(ctx context.Context, inputFile, audioFile string, measured Loudness, sampleRate int, codecArgs []string) (string, error) {
+	format := func(f float64) string { return strconv.FormatFloat(f, 'f', 2, 64) }
+
+	filter := normalizeFilter() +
+		":measured_I=" + format(measured.Integrated) +
+		":measured_TP=" + format(measured.TruePeak) +
+		":measured_LRA=" + format(measured.Range) +
+		":measured_thresh=" + format(measured.Threshold) +
+		":offset=" + format(measured.Offset) +
+		":linear=true"
+
+	args := []string{
 		"-y",
 		"-loglevel", "warning",
-		"-i", videoFile,
+		"-i", inputFile,
 		"-vn",
-		"-c:a", "libmp3lame",
-		"-q:a", "2",
-		audioFile,
+		"-af", filter,
+		"-ar", strconv.Itoa(sampleRate),
+	}
+	args = append(args, codecArgs...)
+	args = append(args, audioFile)
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.Normalize: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// DecodeAudio decodes the audio in inputFile to mono signed 16-bit
+// little-endian PCM at sampleRate, and hands it to read as it's decoded.
+// ffmpeg is stopped if read returns an error.
+func DecodeAudio(ctx context.Context, inputFile string, sampleRate int, read func(r io.Reader) error) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	cmd := exec.CommandContext(
+		ctx, "ffmpeg",
+		"-loglevel", "warning",
+		"-i", inputFile,
+		"-vn",
+		"-ac", "1",
+		"-ar", strconv.Itoa(sampleRate),
+		"-f", "s16le",
+		"-",
 	)
 
 	var buf bytes.Buffer
 
+	cmd.Stdin = nil
+	cmd.Stderr = &buf
+
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return "", fmt.Errorf("ffmpeg.DecodeAudio: %w", err)
+	}
+
+	if err := cmd.Start(); err != nil {
+		return "", fmt.Errorf("ffmpeg.DecodeAudio: %w", err)
+	}
+
+	if err := read(stdout); err != nil {
+		cancel()
+		cmd.Wait()
+		return buf.String(), fmt.Errorf("ffmpeg.DecodeAudio: %w", err)
+	}
+
+	if err := cmd.Wait(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.DecodeAudio: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// PackageHLS splits videoFile into HLS segments in dir, with a media
+// playlist called index.m3u8. The streams are copied rather than encoded
+// again, so segments are cut at the video's own keyframes. Segments are
+// MPEG-TS unless fragmented is set, in which case they're fragmented MP4
+// with an init.mp4, which is the only way players take hevc over HLS.
+func PackageHLS(ctx context.Context, videoFile, dir string, fragmented bool) (string, error) {
+	args := []string{
+		"-y",
+		"-loglevel", "warning",
+		"-i", videoFile,
+		"-c", "copy",
+		"-f", "hls",
+		"-hls_time", "6",
+		"-hls_playlist_type", "vod",
+	}
+
+	if fragmented {
+		args = append(
+			args,
+			"-hls_segment_type", "fmp4",
+			"-hls_fmp4_init_filename", "init.mp4",
+			"-hls_segment_filename", filepath.Join(dir, "%03d.m4s"),
+		)
+	} else {
+		args = append(args, "-hls_segment_filename", filepath.Join(dir, "%03d.ts"))
+	}
+
+	cmd := exec.CommandContext(ctx, "ffmpeg", append(args, filepath.Join(dir, "index.m3u8"))...)
+
+	var buf bytes.Buffer
+
 	cmd.Stdin = nil
 	cmd.Stdout = &buf
 	cmd.Stderr = &buf
 
 	if err := cmd.Run(); err != nil {
-		return buf.String(), fmt.Errorf("ffmpeg.ExtractAudio: %w", err)
+		return buf.String(), fmt.Errorf("ffmpeg.PackageHLS: %w", err)
+	}
+
+	return buf.String(), nil
+}
+
+// ExtractFrames takes a frame from videoFile every interval, scaled to
+// width, and writes them to dir as 00001.jpg, 00002.jpg, and so on. The
+// first is from the very start of the video.
+func ExtractFrames(ctx context.Context, videoFile string, interval time.Duration, width int, dir string) (string, error) {
+	cmd := exec.CommandContext(
+		ctx, "ffmpeg",
+		"-y",
+		"-loglevel", "warning",
+		"-i", videoFile,
+		"-an",
+		"-vf", fmt.Sprintf("fps=1/%s,scale=%d:-2", strconv.FormatFloat(interval.Seconds(), 'f', -1, 64), width),
+		"-q:v", "5",
+		filepath.Join(dir, "%05d.jpg"),
+	)
+
+	var buf bytes.Buffer
+
+	cmd.Stdin = nil
+	cmd.Stdout = &buf
+	cmd.Stderr = &buf
+
+	if err := cmd.Run(); err != nil {
+		return buf.String(), fmt.Errorf("ffmpeg.ExtractFrames: %w", err)
 	}
 
 	return buf.String(), nil
@@ -0,0 +1,156 @@
+package ffmpeg
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const loudnormOutput = `Input #0, mov,mp4,m4a,3gp,3g2,mj2, from 'video.mp4':
+  Duration: 00:03:32.07, start: 0.000000, bitrate: 1053 kb/s
+[Parsed_loudnorm_0 @ 0x5581d1b3c6c0] 
+{
+	"input_i" : "-9.87",
+	"input_tp" : "0.45",
+	"input_lra" : "5.20",
+	"input_thresh" : "-20.01",
+	"output_i" : "-18.02",
+	"output_tp" : "-1.00",
+	"output_lra" : "4.90",
+	"output_thresh" : "-28.12",
+	"normalization_type" : "dynamic",
+	"target_offset" : "0.02"
+}
+`
+
+func TestParseLoudness(t *testing.T) {
+	a := assert.New(t)
+
+	l, err := parseLoudness(loudnormOutput)
+	a.NoError(err)
+	a.Equal(&Loudness{Integrated: -9.87, TruePeak: 0.45, Range: 5.2, Threshold: -20.01, Offset: 0.02}, l)
+	a.InDelta(-8.13, l.Gain(), 0.001)
+	a.InDelta(1.053, l.Peak(), 0.001)
+}
+
+func TestParseLoudnessSilent(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := parseLoudness(`{"input_i" : "-inf", "input_tp" : "-inf", "input_lra" : "0.00", "input_thresh" : "-70.00", "target_offset" : "inf"}`)
+	a.Error(err)
+}
+
+func TestParseLoudnessMissing(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := parseLoudness("Error opening input file video.mp4.\n")
+	a.Error(err)
+}
+
+func TestTagsArgs(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]string{
+		"-metadata", "title=Song",
+		"-metadata", "track=3",
+		"-metadata", "REPLAYGAIN_TRACK_GAIN=-8.13 dB",
+		"-metadata", "REPLAYGAIN_TRACK_PEAK=1.053174",
+	}, Tags{Title: "Song", Track: 3, TrackGain: &Loudness{Integrated: -9.87, TruePeak: 0.45}}.args())
+}
+
+const probeOutput = `{
+    "streams": [
+        {
+            "index": 0,
+            "codec_name": "mp3",
+            "codec_type": "audio",
+            "sample_rate": "44100",
+            "channels": 2,
+            "disposition": {
+                "attached_pic": 0
+            }
+        },
+        {
+            "index": 1,
+            "codec_name": "mjpeg",
+            "codec_type": "video",
+            "width": 480,
+            "height": 360,
+            "disposition": {
+                "attached_pic": 1
+            }
+        }
+    ],
+    "format": {
+        "filename": "video.mp3",
+        "format_name": "mp3",
+        "duration": "212.071837",
+        "size": "3428911",
+        "bit_rate": "129353"
+    }
+}
+`
+
+func TestParseProbe(t *testing.T) {
+	a := assert.New(t)
+
+	info, err := parseProbe([]byte(probeOutput))
+	a.NoError(err)
+	a.Equal(&MediaInfo{
+		Format:     "mp3",
+		Size:       3428911,
+		Duration:   212071837 * time.Microsecond,
+		BitRate:    129353,
+		AudioCodec: "mp3",
+		SampleRate: 44100,
+		Channels:   2,
+	}, info)
+}
+
+func TestParseProbeVideo(t *testing.T) {
+	a := assert.New(t)
+
+	info, err := parseProbe([]byte(`{
+		"streams": [
+			{"codec_name": "h264", "profile": "High", "level": 31, "codec_type": "video", "width": 1280, "height": 720},
+			{"codec_name": "aac", "profile": "LC", "level": -99, "codec_type": "audio", "sample_rate": "48000", "channels": 2}
+		],
+		"format": {"format_name": "mov,mp4,m4a,3gp,3g2,mj2", "duration": "10.000000", "size": "1000", "bit_rate": "800"}
+	}`))
+	a.NoError(err)
+	a.Equal("h264", info.VideoCodec)
+	a.Equal("High", info.VideoProfile)
+	a.Equal(31, info.VideoLevel)
+	a.Equal(1280, info.Width)
+	a.Equal(720, info.Height)
+	a.Equal("aac", info.AudioCodec)
+	a.Equal("LC", info.AudioProfile)
+	a.Equal(10*time.Second, info.Duration)
+}
+
+func TestParseProbeUnrecognised(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := parseProbe([]byte(`{}`))
+	a.Error(err)
+}
+
+func TestMediaInfoCheck(t *testing.T) {
+	a := assert.New(t)
+
+	audio := MediaInfo{AudioCodec: "mp3", Duration: 212 * time.Second}
+
+	a.NoError(audio.Check(0))
+	a.NoError(audio.Check(212 * time.Second))
+	a.NoError(audio.Check(212500 * time.Millisecond))
+	a.NoError(audio.Check(214 * time.Second))
+	a.Error(audio.Check(215 * time.Second))
+	a.Error(audio.Check(10 * time.Minute))
+
+	// one percent of a long video is more than a second
+	a.NoError(MediaInfo{VideoCodec: "h264", Duration: time.Hour}.Check(time.Hour + 30*time.Second))
+
+	a.Error(MediaInfo{Duration: time.Second}.Check(0))
+	a.Error(MediaInfo{AudioCodec: "mp3"}.Check(0))
+}
@@ -0,0 +1,198 @@
+// Package hls writes the master playlist that ties a video's HLS renditions
+// together. The renditions themselves are segmented by ffmpeg; this only
+// reads their media playlists to work out how much bandwidth each needs.
+package hls
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variant is one rendition in a master playlist. URI is relative to the
+// master playlist. Bandwidth is the peak bitrate of any segment, and
+// AverageBandwidth is over the whole rendition, both in bits per second.
+// Resolution is like "1280x720", and Codecs is what Codecs returns; either
+// is left out of the playlist when it's empty.
+type Variant struct {
+	URI              string
+	Bandwidth        int
+	AverageBandwidth int
+	Resolution       string
+	Codecs           string
+}
+
+// avcProfiles are the profile_idc and constraint flags bytes, in hex, for
+// ffprobe's names for h264 profiles.
+var avcProfiles = map[string]string{
+	"Baseline":              "4200",
+	"Constrained Baseline":  "42E0",
+	"Main":                  "4D40",
+	"Extended":              "5800",
+	"High":                  "6400",
+	"High 10":               "6E00",
+	"High 4:2:2":            "7A00",
+	"High 4:4:4 Predictive": "F400",
+}
+
+// hevcProfiles are the general_profile_idc and compatibility flags for
+// ffprobe's names for hevc profiles.
+var hevcProfiles = map[string]string{
+	"Main":    "1.6",
+	"Main 10": "2.4",
+}
+
+// Codecs makes the CODECS attribute for a rendition, as RFC 6381 strings,
+// from what ffprobe says about its streams. Either codec can be empty if the
+// rendition doesn't have that kind of stream.
+func Codecs(videoCodec, videoProfile string, videoLevel int, audioCodec, audioProfile string) (string, error) {
+	var codecs []string
+
+	switch videoCodec {
+	case "":
+	case "h264":
+		p, ok := avcProfiles[videoProfile]
+		if !ok || videoLevel <= 0 {
+			return "", fmt.Errorf("hls.Codecs: unrecognised h264 profile %q level %d", videoProfile, videoLevel)
+		}
+		codecs = append(codecs, fmt.Sprintf("avc1.%s%02X", p, videoLevel))
+	case "hevc":
+		p, ok := hevcProfiles[videoProfile]
+		if !ok || videoLevel <= 0 {
+			return "", fmt.Errorf("hls.Codecs: unrecognised hevc profile %q level %d", videoProfile, videoLevel)
+		}
+		// main tier, progressive frames only, which is what x265 makes
+		codecs = append(codecs, fmt.Sprintf("hvc1.%s.L%d.90", p, videoLevel))
+	default:
+		return "", fmt.Errorf("hls.Codecs: unrecognised video codec %q", videoCodec)
+	}
+
+	switch audioCodec {
+	case "":
+	case "aac":
+		switch audioProfile {
+		case "", "LC":
+			codecs = append(codecs, "mp4a.40.2")
+		case "HE-AAC":
+			codecs = append(codecs, "mp4a.40.5")
+		case "HE-AACv2":
+			codecs = append(codecs, "mp4a.40.29")
+		default:
+			return "", fmt.Errorf("hls.Codecs: unrecognised aac profile %q", audioProfile)
+		}
+	case "mp3":
+		codecs = append(codecs, "mp4a.40.34")
+	case "ac3":
+		codecs = append(codecs, "ac-3")
+	case "eac3":
+		codecs = append(codecs, "ec-3")
+	case "opus":
+		codecs = append(codecs, "Opus")
+	case "flac":
+		codecs = append(codecs, "fLaC")
+	default:
+		return "", fmt.Errorf("hls.Codecs: unrecognised audio codec %q", audioCodec)
+	}
+
+	if len(codecs) == 0 {
+		return "", fmt.Errorf("hls.Codecs: no audio or video")
+	}
+
+	return strings.Join(codecs, ","), nil
+}
+
+// MeasureVariant reads the media playlist at dir/name and the segments it
+// lists, which are relative to the playlist.
+func MeasureVariant(dir, name string) (*Variant, error) {
+	f, err := os.Open(filepath.Join(dir, name))
+	if err != nil {
+		return nil, fmt.Errorf("hls.MeasureVariant: %w", err)
+	}
+	defer f.Close()
+
+	var (
+		duration, total, peak float64
+		segmentDuration       float64
+		segments              int
+	)
+
+	s := bufio.NewScanner(f)
+	for s.Scan() {
+		line := strings.TrimSpace(s.Text())
+
+		switch {
+		case strings.HasPrefix(line, "#EXTINF:"):
+			d, err := strconv.ParseFloat(strings.SplitN(strings.TrimPrefix(line, "#EXTINF:"), ",", 2)[0], 64)
+			if err != nil {
+				return nil, fmt.Errorf("hls.MeasureVariant: couldn't parse %q: %w", line, err)
+			}
+			segmentDuration = d
+		case line == "" || strings.HasPrefix(line, "#"):
+		default:
+			st, err := os.Stat(filepath.Join(dir, filepath.Dir(name), line))
+			if err != nil {
+				return nil, fmt.Errorf("hls.MeasureVariant: %w", err)
+			}
+
+			bits := float64(st.Size() * 8)
+			if segmentDuration > 0 && bits/segmentDuration > peak {
+				peak = bits / segmentDuration
+			}
+
+			duration += segmentDuration
+			total += bits
+			segmentDuration = 0
+			segments++
+		}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("hls.MeasureVariant: %w", err)
+	}
+
+	if segments == 0 || duration == 0 {
+		return nil, fmt.Errorf("hls.MeasureVariant: %s has no segments", name)
+	}
+
+	return &Variant{
+		URI:              name,
+		Bandwidth:        int(peak + 0.5),
+		AverageBandwidth: int(total/duration + 0.5),
+	}, nil
+}
+
+// WriteMaster writes a master playlist listing variants, lowest bandwidth
+// first.
+func WriteMaster(w io.Writer, variants []Variant) error {
+	variants = append([]Variant(nil), variants...)
+	sort.SliceStable(variants, func(i, j int) bool {
+		return variants[i].Bandwidth < variants[j].Bandwidth
+	})
+
+	var b strings.Builder
+
+	b.WriteString("#EXTM3U\n")
+	b.WriteString("#EXT-X-VERSION:3\n")
+
+	for _, v := range variants {
+		fmt.Fprintf(&b, "#EXT-X-STREAM-INF:BANDWIDTH=%d,AVERAGE-BANDWIDTH=%d", v.Bandwidth, v.AverageBandwidth)
+		if v.Resolution != "" {
+			fmt.Fprintf(&b, ",RESOLUTION=%s", v.Resolution)
+		}
+		if v.Codecs != "" {
+			fmt.Fprintf(&b, ",CODECS=%q", v.Codecs)
+		}
+		b.WriteString("\n")
+		b.WriteString(v.URI + "\n")
+	}
+
+	if _, err := io.WriteString(w, b.String()); err != nil {
+		return fmt.Errorf("hls.WriteMaster: %w", err)
+	}
+
+	return nil
+}
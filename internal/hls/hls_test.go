@@ -0,0 +1,129 @@
+package hls
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func writeFile(t *testing.T, name string, size int) {
+	if err := os.MkdirAll(filepath.Dir(name), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(name, make([]byte, size), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestMeasureVariant(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	writeFile(t, filepath.Join(dir, "360", "000.ts"), 6000)
+	writeFile(t, filepath.Join(dir, "360", "001.ts"), 1000)
+	if err := os.WriteFile(filepath.Join(dir, "360", "index.m3u8"), []byte(strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:3",
+		"#EXT-X-TARGETDURATION:6",
+		"#EXT-X-MEDIA-SEQUENCE:0",
+		"#EXT-X-PLAYLIST-TYPE:VOD",
+		"#EXTINF:6.000000,",
+		"000.ts",
+		"#EXTINF:2.000000,",
+		"001.ts",
+		"#EXT-X-ENDLIST",
+		"",
+	}, "\n")), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := MeasureVariant(dir, "360/index.m3u8")
+	a.NoError(err)
+	a.Equal(&Variant{URI: "360/index.m3u8", Bandwidth: 8000, AverageBandwidth: 7000}, v)
+}
+
+func TestMeasureVariantMissingSegment(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte("#EXTM3U\n#EXTINF:6.0,\n000.ts\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := MeasureVariant(dir, "index.m3u8")
+	a.Error(err)
+}
+
+func TestMeasureVariantEmpty(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte("#EXTM3U\n#EXT-X-ENDLIST\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := MeasureVariant(dir, "index.m3u8")
+	a.Error(err)
+}
+
+func TestWriteMaster(t *testing.T) {
+	a := assert.New(t)
+
+	var b strings.Builder
+	a.NoError(WriteMaster(&b, []Variant{
+		{URI: "720/index.m3u8", Bandwidth: 2000000, AverageBandwidth: 1500000, Resolution: "1280x720", Codecs: "hvc1.1.6.L93.90,mp4a.40.34"},
+		{URI: "360/index.m3u8", Bandwidth: 800000, AverageBandwidth: 600000},
+	}))
+
+	a.Equal(strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:3",
+		"#EXT-X-STREAM-INF:BANDWIDTH=800000,AVERAGE-BANDWIDTH=600000",
+		"360/index.m3u8",
+		`#EXT-X-STREAM-INF:BANDWIDTH=2000000,AVERAGE-BANDWIDTH=1500000,RESOLUTION=1280x720,CODECS="hvc1.1.6.L93.90,mp4a.40.34"`,
+		"720/index.m3u8",
+		"",
+	}, "\n"), b.String())
+}
+
+func TestCodecs(t *testing.T) {
+	for _, tc := range []struct {
+		videoCodec, videoProfile string
+		videoLevel               int
+		audioCodec, audioProfile string
+		expected                 string
+	}{
+		{"h264", "High", 40, "aac", "LC", "avc1.640028,mp4a.40.2"},
+		{"h264", "Constrained Baseline", 30, "mp3", "", "avc1.42E01E,mp4a.40.34"},
+		{"hevc", "Main", 120, "mp3", "", "hvc1.1.6.L120.90,mp4a.40.34"},
+		{"hevc", "Main 10", 93, "", "", "hvc1.2.4.L93.90"},
+		{"", "", 0, "aac", "HE-AAC", "mp4a.40.5"},
+	} {
+		t.Run(tc.expected, func(t *testing.T) {
+			a := assert.New(t)
+
+			codecs, err := Codecs(tc.videoCodec, tc.videoProfile, tc.videoLevel, tc.audioCodec, tc.audioProfile)
+			a.NoError(err)
+			a.Equal(tc.expected, codecs)
+		})
+	}
+}
+
+func TestCodecsUnrecognised(t *testing.T) {
+	a := assert.New(t)
+
+	_, err := Codecs("vp9", "Profile 0", 0, "opus", "")
+	a.Error(err)
+
+	_, err = Codecs("h264", "", 0, "mp3", "")
+	a.Error(err)
+
+	_, err = Codecs("", "", 0, "", "")
+	a.Error(err)
+}
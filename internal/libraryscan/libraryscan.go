@@ -0,0 +1,93 @@
+// Package libraryscan compares the files in the data directories with the
+// files that the database says should be there.
+package libraryscan
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Expected is a file that the database says should exist, along with the
+// job that makes it. File is relative to Directory.
+type Expected struct {
+	Directory       string
+	File            string
+	VideoExternalID string
+	QueueName       string
+	Payload         string
+}
+
+func (e Expected) Path() string {
+	return e.Directory + "/" + e.File
+}
+
+// Result is how the files on disk line up with what was expected. Orphaned
+// files are given as paths relative to the data directory, in order.
+type Result struct {
+	Present  []Expected
+	Missing  []Expected
+	Orphaned []string
+}
+
+// Compare walks each of directories under root and sorts expected into the
+// files that are there and the ones that aren't. Anything else that's found
+// is orphaned. A directory that doesn't exist is treated as empty.
+func Compare(root string, directories []string, expected []Expected) (*Result, error) {
+	found := make(map[string]bool)
+
+	for _, directory := range directories {
+		base := filepath.Join(root, directory)
+
+		if err := filepath.WalkDir(base, func(path string, d fs.DirEntry, err error) error {
+			if err != nil {
+				if path == base && os.IsNotExist(err) {
+					return filepath.SkipDir
+				}
+
+				return err
+			}
+
+			if d.IsDir() {
+				return nil
+			}
+
+			rel, err := filepath.Rel(root, path)
+			if err != nil {
+				return err
+			}
+
+			found[filepath.ToSlash(rel)] = true
+
+			return nil
+		}); err != nil {
+			return nil, fmt.Errorf("libraryscan.Compare: %w", err)
+		}
+	}
+
+	var result Result
+
+	for _, e := range expected {
+		if found[e.Path()] {
+			result.Present = append(result.Present, e)
+		} else {
+			result.Missing = append(result.Missing, e)
+		}
+	}
+
+	// files are deleted afterwards so that two expectations for the same
+	// file are both present
+	for _, e := range expected {
+		delete(found, e.Path())
+	}
+
+	for path := range found {
+		result.Orphaned = append(result.Orphaned, path)
+	}
+
+	sort.Strings(result.Orphaned)
+
+	return &result, nil
+}
@@ -0,0 +1,49 @@
+package libraryscan
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestCompare(t *testing.T) {
+	a := assert.New(t)
+
+	root := t.TempDir()
+
+	for _, file := range []string{
+		"videos/abc.mp4",
+		"videos/abc.partial.mp4",
+		"audio/mp3/abc.mp3",
+		"audio/mp3/def.mp3",
+		"thumbnails/abc.jpg",
+	} {
+		a.NoError(os.MkdirAll(filepath.Dir(filepath.Join(root, file)), 0755))
+		a.NoError(os.WriteFile(filepath.Join(root, file), []byte("x"), 0644))
+	}
+
+	video := Expected{Directory: "videos", File: "abc.mp4", VideoExternalID: "abc", QueueName: "video_download", Payload: "abc"}
+	audio := Expected{Directory: "audio", File: "mp3/abc.mp3", VideoExternalID: "abc", QueueName: "video_extract_audio", Payload: "abc?profile=mp3"}
+	rendition := Expected{Directory: "videos", File: "abc_360.mp4", VideoExternalID: "abc", QueueName: "video_transcode", Payload: "abc?rendition=360"}
+	chapter := Expected{Directory: "chapters", File: "abc.01.mp3", VideoExternalID: "abc", QueueName: "video_split_chapters", Payload: "abc"}
+
+	result, err := Compare(root, []string{"videos", "audio", "chapters"}, []Expected{video, audio, rendition, chapter})
+	a.NoError(err)
+
+	a.Equal([]Expected{video, audio}, result.Present)
+	a.Equal([]Expected{rendition, chapter}, result.Missing)
+	a.Equal([]string{"audio/mp3/def.mp3", "videos/abc.partial.mp4"}, result.Orphaned)
+}
+
+func TestCompareEmpty(t *testing.T) {
+	a := assert.New(t)
+
+	result, err := Compare(t.TempDir(), []string{"videos"}, nil)
+	a.NoError(err)
+
+	a.Empty(result.Present)
+	a.Empty(result.Missing)
+	a.Empty(result.Orphaned)
+}
@@ -0,0 +1,123 @@
+// Package metadatasource lets the workers fetch channel, playlist, and video
+// metadata without caring where it comes from, falling back to another
+// source when the preferred one breaks.
+package metadatasource
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/sirupsen/logrus"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxlogger"
+	"fknsrs.biz/p/ytmusic/internal/ytdirect"
+	"fknsrs.biz/p/ytmusic/internal/ytdl"
+	"fknsrs.biz/p/ytmusic/internal/ytmeta"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+type MetadataSource interface {
+	Name() string
+	GetChannel(ctx context.Context, id string) (*ytmeta.Channel, error)
+	GetPlaylist(ctx context.Context, id string) (*ytmeta.Playlist, error)
+	GetVideo(ctx context.Context, id string) (*ytmeta.Video, error)
+}
+
+var available = map[string]MetadataSource{
+	ytdirect.Source{}.Name(): ytdirect.Source{},
+	ytdl.Source{}.Name():     ytdl.Source{},
+}
+
+// Lookup finds sources by name, keeping the order they were given in.
+func Lookup(names []string) ([]MetadataSource, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("metadatasource.Lookup: no sources given")
+	}
+
+	var sources []MetadataSource
+	for _, name := range names {
+		s, ok := available[name]
+		if !ok {
+			return nil, fmt.Errorf("metadatasource.Lookup: unknown source %q", name)
+		}
+
+		sources = append(sources, s)
+	}
+
+	return sources, nil
+}
+
+// Chain is a MetadataSource that tries each of its sources in turn for every
+// kind of object, returning the first answer it gets. An UnavailableError
+// is an answer too: if YouTube says a video is private, asking again some
+// other way won't change that.
+type Chain struct {
+	Channel  []MetadataSource
+	Playlist []MetadataSource
+	Video    []MetadataSource
+}
+
+func (c *Chain) Name() string {
+	return "chain"
+}
+
+func (c *Chain) GetChannel(ctx context.Context, id string) (*ytmeta.Channel, error) {
+	return try(ctx, "GetChannel", id, c.Channel, func(s MetadataSource) (*ytmeta.Channel, error) {
+		return s.GetChannel(ctx, id)
+	})
+}
+
+func (c *Chain) GetPlaylist(ctx context.Context, id string) (*ytmeta.Playlist, error) {
+	return try(ctx, "GetPlaylist", id, c.Playlist, func(s MetadataSource) (*ytmeta.Playlist, error) {
+		return s.GetPlaylist(ctx, id)
+	})
+}
+
+func (c *Chain) GetVideo(ctx context.Context, id string) (*ytmeta.Video, error) {
+	return try(ctx, "GetVideo", id, c.Video, func(s MetadataSource) (*ytmeta.Video, error) {
+		return s.GetVideo(ctx, id)
+	})
+}
+
+func try[T any](ctx context.Context, op, id string, sources []MetadataSource, fn func(s MetadataSource) (*T, error)) (*T, error) {
+	if len(sources) == 0 {
+		return nil, fmt.Errorf("metadatasource.%s: no sources configured", op)
+	}
+
+	var messages []string
+	var lastErr error
+
+	for i, s := range sources {
+		v, err := fn(s)
+		if err == nil {
+			if i > 0 {
+				ctxlogger.GetLogger(ctx).WithFields(logrus.Fields{
+					"id":     id,
+					"source": s.Name(),
+				}).Warn("metadatasource: used fallback source")
+			}
+
+			return v, nil
+		}
+
+		var unavailableErr *ytutil.UnavailableError
+		if errors.As(err, &unavailableErr) || ctx.Err() != nil {
+			return nil, fmt.Errorf("metadatasource.%s: %s: %w", op, s.Name(), err)
+		}
+
+		messages = append(messages, s.Name()+": "+err.Error())
+		lastErr = err
+	}
+
+	last := sources[len(sources)-1].Name()
+
+	if len(messages) == 1 {
+		return nil, fmt.Errorf("metadatasource.%s: %s: %w", op, last, lastErr)
+	}
+
+	// only the last error can be wrapped, but the others are still worth
+	// seeing in the job output
+	return nil, fmt.Errorf("metadatasource.%s: %s; %s: %w", op, strings.Join(messages[:len(messages)-1], "; "), last, lastErr)
+}
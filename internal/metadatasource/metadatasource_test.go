@@ -0,0 +1,122 @@
+package metadatasource
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ytdirect"
+	"fknsrs.biz/p/ytmusic/internal/ytmeta"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+type fakeSource struct {
+	name  string
+	err   error
+	calls int
+}
+
+func (f *fakeSource) Name() string { return f.name }
+
+func (f *fakeSource) GetChannel(ctx context.Context, id string) (*ytmeta.Channel, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ytmeta.Channel{ID: id, Title: f.name}, nil
+}
+
+func (f *fakeSource) GetPlaylist(ctx context.Context, id string) (*ytmeta.Playlist, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ytmeta.Playlist{ID: id, Title: f.name}, nil
+}
+
+func (f *fakeSource) GetVideo(ctx context.Context, id string) (*ytmeta.Video, error) {
+	f.calls++
+	if f.err != nil {
+		return nil, f.err
+	}
+	return &ytmeta.Video{ID: id, Title: f.name}, nil
+}
+
+func TestChain(t *testing.T) {
+	layoutErr := &ytdirect.LayoutChangedError{Page: "video", ID: "v", Path: "x"}
+	unavailableErr := &ytutil.UnavailableError{ID: "v", Availability: ytutil.Private}
+
+	for _, tc := range []struct {
+		name      string
+		errs      []error
+		title     string
+		calls     []int
+		errTarget error
+	}{
+		{"first succeeds", []error{nil, nil}, "a", []int{1, 0}, nil},
+		{"falls back", []error{layoutErr, nil}, "b", []int{1, 1}, nil},
+		{"unavailable stops", []error{unavailableErr, nil}, "", []int{1, 0}, ytutil.ErrUnavailable},
+		{"all fail", []error{layoutErr, errors.New("exit status 1")}, "", []int{1, 1}, nil},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			var sources []MetadataSource
+			var fakes []*fakeSource
+			for i, err := range tc.errs {
+				f := &fakeSource{name: string(rune('a' + i)), err: err}
+				fakes = append(fakes, f)
+				sources = append(sources, f)
+			}
+
+			c := &Chain{Video: sources}
+
+			v, err := c.GetVideo(context.Background(), "v")
+			if tc.title != "" {
+				if a.NoError(err) {
+					a.Equal(tc.title, v.Title)
+				}
+			} else {
+				a.Error(err)
+				if tc.errTarget != nil {
+					a.ErrorIs(err, tc.errTarget)
+				}
+			}
+
+			for i, f := range fakes {
+				a.Equal(tc.calls[i], f.calls, "calls to %s", f.name)
+			}
+		})
+	}
+}
+
+func TestChainAllFailMessage(t *testing.T) {
+	a := assert.New(t)
+
+	c := &Chain{Channel: []MetadataSource{
+		&fakeSource{name: "a", err: errors.New("first")},
+		&fakeSource{name: "b", err: ytdirect.ErrLayoutChanged},
+	}}
+
+	_, err := c.GetChannel(context.Background(), "c")
+	a.EqualError(err, "metadatasource.GetChannel: a: first; b: "+ytdirect.ErrLayoutChanged.Error())
+	a.ErrorIs(err, ytdirect.ErrLayoutChanged)
+}
+
+func TestLookup(t *testing.T) {
+	a := assert.New(t)
+
+	sources, err := Lookup([]string{"ytdlp", "ytdirect"})
+	if a.NoError(err) && a.Len(sources, 2) {
+		a.Equal("ytdlp", sources[0].Name())
+		a.Equal("ytdirect", sources[1].Name())
+	}
+
+	_, err = Lookup([]string{"ytdirect", "nope"})
+	a.Error(err)
+
+	_, err = Lookup(nil)
+	a.Error(err)
+}
@@ -0,0 +1,48 @@
+// Package partial names the files that media steps write to before they've
+// been checked, so that a crash or a timeout part way through never leaves
+// something behind that looks finished. The partial name keeps the
+// extension, which is how ffmpeg and yt-dlp pick a format.
+package partial
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const infix = ".partial"
+
+// Name is where to write file until it's finished: videos/abc.mp4 becomes
+// videos/abc.partial.mp4.
+func Name(file string) string {
+	ext := filepath.Ext(file)
+	return strings.TrimSuffix(file, ext) + infix + ext
+}
+
+// Is reports whether file is a partial name.
+func Is(file string) bool {
+	return strings.HasSuffix(strings.TrimSuffix(file, filepath.Ext(file)), infix)
+}
+
+// Final is the name that a partial file is moved to once it's finished.
+func Final(partialFile string) string {
+	ext := filepath.Ext(partialFile)
+	return strings.TrimSuffix(strings.TrimSuffix(partialFile, ext), infix) + ext
+}
+
+// Commit moves a finished partial file into place, replacing whatever was
+// there before.
+func Commit(partialFile string) (string, error) {
+	if !Is(partialFile) {
+		return "", fmt.Errorf("partial.Commit: %s isn't a partial file", partialFile)
+	}
+
+	file := Final(partialFile)
+
+	if err := os.Rename(partialFile, file); err != nil {
+		return "", fmt.Errorf("partial.Commit: %w", err)
+	}
+
+	return file, nil
+}
@@ -0,0 +1,59 @@
+package partial
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestName(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("videos/abc.partial.mp4", Name("videos/abc.mp4"))
+	a.Equal("audio/mp3/abc_def.partial.mp3", Name("audio/mp3/abc_def.mp3"))
+	a.Equal("audio/original/abc.partial.%(ext)s", Name("audio/original/abc.%(ext)s"))
+}
+
+func TestIs(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(Is("videos/abc.partial.mp4"))
+	a.False(Is("videos/abc.mp4"))
+	a.False(Is("videos/abc.partial.mp4.part"))
+	a.False(Is("videos/partial.mp4"))
+}
+
+func TestFinal(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("videos/abc.mp4", Final("videos/abc.partial.mp4"))
+
+	for _, file := range []string{"videos/abc.mp4", "audio/original/abc.webm", "chapters/abc.01.m4a"} {
+		a.Equal(file, Final(Name(file)))
+	}
+}
+
+func TestCommit(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	a.NoError(os.WriteFile(filepath.Join(dir, "abc.mp4"), []byte("old"), 0644))
+	a.NoError(os.WriteFile(filepath.Join(dir, "abc.partial.mp4"), []byte("new"), 0644))
+
+	file, err := Commit(filepath.Join(dir, "abc.partial.mp4"))
+	a.NoError(err)
+	a.Equal(filepath.Join(dir, "abc.mp4"), file)
+
+	b, err := os.ReadFile(file)
+	a.NoError(err)
+	a.Equal("new", string(b))
+
+	_, err = os.Stat(filepath.Join(dir, "abc.partial.mp4"))
+	a.True(os.IsNotExist(err))
+
+	_, err = Commit(filepath.Join(dir, "abc.mp4"))
+	a.Error(err)
+}
@@ -0,0 +1,234 @@
+package playlistsync
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Entry is our record of a video's place in a playlist.
+type Entry struct {
+	ID         int
+	VideoID    string
+	SetVideoID string
+	Position   int
+	Removed    bool
+}
+
+// Item is one entry in a playlist as YouTube has it now. SetVideoID is
+// YouTube's own ID for the entry, which tells apart copies of the same video
+// in one playlist; it can be empty if the source didn't supply it.
+type Item struct {
+	VideoID    string
+	SetVideoID string
+}
+
+type ChangeKind string
+
+const (
+	Added    = ChangeKind("added")
+	Removed  = ChangeKind("removed")
+	Restored = ChangeKind("restored")
+	Moved    = ChangeKind("moved")
+	// Shifted entries have a new position only because something before them
+	// was added or removed. They need saving, but aren't worth reporting.
+	Shifted = ChangeKind("shifted")
+)
+
+type Change struct {
+	Kind       ChangeKind
+	EntryID    int
+	VideoID    string
+	SetVideoID string
+	From       int
+	To         int
+}
+
+// Diff works out what has to happen to local to make it match remote, which
+// is the playlist as it is now. Entries are matched up by SetVideoID where
+// both sides have one, and otherwise by video ID, preferring ones that haven't
+// been removed, so a video that comes back gets its old entry restored rather
+// than a new one.
+func Diff(local []Entry, remote []Item) []Change {
+	return diff(local, remote, false)
+}
+
+// DiffPartial is like Diff, but for when remote might be only the start of the
+// playlist. Entries missing from remote could still be further along, so they
+// are left alone rather than removed.
+func DiffPartial(local []Entry, remote []Item) []Change {
+	return diff(local, remote, true)
+}
+
+func diff(local []Entry, remote []Item, partial bool) []Change {
+	sorted := append([]Entry(nil), local...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Removed != sorted[j].Removed {
+			return !sorted[i].Removed
+		}
+		return sorted[i].Position < sorted[j].Position
+	})
+
+	matches := make([]*Entry, len(remote))
+	matched := make(map[int]bool)
+
+	bySetVideoID := make(map[string]*Entry)
+	for i := range sorted {
+		if sorted[i].SetVideoID != "" {
+			bySetVideoID[sorted[i].SetVideoID] = &sorted[i]
+		}
+	}
+
+	for i, item := range remote {
+		if e, ok := bySetVideoID[item.SetVideoID]; ok && item.SetVideoID != "" && e.VideoID == item.VideoID && !matched[e.ID] {
+			matches[i] = e
+			matched[e.ID] = true
+		}
+	}
+
+	available := make(map[string][]*Entry)
+	for i := range sorted {
+		if !matched[sorted[i].ID] {
+			available[sorted[i].VideoID] = append(available[sorted[i].VideoID], &sorted[i])
+		}
+	}
+
+	for i, item := range remote {
+		if matches[i] != nil {
+			continue
+		}
+
+		if candidates := available[item.VideoID]; len(candidates) > 0 {
+			matches[i] = candidates[0]
+			available[item.VideoID] = candidates[1:]
+			matched[candidates[0].ID] = true
+		}
+	}
+
+	var changes []Change
+	var kept []int
+	relabelled := make(map[int]bool)
+
+	for i, item := range remote {
+		e := matches[i]
+
+		switch {
+		case e == nil:
+			changes = append(changes, Change{Kind: Added, VideoID: item.VideoID, SetVideoID: item.SetVideoID, From: -1, To: i})
+		case e.Removed:
+			changes = append(changes, Change{Kind: Restored, EntryID: e.ID, VideoID: item.VideoID, SetVideoID: item.SetVideoID, From: e.Position, To: i})
+		default:
+			// a source that doesn't supply set video IDs would otherwise
+			// relabel every entry that has one on every sync
+			if item.SetVideoID != "" && item.SetVideoID != e.SetVideoID {
+				relabelled[len(changes)] = true
+			}
+			kept = append(kept, len(changes))
+			changes = append(changes, Change{Kind: Shifted, EntryID: e.ID, VideoID: item.VideoID, SetVideoID: item.SetVideoID, From: e.Position, To: i})
+		}
+	}
+
+	// of the entries that stayed, the ones in the longest run that kept their
+	// relative order didn't really move; everything else did
+	inOrder := longestIncreasing(kept, func(i int) int { return changes[i].From })
+	for _, i := range kept {
+		if !inOrder[i] {
+			changes[i].Kind = Moved
+		}
+	}
+
+	var filtered []Change
+	for i, c := range changes {
+		if c.Kind == Shifted && c.From == c.To && !relabelled[i] {
+			continue
+		}
+		filtered = append(filtered, c)
+	}
+
+	if partial {
+		return filtered
+	}
+
+	for _, e := range sorted {
+		if !matched[e.ID] && !e.Removed {
+			filtered = append(filtered, Change{Kind: Removed, EntryID: e.ID, VideoID: e.VideoID, SetVideoID: e.SetVideoID, From: e.Position, To: -1})
+		}
+	}
+
+	return filtered
+}
+
+// Rearranged reports whether changes add, remove or move any entry. Changes
+// that only fill in set video IDs need saving, but leave the playlist as it
+// was.
+func Rearranged(changes []Change) bool {
+	for _, c := range changes {
+		if c.Kind != Shifted || c.From != c.To {
+			return true
+		}
+	}
+
+	return false
+}
+
+// longestIncreasing returns the members of the longest subsequence of items
+// whose keys are strictly increasing.
+func longestIncreasing(items []int, key func(int) int) map[int]bool {
+	if len(items) == 0 {
+		return nil
+	}
+
+	lengths := make([]int, len(items))
+	previous := make([]int, len(items))
+
+	best := 0
+	for i := range items {
+		lengths[i], previous[i] = 1, -1
+		for j := 0; j < i; j++ {
+			if key(items[j]) < key(items[i]) && lengths[j]+1 > lengths[i] {
+				lengths[i], previous[i] = lengths[j]+1, j
+			}
+		}
+		if lengths[i] > lengths[best] {
+			best = i
+		}
+	}
+
+	m := make(map[int]bool)
+	for i := best; i != -1; i = previous[i] {
+		m[items[i]] = true
+	}
+
+	return m
+}
+
+// Summarize describes changes for a job's output, one line per interesting
+// change after a line of totals.
+func Summarize(changes []Change) string {
+	counts := make(map[ChangeKind]int)
+	var lines []string
+
+	for _, c := range changes {
+		counts[c.Kind]++
+
+		switch c.Kind {
+		case Added:
+			lines = append(lines, fmt.Sprintf("+ %s at %d", c.VideoID, c.To))
+		case Removed:
+			lines = append(lines, fmt.Sprintf("- %s from %d", c.VideoID, c.From))
+		case Restored:
+			lines = append(lines, fmt.Sprintf("^ %s at %d (was at %d)", c.VideoID, c.To, c.From))
+		case Moved:
+			lines = append(lines, fmt.Sprintf("~ %s from %d to %d", c.VideoID, c.From, c.To))
+		}
+	}
+
+	if len(lines) == 0 {
+		return "no changes"
+	}
+
+	return strings.Join(append([]string{fmt.Sprintf(
+		"%d added, %d removed, %d restored, %d moved",
+		counts[Added], counts[Removed], counts[Restored], counts[Moved],
+	)}, lines...), "\n")
+}
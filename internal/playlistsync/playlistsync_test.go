@@ -0,0 +1,213 @@
+package playlistsync
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func entries(videoIDs ...string) []Entry {
+	var l []Entry
+	for i, videoID := range videoIDs {
+		l = append(l, Entry{ID: i + 1, VideoID: videoID, Position: i})
+	}
+	return l
+}
+
+func items(videoIDs ...string) []Item {
+	var l []Item
+	for _, videoID := range videoIDs {
+		l = append(l, Item{VideoID: videoID})
+	}
+	return l
+}
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name    string
+		local   []Entry
+		remote  []Item
+		changes []Change
+	}{
+		{
+			"empty",
+			nil,
+			nil,
+			nil,
+		},
+		{
+			"new playlist",
+			nil,
+			items("a", "b"),
+			[]Change{
+				{Kind: Added, VideoID: "a", From: -1, To: 0},
+				{Kind: Added, VideoID: "b", From: -1, To: 1},
+			},
+		},
+		{
+			"unchanged",
+			entries("a", "b", "c"),
+			items("a", "b", "c"),
+			nil,
+		},
+		{
+			"appended",
+			entries("a", "b"),
+			items("a", "b", "c"),
+			[]Change{
+				{Kind: Added, VideoID: "c", From: -1, To: 2},
+			},
+		},
+		{
+			"inserted at start shifts the rest",
+			entries("a", "b"),
+			items("z", "a", "b"),
+			[]Change{
+				{Kind: Added, VideoID: "z", From: -1, To: 0},
+				{Kind: Shifted, EntryID: 1, VideoID: "a", From: 0, To: 1},
+				{Kind: Shifted, EntryID: 2, VideoID: "b", From: 1, To: 2},
+			},
+		},
+		{
+			"removed from middle",
+			entries("a", "b", "c"),
+			items("a", "c"),
+			[]Change{
+				{Kind: Shifted, EntryID: 3, VideoID: "c", From: 2, To: 1},
+				{Kind: Removed, EntryID: 2, VideoID: "b", From: 1, To: -1},
+			},
+		},
+		{
+			"moved to end",
+			entries("a", "b", "c", "d"),
+			items("b", "c", "d", "a"),
+			[]Change{
+				{Kind: Shifted, EntryID: 2, VideoID: "b", From: 1, To: 0},
+				{Kind: Shifted, EntryID: 3, VideoID: "c", From: 2, To: 1},
+				{Kind: Shifted, EntryID: 4, VideoID: "d", From: 3, To: 2},
+				{Kind: Moved, EntryID: 1, VideoID: "a", From: 0, To: 3},
+			},
+		},
+		{
+			"removed video comes back",
+			[]Entry{
+				{ID: 1, VideoID: "a", Position: 0},
+				{ID: 2, VideoID: "b", Position: 1, Removed: true},
+				{ID: 3, VideoID: "c", Position: 1},
+			},
+			items("a", "b", "c"),
+			[]Change{
+				{Kind: Restored, EntryID: 2, VideoID: "b", From: 1, To: 1},
+				{Kind: Shifted, EntryID: 3, VideoID: "c", From: 1, To: 2},
+			},
+		},
+		{
+			"already removed stays removed",
+			[]Entry{
+				{ID: 1, VideoID: "a", Position: 0},
+				{ID: 2, VideoID: "b", Position: 1, Removed: true},
+			},
+			items("a"),
+			nil,
+		},
+		{
+			"duplicates match one entry each",
+			entries("a", "b"),
+			items("a", "b", "a"),
+			[]Change{
+				{Kind: Added, VideoID: "a", From: -1, To: 2},
+			},
+		},
+		{
+			"duplicates matched by set video id",
+			[]Entry{
+				{ID: 1, VideoID: "a", SetVideoID: "s1", Position: 0},
+				{ID: 2, VideoID: "b", SetVideoID: "s2", Position: 1},
+				{ID: 3, VideoID: "a", SetVideoID: "s3", Position: 2},
+			},
+			[]Item{{"b", "s2"}, {"a", "s3"}},
+			[]Change{
+				{Kind: Shifted, EntryID: 2, VideoID: "b", SetVideoID: "s2", From: 1, To: 0},
+				{Kind: Shifted, EntryID: 3, VideoID: "a", SetVideoID: "s3", From: 2, To: 1},
+				{Kind: Removed, EntryID: 1, VideoID: "a", SetVideoID: "s1", From: 0, To: -1},
+			},
+		},
+		{
+			"set video ids filled in for old entries",
+			entries("a", "b"),
+			[]Item{{"a", "s1"}, {"b", "s2"}},
+			[]Change{
+				{Kind: Shifted, EntryID: 1, VideoID: "a", SetVideoID: "s1", From: 0, To: 0},
+				{Kind: Shifted, EntryID: 2, VideoID: "b", SetVideoID: "s2", From: 1, To: 1},
+			},
+		},
+		{
+			"missing set video ids leave old ones alone",
+			[]Entry{
+				{ID: 1, VideoID: "a", SetVideoID: "s1", Position: 0},
+				{ID: 2, VideoID: "b", SetVideoID: "s2", Position: 1},
+			},
+			items("a", "b"),
+			nil,
+		},
+		{
+			"re-added video restores its old entry",
+			[]Entry{
+				{ID: 1, VideoID: "a", SetVideoID: "s1", Position: 0, Removed: true},
+			},
+			[]Item{{"a", "s2"}},
+			[]Change{
+				{Kind: Restored, EntryID: 1, VideoID: "a", SetVideoID: "s2", From: 0, To: 0},
+			},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.changes, Diff(tc.local, tc.remote))
+		})
+	}
+}
+
+func TestDiffPartial(t *testing.T) {
+	a := assert.New(t)
+
+	a.Nil(DiffPartial(entries("a", "b", "c"), items("a", "b")))
+	a.Equal([]Change{
+		{Kind: Added, VideoID: "d", From: -1, To: 1},
+		{Kind: Shifted, EntryID: 2, VideoID: "b", From: 1, To: 2},
+	}, DiffPartial(entries("a", "b", "c"), items("a", "d", "b")))
+	a.Equal([]Change{
+		{Kind: Removed, EntryID: 3, VideoID: "c", From: 2, To: -1},
+	}, Diff(entries("a", "b", "c"), items("a", "b")))
+}
+
+func TestRearranged(t *testing.T) {
+	a := assert.New(t)
+
+	a.False(Rearranged(nil))
+	a.False(Rearranged(Diff(entries("a", "b"), []Item{{"a", "s1"}, {"b", "s2"}})))
+	a.True(Rearranged(Diff(entries("a", "b"), items("b", "a"))))
+	a.True(Rearranged(Diff(entries("a", "b"), items("b"))))
+	a.True(Rearranged(Diff(entries("a", "b"), items("c", "a", "b"))))
+}
+
+func TestSummarize(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("no changes", Summarize(nil))
+	a.Equal("no changes", Summarize([]Change{{Kind: Shifted, EntryID: 1, VideoID: "a", From: 0, To: 1}}))
+
+	a.Equal(
+		"1 added, 1 removed, 1 restored, 1 moved\n"+
+			"+ z at 0\n"+
+			"~ a from 0 to 3\n"+
+			"^ b at 1 (was at 4)\n"+
+			"- c from 2",
+		Summarize([]Change{
+			{Kind: Added, VideoID: "z", From: -1, To: 0},
+			{Kind: Moved, EntryID: 1, VideoID: "a", From: 0, To: 3},
+			{Kind: Shifted, EntryID: 4, VideoID: "d", From: 3, To: 2},
+			{Kind: Restored, EntryID: 2, VideoID: "b", From: 4, To: 1},
+			{Kind: Removed, EntryID: 3, VideoID: "c", From: 2, To: -1},
+		}),
+	)
+}
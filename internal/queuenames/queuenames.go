@@ -1,16 +1,27 @@
 package queuenames
 
 const (
-	ChannelUpdateMetadata  = "channel_update_metadata"
-	ChannelUpdatePlaylists = "channel_update_playlists"
-	ChannelUpdateVideos    = "channel_update_videos"
-	PlaylistUpdateMetadata = "playlist_update_metadata"
-	PlaylistUpdateVideos   = "playlist_update_videos"
-	VideoUpdateMetadata    = "video_update_metadata"
-	VideoDownload          = "video_download"
-	VideoUpdateThumbnail   = "video_update_thumbnail"
-	VideoTranscode         = "video_transcode"
-	VideoExtractAudio      = "video_extract_audio"
+	ChannelUpdateMetadata   = "channel_update_metadata"
+	ChannelUpdatePlaylists  = "channel_update_playlists"
+	ChannelUpdateVideos     = "channel_update_videos"
+	ChannelUpdateThumbnail  = "channel_update_thumbnail"
+	PlaylistUpdateMetadata  = "playlist_update_metadata"
+	PlaylistUpdateVideos    = "playlist_update_videos"
+	PlaylistUpdateThumbnail = "playlist_update_thumbnail"
+	VideoUpdateMetadata     = "video_update_metadata"
+	VideoDownload           = "video_download"
+	VideoUpdateThumbnail    = "video_update_thumbnail"
+	VideoTranscode          = "video_transcode"
+	VideoExtractAudio       = "video_extract_audio"
+	VideoDownloadSubtitles  = "video_download_subtitles"
+	VideoSplitChapters      = "video_split_chapters"
+	VideoRetag              = "video_retag"
+	VideoAnalyzeLoudness    = "video_analyze_loudness"
+	VideoWaveform           = "video_waveform"
+	VideoPackageHLS         = "video_package_hls"
+	VideoProbe              = "video_probe"
+	VideoStoryboard         = "video_storyboard"
+	LibraryVerify           = "library_verify"
 )
 
 var Priority = []string{
@@ -22,6 +33,17 @@ var Priority = []string{
 	ChannelUpdateVideos,
 	VideoDownload,
 	VideoUpdateThumbnail,
+	ChannelUpdateThumbnail,
+	PlaylistUpdateThumbnail,
 	VideoExtractAudio,
+	VideoDownloadSubtitles,
+	VideoSplitChapters,
+	VideoAnalyzeLoudness,
+	VideoWaveform,
+	VideoRetag,
+	VideoProbe,
+	VideoStoryboard,
 	VideoTranscode,
+	VideoPackageHLS,
+	LibraryVerify,
 }
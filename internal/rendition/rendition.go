@@ -0,0 +1,118 @@
+// Package rendition describes the smaller copies of each video that are
+// transcoded for streaming. A rendition is written as
+// name:height:codec[:crf[:preset]], e.g. "720p:720:h264" or
+// "480p:480:h265:30:medium". The CRF and preset default to the usual ones
+// for the codec.
+package rendition
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+type codec struct {
+	encoder       string
+	defaultCRF    int
+	defaultPreset string
+	extraArgs     []string
+}
+
+var codecs = map[string]codec{
+	"h264": {encoder: "libx264", defaultCRF: 23, defaultPreset: "veryslow", extraArgs: []string{"-tune", "fastdecode"}},
+	// hvc1 is the tag that Apple's players want
+	"h265": {encoder: "libx265", defaultCRF: 28, defaultPreset: "medium", extraArgs: []string{"-tag:v", "hvc1"}},
+}
+
+var presets = []string{"ultrafast", "superfast", "veryfast", "faster", "fast", "medium", "slow", "slower", "veryslow", "placebo"}
+
+var namePattern = regexp.MustCompile(`^[a-z0-9_-]+$`)
+
+type Rendition struct {
+	Name   string
+	Height int
+	Codec  string
+	CRF    int
+	Preset string
+}
+
+func Parse(s string) (Rendition, error) {
+	parts := strings.Split(strings.TrimSpace(s), ":")
+	if len(parts) < 3 || len(parts) > 5 {
+		return Rendition{}, fmt.Errorf("rendition.Parse: %q should look like name:height:codec[:crf[:preset]]", s)
+	}
+
+	r := Rendition{Name: parts[0], Codec: parts[2]}
+
+	if !namePattern.MatchString(r.Name) {
+		return Rendition{}, fmt.Errorf("rendition.Parse: %q is not a usable rendition name", r.Name)
+	}
+
+	height, err := strconv.Atoi(parts[1])
+	if err != nil || height < 2 || height%2 != 0 {
+		return Rendition{}, fmt.Errorf("rendition.Parse: height %q should be an even number of pixels", parts[1])
+	}
+	r.Height = height
+
+	c, ok := codecs[r.Codec]
+	if !ok {
+		return Rendition{}, fmt.Errorf("rendition.Parse: unrecognised codec %q; valid options are h264, h265", r.Codec)
+	}
+
+	r.CRF = c.defaultCRF
+	if len(parts) > 3 && parts[3] != "" {
+		crf, err := strconv.Atoi(parts[3])
+		if err != nil || crf < 0 || crf > 51 {
+			return Rendition{}, fmt.Errorf("rendition.Parse: crf %q should be a number from 0 to 51", parts[3])
+		}
+		r.CRF = crf
+	}
+
+	r.Preset = c.defaultPreset
+	if len(parts) > 4 && parts[4] != "" {
+		if !isPreset(parts[4]) {
+			return Rendition{}, fmt.Errorf("rendition.Parse: unrecognised preset %q; valid options are %s", parts[4], strings.Join(presets, ", "))
+		}
+		r.Preset = parts[4]
+	}
+
+	return r, nil
+}
+
+func isPreset(s string) bool {
+	for _, p := range presets {
+		if p == s {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (r Rendition) String() string {
+	return r.Name + ":" + strconv.Itoa(r.Height) + ":" + r.Codec + ":" + strconv.Itoa(r.CRF) + ":" + r.Preset
+}
+
+// File is where a video's copy for this rendition lives, relative to the
+// videos data directory.
+func (r Rendition) File(videoID string) string {
+	return videoID + "_" + r.Name + ".mp4"
+}
+
+// FFmpegArgs are the ffmpeg output options that scale and encode a video for
+// this rendition. The audio is encoded the same way for every rendition.
+func (r Rendition) FFmpegArgs() []string {
+	c := codecs[r.Codec]
+
+	args := []string{
+		"-vf", "scale=-2:" + strconv.Itoa(r.Height),
+		"-c:v", c.encoder,
+		"-crf", strconv.Itoa(r.CRF),
+		"-preset", r.Preset,
+	}
+	args = append(args, c.extraArgs...)
+	args = append(args, "-c:a", "libmp3lame", "-q:a", "2")
+
+	return args
+}
@@ -0,0 +1,75 @@
+package rendition
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	for _, tc := range []struct {
+		in   string
+		out  Rendition
+		args []string
+		str  string
+	}{
+		{
+			"720p:720:h264",
+			Rendition{Name: "720p", Height: 720, Codec: "h264", CRF: 23, Preset: "veryslow"},
+			[]string{"-vf", "scale=-2:720", "-c:v", "libx264", "-crf", "23", "-preset", "veryslow", "-tune", "fastdecode", "-c:a", "libmp3lame", "-q:a", "2"},
+			"720p:720:h264:23:veryslow",
+		},
+		{
+			"small:360:h264:28",
+			Rendition{Name: "small", Height: 360, Codec: "h264", CRF: 28, Preset: "veryslow"},
+			[]string{"-vf", "scale=-2:360", "-c:v", "libx264", "-crf", "28", "-preset", "veryslow", "-tune", "fastdecode", "-c:a", "libmp3lame", "-q:a", "2"},
+			"small:360:h264:28:veryslow",
+		},
+		{
+			"hevc:1080:h265::slow",
+			Rendition{Name: "hevc", Height: 1080, Codec: "h265", CRF: 28, Preset: "slow"},
+			[]string{"-vf", "scale=-2:1080", "-c:v", "libx265", "-crf", "28", "-preset", "slow", "-tag:v", "hvc1", "-c:a", "libmp3lame", "-q:a", "2"},
+			"hevc:1080:h265:28:slow",
+		},
+	} {
+		r, err := Parse(tc.in)
+		if a.NoError(err, tc.in) {
+			a.Equal(tc.out, r, tc.in)
+			a.Equal(tc.args, r.FFmpegArgs(), tc.in)
+			a.Equal(tc.str, r.String(), tc.in)
+
+			again, err := Parse(r.String())
+			a.NoError(err, tc.in)
+			a.Equal(r, again, tc.in)
+		}
+	}
+}
+
+func TestParseInvalid(t *testing.T) {
+	a := assert.New(t)
+
+	for _, in := range []string{
+		"",
+		"720p:720",
+		"720p:tall:h264",
+		"720p:721:h264",
+		"720p:0:h264",
+		"720p:720:vp8",
+		"720p:720:h264:52",
+		"720p:720:h264:good",
+		"720p:720:h264:23:quick",
+		"Big One:720:h264",
+		"a:720:h264:23:slow:extra",
+	} {
+		_, err := Parse(in)
+		a.Error(err, in)
+	}
+}
+
+func TestFile(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("abc_720p.mp4", Rendition{Name: "720p"}.File("abc"))
+}
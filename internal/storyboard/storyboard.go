@@ -0,0 +1,99 @@
+// Package storyboard tiles frames taken from a video into sprite sheets, and
+// describes them with a WebVTT thumbnails track, so players can show a
+// preview of wherever the pointer is on the seek bar.
+package storyboard
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"io"
+	"time"
+)
+
+// Interval is how far apart the frames are taken, and TileWidth is how wide
+// each one is scaled to.
+const (
+	Interval  = 10 * time.Second
+	TileWidth = 160
+)
+
+// Layout is how many frames go across and down each sheet.
+type Layout struct {
+	Columns int
+	Rows    int
+}
+
+// Default makes sheets of 100 frames, which is a bit over 16 minutes of
+// video at the default interval.
+var Default = Layout{Columns: 10, Rows: 10}
+
+func (l Layout) perSheet() int {
+	return l.Columns * l.Rows
+}
+
+// Tile draws frames into as many sheets as it takes, left to right then top
+// to bottom. Every frame is drawn at the size of the first, and the last
+// sheet only has as many rows as it needs.
+func (l Layout) Tile(frames []image.Image) []*image.RGBA {
+	if len(frames) == 0 {
+		return nil
+	}
+
+	size := frames[0].Bounds().Size()
+
+	var sheets []*image.RGBA
+
+	for from := 0; from < len(frames); from += l.perSheet() {
+		to := from + l.perSheet()
+		if to > len(frames) {
+			to = len(frames)
+		}
+
+		rows := (to - from + l.Columns - 1) / l.Columns
+
+		sheet := image.NewRGBA(image.Rect(0, 0, size.X*l.Columns, size.Y*rows))
+
+		for i, frame := range frames[from:to] {
+			at := image.Pt((i%l.Columns)*size.X, (i/l.Columns)*size.Y)
+			draw.Draw(sheet, image.Rectangle{Min: at, Max: at.Add(size)}, frame, frame.Bounds().Min, draw.Src)
+		}
+
+		sheets = append(sheets, sheet)
+	}
+
+	return sheets
+}
+
+// WriteVTT writes a thumbnails track for frames taken every interval, each
+// of the given size, pointing at the sheets Tile made. sheetURL names each
+// sheet, relative to where the track will be. The last frame lasts until
+// duration, if that's known.
+func (l Layout) WriteVTT(w io.Writer, frames int, size image.Point, interval, duration time.Duration, sheetURL func(sheet int) string) error {
+	if _, err := io.WriteString(w, "WEBVTT\n"); err != nil {
+		return fmt.Errorf("storyboard.WriteVTT: %w", err)
+	}
+
+	for i := 0; i < frames; i++ {
+		start := time.Duration(i) * interval
+		stop := start + interval
+		if i == frames-1 && duration > start {
+			stop = duration
+		}
+
+		n := i % l.perSheet()
+		x, y := (n%l.Columns)*size.X, (n/l.Columns)*size.Y
+
+		if _, err := fmt.Fprintf(w, "\n%s --> %s\n%s#xywh=%d,%d,%d,%d\n", formatTimestamp(start), formatTimestamp(stop), sheetURL(i/l.perSheet()), x, y, size.X, size.Y); err != nil {
+			return fmt.Errorf("storyboard.WriteVTT: %w", err)
+		}
+	}
+
+	return nil
+}
+
+func formatTimestamp(d time.Duration) string {
+	ms := d.Milliseconds()
+
+	return fmt.Sprintf("%02d:%02d:%02d.%03d", ms/3600000, ms/60000%60, ms/1000%60, ms%1000)
+}
@@ -0,0 +1,85 @@
+package storyboard
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func solid(c color.Color, w, h int) image.Image {
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(c), image.Point{}, draw.Src)
+	return img
+}
+
+func TestTile(t *testing.T) {
+	a := assert.New(t)
+
+	var frames []image.Image
+	for i := 0; i < 7; i++ {
+		frames = append(frames, solid(color.Gray{uint8(i * 10)}, 4, 3))
+	}
+
+	sheets := Layout{Columns: 2, Rows: 2}.Tile(frames)
+	a.Len(sheets, 2)
+
+	a.Equal(image.Rect(0, 0, 8, 6), sheets[0].Bounds())
+	a.Equal(color.RGBA{0, 0, 0, 0xff}, sheets[0].At(0, 0))
+	a.Equal(color.RGBA{10, 10, 10, 0xff}, sheets[0].At(4, 0))
+	a.Equal(color.RGBA{20, 20, 20, 0xff}, sheets[0].At(0, 3))
+	a.Equal(color.RGBA{30, 30, 30, 0xff}, sheets[0].At(7, 5))
+
+	// three frames left over need both rows, but only the first is full
+	a.Equal(image.Rect(0, 0, 8, 6), sheets[1].Bounds())
+	a.Equal(color.RGBA{60, 60, 60, 0xff}, sheets[1].At(0, 3))
+	a.Equal(color.RGBA{}, sheets[1].At(4, 3))
+}
+
+func TestTileOneRow(t *testing.T) {
+	a := assert.New(t)
+
+	sheets := Layout{Columns: 4, Rows: 4}.Tile([]image.Image{solid(color.White, 4, 3), solid(color.White, 4, 3)})
+	a.Len(sheets, 1)
+	a.Equal(image.Rect(0, 0, 16, 3), sheets[0].Bounds())
+}
+
+func TestTileEmpty(t *testing.T) {
+	assert.Nil(t, Default.Tile(nil))
+}
+
+func TestWriteVTT(t *testing.T) {
+	a := assert.New(t)
+
+	var buf bytes.Buffer
+	a.NoError(Layout{Columns: 2, Rows: 2}.WriteVTT(&buf, 5, image.Pt(160, 90), 10*time.Second, 45500*time.Millisecond, func(sheet int) string {
+		return fmt.Sprintf("%03d.jpg", sheet)
+	}))
+
+	a.Equal(`WEBVTT
+
+00:00:00.000 --> 00:00:10.000
+000.jpg#xywh=0,0,160,90
+
+00:00:10.000 --> 00:00:20.000
+000.jpg#xywh=160,0,160,90
+
+00:00:20.000 --> 00:00:30.000
+000.jpg#xywh=0,90,160,90
+
+00:00:30.000 --> 00:00:40.000
+000.jpg#xywh=160,90,160,90
+
+00:00:40.000 --> 00:00:45.500
+001.jpg#xywh=0,0,160,90
+`, buf.String())
+}
+
+func TestFormatTimestamp(t *testing.T) {
+	assert.Equal(t, "01:02:03.004", formatTimestamp(time.Hour+2*time.Minute+3*time.Second+4*time.Millisecond))
+}
@@ -0,0 +1,111 @@
+package textdiff
+
+import (
+	"unicode"
+)
+
+type OpKind string
+
+const (
+	Equal  = OpKind("equal")
+	Insert = OpKind("insert")
+	Delete = OpKind("delete")
+)
+
+type Op struct {
+	Kind OpKind
+	Text string
+}
+
+// maxCells bounds the size of the table Diff builds. Past it, the texts are
+// treated as entirely different rather than spending a lot of memory on
+// working out exactly how.
+const maxCells = 4 << 20
+
+// Diff compares a and b a word at a time, returning the operations that turn
+// a into b. Whitespace is kept, so joining the Equal and Delete texts gives
+// back a and joining the Equal and Insert texts gives back b.
+func Diff(a, b string) []Op {
+	x, y := tokenize(a), tokenize(b)
+
+	if len(x)*len(y) > maxCells {
+		var ops []Op
+		ops = appendOp(ops, Delete, a)
+		ops = appendOp(ops, Insert, b)
+		return ops
+	}
+
+	// lengths[i][j] is the length of the longest common subsequence of x[i:]
+	// and y[j:]
+	lengths := make([][]int, len(x)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(y)+1)
+	}
+	for i := len(x) - 1; i >= 0; i-- {
+		for j := len(y) - 1; j >= 0; j-- {
+			if x[i] == y[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else if lengths[i+1][j] >= lengths[i][j+1] {
+				lengths[i][j] = lengths[i+1][j]
+			} else {
+				lengths[i][j] = lengths[i][j+1]
+			}
+		}
+	}
+
+	var ops []Op
+	i, j := 0, 0
+	for i < len(x) && j < len(y) {
+		switch {
+		case x[i] == y[j]:
+			ops = appendOp(ops, Equal, x[i])
+			i, j = i+1, j+1
+		case lengths[i+1][j] >= lengths[i][j+1]:
+			ops = appendOp(ops, Delete, x[i])
+			i++
+		default:
+			ops = appendOp(ops, Insert, y[j])
+			j++
+		}
+	}
+	for ; i < len(x); i++ {
+		ops = appendOp(ops, Delete, x[i])
+	}
+	for ; j < len(y); j++ {
+		ops = appendOp(ops, Insert, y[j])
+	}
+
+	return ops
+}
+
+func appendOp(ops []Op, kind OpKind, text string) []Op {
+	if text == "" {
+		return ops
+	}
+
+	if len(ops) > 0 && ops[len(ops)-1].Kind == kind {
+		ops[len(ops)-1].Text += text
+		return ops
+	}
+
+	return append(ops, Op{Kind: kind, Text: text})
+}
+
+// tokenize splits s into runs of whitespace and runs of everything else.
+func tokenize(s string) []string {
+	var tokens []string
+
+	start, space := 0, false
+	for i, r := range s {
+		if i > start && unicode.IsSpace(r) != space {
+			tokens = append(tokens, s[start:i])
+			start = i
+		}
+		space = unicode.IsSpace(r)
+	}
+	if start < len(s) {
+		tokens = append(tokens, s[start:])
+	}
+
+	return tokens
+}
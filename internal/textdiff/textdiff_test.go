@@ -0,0 +1,56 @@
+package textdiff
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestDiff(t *testing.T) {
+	for _, tc := range []struct {
+		name string
+		a, b string
+		ops  []Op
+	}{
+		{"both empty", "", "", nil},
+		{"same", "Nightjar", "Nightjar", []Op{{Equal, "Nightjar"}}},
+		{"blanked", "some words", "", []Op{{Delete, "some words"}}},
+		{"filled in", "", "some words", []Op{{Insert, "some words"}}},
+		{
+			"word replaced",
+			"Nightjar (Official Audio)",
+			"Nightjar (Remastered)",
+			[]Op{{Equal, "Nightjar "}, {Delete, "(Official Audio)"}, {Insert, "(Remastered)"}},
+		},
+		{
+			"line added",
+			"one\ntwo",
+			"one\nand a half\ntwo",
+			[]Op{{Equal, "one\n"}, {Insert, "and a half\n"}, {Equal, "two"}},
+		},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			a := assert.New(t)
+
+			ops := Diff(tc.a, tc.b)
+			a.Equal(tc.ops, ops)
+
+			var before, after strings.Builder
+			for _, op := range ops {
+				if op.Kind != Insert {
+					before.WriteString(op.Text)
+				}
+				if op.Kind != Delete {
+					after.WriteString(op.Text)
+				}
+			}
+			a.Equal(tc.a, before.String())
+			a.Equal(tc.b, after.String())
+		})
+	}
+}
+
+func TestTokenize(t *testing.T) {
+	assert.Equal(t, []string{"ünïcode", "  ", "words", "\n", "here"}, tokenize("ünïcode  words\nhere"))
+}
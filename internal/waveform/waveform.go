@@ -0,0 +1,85 @@
+// Package waveform computes the peaks that the web player draws as a
+// waveform. They're in the JSON format used by audiowaveform and peaks.js:
+// a minimum and a maximum for each block of samples, scaled to 8 bits.
+package waveform
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+)
+
+const (
+	// SampleRate is what audio should be decoded at for Read. Peaks don't
+	// need the detail of the full rate.
+	SampleRate = 8000
+	// SamplesPerPixel makes ten peaks for each second of audio.
+	SamplesPerPixel = SampleRate / 10
+)
+
+type Waveform struct {
+	Version         int    `json:"version"`
+	Channels        int    `json:"channels"`
+	SampleRate      int    `json:"sample_rate"`
+	SamplesPerPixel int    `json:"samples_per_pixel"`
+	Bits            int    `json:"bits"`
+	Length          int    `json:"length"`
+	Data            []int8 `json:"data"`
+}
+
+// Read computes the peaks of mono signed 16-bit little-endian PCM audio at
+// sampleRate, one pair for every samplesPerPixel samples. The last pair can
+// cover fewer samples.
+func Read(r io.Reader, sampleRate, samplesPerPixel int) (*Waveform, error) {
+	if samplesPerPixel < 1 {
+		return nil, fmt.Errorf("waveform.Read: samplesPerPixel should be at least 1")
+	}
+
+	w := Waveform{
+		Version:         2,
+		Channels:        1,
+		SampleRate:      sampleRate,
+		SamplesPerPixel: samplesPerPixel,
+		Bits:            8,
+	}
+
+	br := bufio.NewReader(r)
+
+	var min, max int8
+	var n int
+	var buf [2]byte
+
+	for {
+		if _, err := io.ReadFull(br, buf[:]); err != nil {
+			// a half sample at the end is ignored
+			if err == io.EOF || err == io.ErrUnexpectedEOF {
+				break
+			}
+
+			return nil, fmt.Errorf("waveform.Read: %w", err)
+		}
+
+		v := int8(int16(binary.LittleEndian.Uint16(buf[:])) >> 8)
+
+		if n == 0 || v < min {
+			min = v
+		}
+		if n == 0 || v > max {
+			max = v
+		}
+
+		if n++; n == samplesPerPixel {
+			w.Data = append(w.Data, min, max)
+			n = 0
+		}
+	}
+
+	if n > 0 {
+		w.Data = append(w.Data, min, max)
+	}
+
+	w.Length = len(w.Data) / 2
+
+	return &w, nil
+}
@@ -0,0 +1,55 @@
+package waveform
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func pcm(samples ...int16) *bytes.Reader {
+	var buf bytes.Buffer
+	for _, s := range samples {
+		binary.Write(&buf, binary.LittleEndian, s)
+	}
+	return bytes.NewReader(buf.Bytes())
+}
+
+func TestRead(t *testing.T) {
+	a := assert.New(t)
+
+	w, err := Read(pcm(0, 32767, -32768, 256, -256, 512, 1024), 8000, 3)
+	a.NoError(err)
+	a.Equal(3, w.Length)
+	a.Equal([]int8{-128, 127, -1, 2, 4, 4}, w.Data)
+}
+
+func TestReadEmpty(t *testing.T) {
+	a := assert.New(t)
+
+	w, err := Read(pcm(), 8000, 3)
+	a.NoError(err)
+	a.Equal(0, w.Length)
+	a.Empty(w.Data)
+}
+
+func TestReadHalfSample(t *testing.T) {
+	a := assert.New(t)
+
+	w, err := Read(bytes.NewReader([]byte{0x00, 0x40, 0x01}), 8000, 3)
+	a.NoError(err)
+	a.Equal([]int8{64, 64}, w.Data)
+}
+
+func TestJSON(t *testing.T) {
+	a := assert.New(t)
+
+	w, err := Read(pcm(-512, 512), 8000, 800)
+	a.NoError(err)
+
+	b, err := json.Marshal(w)
+	a.NoError(err)
+	a.JSONEq(`{"version":2,"channels":1,"sample_rate":8000,"samples_per_pixel":800,"bits":8,"length":1,"data":[-2,2]}`, string(b))
+}
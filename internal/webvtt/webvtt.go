@@ -0,0 +1,151 @@
+// Package webvtt reads the text out of WebVTT caption files.
+package webvtt
+
+import (
+	"bufio"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Cue struct {
+	Start time.Duration
+	End   time.Duration
+	// Text has had its markup removed. Lines are separated by "\n".
+	Text string
+}
+
+var (
+	timingPattern    = regexp.MustCompile(`^((?:\d+:)?\d{2}:\d{2}\.\d{3})\s+-->\s+((?:\d+:)?\d{2}:\d{2}\.\d{3})`)
+	timestampPattern = regexp.MustCompile(`^(?:(\d+):)?(\d{2}):(\d{2})\.(\d{3})$`)
+	tagPattern       = regexp.MustCompile(`<[^>]*>`)
+)
+
+// Parse returns the cues in r. Comments, style, and region blocks are
+// skipped, as are cue settings.
+func Parse(r io.Reader) ([]Cue, error) {
+	s := bufio.NewScanner(r)
+	s.Buffer(nil, 1024*1024)
+
+	if !s.Scan() || !strings.HasPrefix(strings.TrimPrefix(s.Text(), "\ufeff"), "WEBVTT") {
+		if err := s.Err(); err != nil {
+			return nil, fmt.Errorf("webvtt.Parse: %w", err)
+		}
+
+		return nil, fmt.Errorf("webvtt.Parse: missing WEBVTT header")
+	}
+
+	var cues []Cue
+	var cue *Cue
+	var lines []string
+
+	flush := func() {
+		if cue != nil {
+			cue.Text = strings.Join(lines, "\n")
+			cues = append(cues, *cue)
+		}
+
+		cue, lines = nil, nil
+	}
+
+	for s.Scan() {
+		line := s.Text()
+
+		// only a truly empty line ends a block; YouTube's automatic captions
+		// have lines with just a space in them
+		if line == "" {
+			flush()
+			continue
+		}
+
+		if cue != nil {
+			line = strings.TrimSpace(html.UnescapeString(tagPattern.ReplaceAllString(line, "")))
+			if line != "" {
+				lines = append(lines, line)
+			}
+			continue
+		}
+
+		// before the timing line comes an optional identifier, and anything
+		// without a timing line isn't a cue at all
+		m := timingPattern.FindStringSubmatch(line)
+		if m == nil {
+			continue
+		}
+
+		start, err := parseTimestamp(m[1])
+		if err != nil {
+			return nil, fmt.Errorf("webvtt.Parse: %w", err)
+		}
+		end, err := parseTimestamp(m[2])
+		if err != nil {
+			return nil, fmt.Errorf("webvtt.Parse: %w", err)
+		}
+
+		cue = &Cue{Start: start, End: end}
+	}
+	if err := s.Err(); err != nil {
+		return nil, fmt.Errorf("webvtt.Parse: %w", err)
+	}
+
+	flush()
+
+	return cues, nil
+}
+
+func parseTimestamp(s string) (time.Duration, error) {
+	m := timestampPattern.FindStringSubmatch(s)
+	if m == nil {
+		return 0, fmt.Errorf("invalid timestamp %q", s)
+	}
+
+	var d time.Duration
+
+	for i, unit := range []time.Duration{time.Hour, time.Minute, time.Second, time.Millisecond} {
+		if m[i+1] == "" {
+			continue
+		}
+
+		n, err := strconv.Atoi(m[i+1])
+		if err != nil {
+			return 0, fmt.Errorf("invalid timestamp %q: %w", s, err)
+		}
+
+		d += time.Duration(n) * unit
+	}
+
+	return d, nil
+}
+
+// Collapse undoes the scrolling in YouTube's automatic captions, where each
+// cue repeats the line before it and the new words arrive one by one. What
+// comes back has each line once, in the cue where it first appeared, and no
+// empty cues.
+//
+// Don't use it on regular subtitles: a line sung twice in a row really is
+// there twice.
+func Collapse(cues []Cue) []Cue {
+	var out []Cue
+	var last string
+
+	for _, c := range cues {
+		lines := strings.Split(c.Text, "\n")
+
+		for len(lines) > 0 && (lines[0] == "" || lines[0] == last) {
+			lines = lines[1:]
+		}
+		if len(lines) == 0 {
+			continue
+		}
+
+		last = lines[len(lines)-1]
+		c.Text = strings.Join(lines, "\n")
+		out = append(out, c)
+	}
+
+	return out
+}
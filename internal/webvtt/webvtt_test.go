@@ -0,0 +1,62 @@
+package webvtt
+
+import (
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func parseFile(t *testing.T, name string) []Cue {
+	t.Helper()
+
+	f, err := os.Open("testdata/" + name)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	cues, err := Parse(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	return cues
+}
+
+func TestParse(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]Cue{
+		{Start: time.Second, End: 4200 * time.Millisecond, Text: "We're no strangers to love\nYou know the rules & so do I"},
+		{Start: time.Hour + 2*time.Minute + 3*time.Second + 4*time.Millisecond, End: time.Hour + 2*time.Minute + 5*time.Second, Text: "Never gonna give you up"},
+		{Start: time.Hour + 2*time.Minute + 5*time.Second, End: time.Hour + 2*time.Minute + 7*time.Second, Text: "Never gonna give you up"},
+	}, parseFile(t, "manual.vtt"))
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, tc := range []struct {
+		name  string
+		input string
+	}{
+		{"empty", ""},
+		{"srt", "1\n00:00:01,000 --> 00:00:02,000\nhello\n"},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tc.input))
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestCollapse(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal([]Cue{
+		{Start: 0, End: 2500 * time.Millisecond, Text: "we're no strangers"},
+		{Start: 2510 * time.Millisecond, End: 5 * time.Second, Text: "to love"},
+		{Start: 5010 * time.Millisecond, End: 7 * time.Second, Text: "[Music]"},
+	}, Collapse(parseFile(t, "automatic.vtt")))
+}
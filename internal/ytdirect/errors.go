@@ -0,0 +1,27 @@
+package ytdirect
+
+import (
+	"fmt"
+)
+
+var (
+	ErrLayoutChanged = fmt.Errorf("layout changed")
+)
+
+// LayoutChangedError is returned when a page is missing structure that the
+// parser depends on. Path names the script variable, selector, or JSON path
+// that could not be found, so a change on YouTube's side can be tracked down
+// without having to diff the page by hand.
+type LayoutChangedError struct {
+	Page string
+	ID   string
+	Path string
+}
+
+func (e *LayoutChangedError) Error() string {
+	return fmt.Sprintf("%s: %s %s: missing %s", ErrLayoutChanged, e.Page, e.ID, e.Path)
+}
+
+func (e *LayoutChangedError) Unwrap() error {
+	return ErrLayoutChanged
+}
@@ -0,0 +1,298 @@
+package ytdirect
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxhttpclient"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+var update = flag.Bool("update", false, "rewrite golden files in testdata")
+
+// rewriteTransport sends every request to target, keeping the path and query
+// intact, so that the hard-coded youtube.com URLs end up at a test server.
+type rewriteTransport struct {
+	target *url.URL
+}
+
+func (t *rewriteTransport) RoundTrip(r *http.Request) (*http.Response, error) {
+	r = r.Clone(r.Context())
+	r.URL.Scheme = t.target.Scheme
+	r.URL.Host = t.target.Host
+	r.Host = t.target.Host
+
+	return http.DefaultTransport.RoundTrip(r)
+}
+
+// withFixture serves testdata/<fixture>.html (or .json, for API responses)
+// in response to any request and calls fn with a context whose HTTP client
+// talks to that server. API requests that follow a page, like continuations,
+// get testdata/<fixture>_continuation.json if there is one. The first URL the
+// code under test asked for is returned so it can be checked.
+func withFixture(t *testing.T, fixture string, fn func(ctx context.Context)) string {
+	t.Helper()
+
+	fixturePath, contentType := filepath.Join("testdata", fixture+".html"), "text/html; charset=utf-8"
+	if _, err := os.Stat(fixturePath); os.IsNotExist(err) {
+		fixturePath, contentType = filepath.Join("testdata", fixture+".json"), "application/json"
+	}
+
+	continuationPath := filepath.Join("testdata", fixture+"_continuation.json")
+	if _, err := os.Stat(continuationPath); os.IsNotExist(err) {
+		continuationPath = ""
+	}
+
+	var requested string
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		if requested == "" {
+			requested = r.URL.RequestURI()
+		}
+
+		if continuationPath != "" && strings.HasPrefix(r.URL.Path, "/youtubei/") {
+			rw.Header().Set("content-type", "application/json")
+			http.ServeFile(rw, r, continuationPath)
+			return
+		}
+
+		rw.Header().Set("content-type", contentType)
+		http.ServeFile(rw, r, fixturePath)
+	}))
+	defer s.Close()
+
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn(ctxhttpclient.WithHTTPClient(context.Background(), &http.Client{
+		Transport: &rewriteTransport{target: target},
+	}))
+
+	return requested
+}
+
+type golden struct {
+	Result interface{} `json:",omitempty"`
+	Error  string      `json:",omitempty"`
+}
+
+// checkGolden compares the result of a call against
+// testdata/<fixture>.golden.json, or rewrites that file when -update is set.
+func checkGolden(t *testing.T, fixture string, v interface{}, err error) {
+	t.Helper()
+
+	var g golden
+	if err != nil {
+		g.Error = err.Error()
+	} else {
+		g.Result = v
+	}
+
+	actual, err := json.MarshalIndent(g, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual = append(actual, '\n')
+
+	goldenPath := filepath.Join("testdata", fixture+".golden.json")
+
+	if *update {
+		if err := os.WriteFile(goldenPath, actual, 0644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	expected, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("could not read golden file; run with -update to create it: %v", err)
+	}
+
+	assert.Equal(t, string(expected), string(actual))
+}
+
+var fixtureTests = []struct {
+	fixture       string
+	id            string
+	url           string
+	layoutChanged bool
+	call          func(ctx context.Context, id string) (interface{}, error)
+}{
+	{"channel_topic", "UCpNvmbdtY8WAzhdNUDxbT2g", "/channel/UCpNvmbdtY8WAzhdNUDxbT2g", false, getChannel},
+	{"channel_no_meta", "UCpNvmbdtY8WAzhdNUDxbT2g", "/channel/UCpNvmbdtY8WAzhdNUDxbT2g", false, getChannel},
+	{"channel_layout_changed", "UCpNvmbdtY8WAzhdNUDxbT2g", "/channel/UCpNvmbdtY8WAzhdNUDxbT2g", true, getChannel},
+	{"channel_artwork", "UCpNvmbdtY8WAzhdNUDxbT2g", "/channel/UCpNvmbdtY8WAzhdNUDxbT2g", false, getChannel},
+	{"channel_artwork_legacy", "UCpNvmbdtY8WAzhdNUDxbT2g", "/channel/UCpNvmbdtY8WAzhdNUDxbT2g", false, getChannel},
+	{"playlist_public", "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", "/playlist?list=PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", false, getPlaylist},
+	{"playlist_artwork", "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", "/playlist?list=PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", false, getPlaylist},
+	{"playlist_layout_changed", "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", "/playlist?list=PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", true, getPlaylist},
+	{"playlist_missing_data", "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", "/playlist?list=PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", true, getPlaylist},
+	{"video_public", "xXa1bGk4pEs", "/watch?v=xXa1bGk4pEs", false, getVideo},
+	{"video_chapters", "fuLL4lbUm00", "/watch?v=fuLL4lbUm00", false, getVideo},
+	{"video_private", "pR1v4t3vId0", "/watch?v=pR1v4t3vId0", false, getVideo},
+	{"video_removed", "r3m0v3dvId0", "/watch?v=r3m0v3dvId0", false, getVideo},
+	{"video_unavailable", "uN4v41lvId0", "/watch?v=uN4v41lvId0", false, getVideo},
+	{"video_age_restricted", "4g3r35tr1ct", "/watch?v=4g3r35tr1ct", false, getVideo},
+	{"video_region_blocked", "r3g10nbl0ck", "/watch?v=r3g10nbl0ck", false, getVideo},
+	{"video_missing_data", "xXa1bGk4pEs", "/watch?v=xXa1bGk4pEs", true, getVideo},
+	{"search_results", "taylor lee czer", "/results?search_query=taylor+lee+czer", false, search},
+	{"search_layout_changed", "taylor lee czer", "/results?search_query=taylor+lee+czer", true, search},
+	{"search_continuation", "EpIDEhB0YXlsb3IgbGVlIGN6ZXIaAA%3D%3D", "/youtubei/v1/search?prettyPrint=false", false, searchContinuation},
+}
+
+func getChannel(ctx context.Context, id string) (interface{}, error)  { return GetChannel(ctx, id) }
+func getPlaylist(ctx context.Context, id string) (interface{}, error) { return GetPlaylist(ctx, id) }
+func getVideo(ctx context.Context, id string) (interface{}, error)    { return GetVideo(ctx, id) }
+func search(ctx context.Context, q string) (interface{}, error)       { return Search(ctx, q, "", "") }
+func searchContinuation(ctx context.Context, c string) (interface{}, error) {
+	return Search(ctx, "", "", c)
+}
+
+func TestFixtures(t *testing.T) {
+	for _, tc := range fixtureTests {
+		t.Run(tc.fixture, func(t *testing.T) {
+			a := assert.New(t)
+
+			var v interface{}
+			var err error
+
+			requested := withFixture(t, tc.fixture, func(ctx context.Context) {
+				v, err = tc.call(ctx, tc.id)
+			})
+
+			a.Equal(tc.url, requested)
+			a.Equal(tc.layoutChanged, errors.Is(err, ErrLayoutChanged), "error: %v", err)
+
+			if tc.layoutChanged {
+				var layoutErr *LayoutChangedError
+				if a.ErrorAs(err, &layoutErr) {
+					a.Equal(tc.id, layoutErr.ID)
+					a.NotEmpty(layoutErr.Path)
+				}
+			}
+
+			checkGolden(t, tc.fixture, v, err)
+		})
+	}
+}
+
+func TestGetVideoUnavailable(t *testing.T) {
+	for _, tc := range []struct {
+		fixture      string
+		id           string
+		availability ytutil.Availability
+	}{
+		{"video_private", "pR1v4t3vId0", ytutil.Private},
+		{"video_removed", "r3m0v3dvId0", ytutil.Removed},
+		{"video_unavailable", "uN4v41lvId0", ytutil.Unavailable},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			a := assert.New(t)
+
+			withFixture(t, tc.fixture, func(ctx context.Context) {
+				v, err := GetVideo(ctx, tc.id)
+				a.Nil(v)
+				a.ErrorIs(err, ytutil.ErrUnavailable)
+
+				var unavailableErr *ytutil.UnavailableError
+				if a.ErrorAs(err, &unavailableErr) {
+					a.Equal(tc.id, unavailableErr.ID)
+					a.Equal(tc.availability, unavailableErr.Availability)
+				}
+			})
+		})
+	}
+}
+
+func TestSearchFilter(t *testing.T) {
+	for _, tc := range []struct {
+		filter ytutil.IDType
+		url    string
+	}{
+		{"", "/results?search_query=taylor+lee+czer"},
+		{ytutil.VideoID, "/results?search_query=taylor+lee+czer&sp=EgIQAQ%3D%3D"},
+		{ytutil.ChannelID, "/results?search_query=taylor+lee+czer&sp=EgIQAg%3D%3D"},
+		{ytutil.PlaylistID, "/results?search_query=taylor+lee+czer&sp=EgIQAw%3D%3D"},
+	} {
+		t.Run(string(tc.filter), func(t *testing.T) {
+			a := assert.New(t)
+
+			requested := withFixture(t, "search_results", func(ctx context.Context) {
+				_, err := Search(ctx, "taylor lee czer", tc.filter, "")
+				a.NoError(err)
+			})
+
+			a.Equal(tc.url, requested)
+		})
+	}
+}
+
+// withFixturePages is like withFixture, but serves a different fixture for
+// each path. The requests made are returned in order.
+func withFixturePages(t *testing.T, fixtures map[string]string, fn func(ctx context.Context)) []string {
+	t.Helper()
+
+	var requested []string
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		requested = append(requested, r.Method+" "+r.URL.Path)
+
+		fixture, ok := fixtures[r.URL.Path]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+
+		fixturePath, contentType := filepath.Join("testdata", fixture+".html"), "text/html; charset=utf-8"
+		if _, err := os.Stat(fixturePath); os.IsNotExist(err) {
+			fixturePath, contentType = filepath.Join("testdata", fixture+".json"), "application/json"
+		}
+
+		rw.Header().Set("content-type", contentType)
+		http.ServeFile(rw, r, fixturePath)
+	}))
+	defer s.Close()
+
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fn(ctxhttpclient.WithHTTPClient(context.Background(), &http.Client{
+		Transport: &rewriteTransport{target: target},
+	}))
+
+	return requested
+}
+
+func TestGetPlaylistContinuationLimit(t *testing.T) {
+	a := assert.New(t)
+
+	var p *Playlist
+	var err error
+
+	requested := withFixturePages(t, map[string]string{
+		"/playlist":           "playlist_public",
+		"/youtubei/v1/browse": "playlist_endless_continuation",
+	}, func(ctx context.Context) {
+		p, err = GetPlaylist(ctx, "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE")
+	})
+
+	a.NoError(err)
+	a.Len(requested, maxPlaylistPages)
+	a.True(p.Truncated)
+	a.Len(p.Entries, 3+2*(maxPlaylistPages-1))
+}
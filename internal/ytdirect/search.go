@@ -0,0 +1,245 @@
+package ytdirect
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+
+	"github.com/Jeffail/gabs/v2"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxhttpclient"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+// innertubeClientVersion is sent with continuation requests. YouTube accepts
+// old versions for a long time, so this only needs bumping if it starts
+// refusing them.
+const innertubeClientVersion = "2.20241010.00.00"
+
+// searchFilters are the "sp" values the search page uses for its type
+// filters.
+var searchFilters = map[ytutil.IDType]string{
+	ytutil.VideoID:    "EgIQAQ==",
+	ytutil.ChannelID:  "EgIQAg==",
+	ytutil.PlaylistID: "EgIQAw==",
+}
+
+type SearchResult struct {
+	Type         ytutil.IDType
+	ID           string
+	Title        string
+	ChannelID    string
+	ChannelTitle string
+	// Detail is whatever short summary YouTube shows with the result: a
+	// video's length, a playlist's video count, or a channel's subscriber
+	// count.
+	Detail       string
+	ThumbnailURL string
+}
+
+type SearchPage struct {
+	Results []SearchResult
+	// Continuation can be passed back to Search to get the next page. It's
+	// empty on the last page.
+	Continuation string
+}
+
+// Search runs a search for query, optionally limited to one type of result.
+// If continuation is set, query and filter are ignored and the page after the
+// one that returned it is fetched instead.
+func Search(ctx context.Context, query string, filter ytutil.IDType, continuation string) (*SearchPage, error) {
+	if continuation != "" {
+		j, err := postInnertube(ctx, "search", map[string]interface{}{"continuation": continuation})
+		if err != nil {
+			return nil, fmt.Errorf("ytdirect.Search: %w", err)
+		}
+
+		const itemsPath = "onResponseReceivedCommands.0.appendContinuationItemsAction.continuationItems"
+
+		if !j.ExistsP(itemsPath) {
+			return nil, fmt.Errorf("ytdirect.Search: %w", &LayoutChangedError{Page: "search continuation", ID: continuation, Path: itemsPath})
+		}
+
+		return parseSearchSections(j.Path(itemsPath).Children()), nil
+	}
+
+	v := url.Values{"search_query": {query}}
+	if sp, ok := searchFilters[filter]; ok {
+		v.Set("sp", sp)
+	}
+
+	doc, err := getDocument(ctx, "https://www.youtube.com/results?"+v.Encode())
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.Search: %w", err)
+	}
+
+	j, ok, err := findScriptJSON(doc, "var ytInitialData =")
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.Search: %w", err)
+	}
+	if !ok {
+		return nil, fmt.Errorf("ytdirect.Search: %w", &LayoutChangedError{Page: "search", ID: query, Path: "ytInitialData"})
+	}
+
+	const sectionListPath = "contents.twoColumnSearchResultsRenderer.primaryContents.sectionListRenderer.contents"
+
+	if !j.ExistsP(sectionListPath) {
+		return nil, fmt.Errorf("ytdirect.Search: %w", &LayoutChangedError{Page: "search", ID: query, Path: sectionListPath})
+	}
+
+	return parseSearchSections(j.Path(sectionListPath).Children()), nil
+}
+
+func parseSearchSections(sections []*gabs.Container) *SearchPage {
+	var page SearchPage
+
+	for _, section := range sections {
+		if token, ok := section.Path("continuationItemRenderer.continuationEndpoint.continuationCommand.token").Data().(string); ok {
+			page.Continuation = token
+			continue
+		}
+
+		for _, item := range section.Path("itemSectionRenderer.contents").Children() {
+			if r, ok := parseSearchResult(item); ok {
+				page.Results = append(page.Results, r)
+			}
+		}
+	}
+
+	return &page
+}
+
+func stringAt(j *gabs.Container, paths ...string) string {
+	for _, path := range paths {
+		if s, ok := j.Path(path).Data().(string); ok {
+			return s
+		}
+	}
+
+	return ""
+}
+
+// parseSearchResult understands the renderers for each kind of result we
+// care about, and skips everything else (ads, shelves of shorts, "people
+// also searched for", and so on).
+func parseSearchResult(item *gabs.Container) (SearchResult, bool) {
+	switch {
+	case item.Exists("videoRenderer"):
+		r := item.Path("videoRenderer")
+
+		return SearchResult{
+			Type:         ytutil.VideoID,
+			ID:           stringAt(r, "videoId"),
+			Title:        stringAt(r, "title.runs.0.text", "title.simpleText"),
+			ChannelID:    stringAt(r, "ownerText.runs.0.navigationEndpoint.browseEndpoint.browseId"),
+			ChannelTitle: stringAt(r, "ownerText.runs.0.text"),
+			Detail:       stringAt(r, "lengthText.simpleText"),
+			ThumbnailURL: stringAt(r, "thumbnail.thumbnails.0.url"),
+		}, stringAt(r, "videoId") != ""
+	case item.Exists("playlistRenderer"):
+		r := item.Path("playlistRenderer")
+
+		return SearchResult{
+			Type:         ytutil.PlaylistID,
+			ID:           stringAt(r, "playlistId"),
+			Title:        stringAt(r, "title.simpleText", "title.runs.0.text"),
+			ChannelID:    stringAt(r, "shortBylineText.runs.0.navigationEndpoint.browseEndpoint.browseId"),
+			ChannelTitle: stringAt(r, "shortBylineText.runs.0.text"),
+			Detail:       videoCountDetail(stringAt(r, "videoCount")),
+			ThumbnailURL: stringAt(r, "thumbnails.0.thumbnails.0.url"),
+		}, stringAt(r, "playlistId") != ""
+	case item.Exists("lockupViewModel") && stringAt(item, "lockupViewModel.contentType") == "LOCKUP_CONTENT_TYPE_PLAYLIST":
+		r := item.Path("lockupViewModel")
+		m := r.Path("metadata.lockupMetadataViewModel")
+
+		return SearchResult{
+			Type:         ytutil.PlaylistID,
+			ID:           stringAt(r, "contentId"),
+			Title:        stringAt(m, "title.content"),
+			ChannelTitle: stringAt(m, "metadata.contentMetadataViewModel.metadataRows.0.metadataParts.0.text.content"),
+			Detail:       stringAt(r, "contentImage.collectionThumbnailViewModel.primaryThumbnail.thumbnailViewModel.overlays.0.thumbnailOverlayBadgeViewModel.thumbnailBadges.0.thumbnailBadgeViewModel.text"),
+			ThumbnailURL: stringAt(r, "contentImage.collectionThumbnailViewModel.primaryThumbnail.thumbnailViewModel.image.sources.0.url"),
+		}, stringAt(r, "contentId") != ""
+	case item.Exists("channelRenderer"):
+		r := item.Path("channelRenderer")
+
+		return SearchResult{
+			Type:         ytutil.ChannelID,
+			ID:           stringAt(r, "channelId"),
+			Title:        stringAt(r, "title.simpleText"),
+			ChannelID:    stringAt(r, "channelId"),
+			ChannelTitle: stringAt(r, "title.simpleText"),
+			Detail:       stringAt(r, "videoCountText.simpleText", "subscriberCountText.simpleText"),
+			ThumbnailURL: absoluteThumbnailURL(stringAt(r, "thumbnail.thumbnails.0.url")),
+		}, stringAt(r, "channelId") != ""
+	default:
+		return SearchResult{}, false
+	}
+}
+
+func videoCountDetail(count string) string {
+	if count == "" {
+		return ""
+	}
+
+	return count + " videos"
+}
+
+// absoluteThumbnailURL fixes up the protocol-relative URLs that channel
+// avatars use.
+func absoluteThumbnailURL(s string) string {
+	if len(s) > 2 && s[:2] == "//" {
+		return "https:" + s
+	}
+
+	return s
+}
+
+// postInnertube calls one of the JSON endpoints that the YouTube web client
+// uses for anything after the first page load.
+func postInnertube(ctx context.Context, endpoint string, body map[string]interface{}) (*gabs.Container, error) {
+	body["context"] = map[string]interface{}{
+		"client": map[string]interface{}{
+			"clientName":    "WEB",
+			"clientVersion": innertubeClientVersion,
+			"hl":            "en",
+		},
+	}
+
+	d, err := json.Marshal(body)
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.postInnertube: %w", err)
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, "https://www.youtube.com/youtubei/v1/"+endpoint+"?prettyPrint=false", bytes.NewReader(d))
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.postInnertube: %w", err)
+	}
+	req.Header.Set("content-type", "application/json")
+
+	res, err := ctxhttpclient.GetHTTPClient(ctx).Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.postInnertube: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ytdirect.postInnertube: status code: %d", res.StatusCode)
+	}
+
+	d, err = io.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.postInnertube: %w", err)
+	}
+
+	j, err := gabs.ParseJSON(d)
+	if err != nil {
+		return nil, fmt.Errorf("ytdirect.postInnertube: %w", err)
+	}
+
+	return j, nil
+}
@@ -0,0 +1,71 @@
+package ytdirect
+
+import (
+	"context"
+
+	"fknsrs.biz/p/ytmusic/internal/ytmeta"
+)
+
+// Source adapts the package-level functions to the metadatasource
+// interface.
+type Source struct{}
+
+func (Source) Name() string { return "ytdirect" }
+
+func (Source) GetChannel(ctx context.Context, id string) (*ytmeta.Channel, error) {
+	c, err := GetChannel(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ytmeta.Channel{
+		ID:        c.ID,
+		Title:     c.Title,
+		Handle:    c.Handle,
+		AvatarURL: c.AvatarURL,
+		BannerURL: c.BannerURL,
+	}
+
+	for _, s := range c.Shelves {
+		shelf := ytmeta.ChannelShelf{Title: s.Title}
+		for _, p := range s.Playlists {
+			shelf.Playlists = append(shelf.Playlists, ytmeta.ChannelPlaylist(p))
+		}
+		m.Shelves = append(m.Shelves, shelf)
+	}
+
+	return &m, nil
+}
+
+func (Source) GetPlaylist(ctx context.Context, id string) (*ytmeta.Playlist, error) {
+	p, err := GetPlaylist(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ytmeta.Playlist{
+		ID:           p.ID,
+		ChannelID:    p.ChannelID,
+		Title:        p.Title,
+		ThumbnailURL: p.ThumbnailURL,
+		VideoIDs:     p.VideoIDs,
+		Truncated:    p.Truncated,
+	}
+
+	for _, e := range p.Entries {
+		m.Entries = append(m.Entries, ytmeta.PlaylistEntry(e))
+	}
+
+	return &m, nil
+}
+
+func (Source) GetVideo(ctx context.Context, id string) (*ytmeta.Video, error) {
+	v, err := GetVideo(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	m := ytmeta.Video(*v)
+
+	return &m, nil
+}
@@ -0,0 +1,57 @@
+package ytdirect
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestSource checks that nothing is lost turning our own types into the
+// ytmeta ones, which have the same shape.
+func TestSource(t *testing.T) {
+	for _, tc := range []struct {
+		fixture string
+		id      string
+		direct  func(ctx context.Context, id string) (interface{}, error)
+		source  func(ctx context.Context, id string) (interface{}, error)
+	}{
+		{"channel_topic", "UCpNvmbdtY8WAzhdNUDxbT2g", getChannel, sourceGetChannel},
+		{"playlist_public", "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", getPlaylist, sourceGetPlaylist},
+		{"video_chapters", "fuLL4lbUm00", getVideo, sourceGetVideo},
+	} {
+		t.Run(tc.fixture, func(t *testing.T) {
+			a := assert.New(t)
+
+			var direct, source interface{}
+			var directErr, sourceErr error
+
+			withFixture(t, tc.fixture, func(ctx context.Context) { direct, directErr = tc.direct(ctx, tc.id) })
+			withFixture(t, tc.fixture, func(ctx context.Context) { source, sourceErr = tc.source(ctx, tc.id) })
+
+			if !a.NoError(directErr) || !a.NoError(sourceErr) {
+				return
+			}
+
+			expected, err := json.Marshal(direct)
+			a.NoError(err)
+			actual, err := json.Marshal(source)
+			a.NoError(err)
+
+			a.JSONEq(string(expected), string(actual))
+		})
+	}
+}
+
+func sourceGetChannel(ctx context.Context, id string) (interface{}, error) {
+	return Source{}.GetChannel(ctx, id)
+}
+
+func sourceGetPlaylist(ctx context.Context, id string) (interface{}, error) {
+	return Source{}.GetPlaylist(ctx, id)
+}
+
+func sourceGetVideo(ctx context.Context, id string) (interface{}, error) {
+	return Source{}.GetVideo(ctx, id)
+}
@@ -6,12 +6,14 @@ import (
   "io"
   "net/http"
   "strings"
+  "time"
 
   "github.com/Jeffail/gabs/v2"
   "github.com/PuerkitoBio/goquery"
   "golang.org/x/net/html"
 
   "fknsrs.biz/p/ytmusic/internal/ctxhttpclient"
+  "fknsrs.biz/p/ytmusic/internal/ytutil"
 )
 
 func getData(ctx context.Context, url string) (io.ReadCloser, error) {
@@ -51,10 +53,40 @@ func getDocument(ctx context.Context, url string) (*goquery.Document, error) {
   return doc, nil
 }
 
+func findScriptJSON(doc *goquery.Document, prefix string) (*gabs.Container, bool, error) {
+  for _, node := range doc.Find("script").Nodes {
+    if node.FirstChild == nil || node.FirstChild.Type != html.TextNode {
+      continue
+    }
+
+    jsContent := node.FirstChild.Data
+
+    if !strings.HasPrefix(jsContent, prefix) {
+      continue
+    }
+
+    jsContent = strings.TrimPrefix(jsContent, prefix)
+    jsContent = strings.TrimSpace(jsContent)
+    jsContent = strings.TrimSuffix(jsContent, ";")
+
+    j, err := gabs.ParseJSON([]byte(jsContent))
+    if err != nil {
+      return nil, false, err
+    }
+
+    return j, true, nil
+  }
+
+  return nil, false, nil
+}
+
 type Channel struct {
-  ID      string
-  Title   string
-  Shelves []ChannelShelf
+  ID        string
+  Title     string
+  Handle    string
+  AvatarURL string
+  BannerURL string
+  Shelves   []ChannelShelf
 }
 
 type ChannelShelf struct {
@@ -76,196 +108,339 @@ func GetChannel(ctx context.Context, id string) (*Channel, error) {
     return nil, fmt.Errorf("ytdirect.GetChannel: %w", err)
   }
 
+  j, ok, err := findScriptJSON(doc, "var ytInitialData =")
+  if err != nil {
+    return nil, fmt.Errorf("ytdirect.GetChannel: %w", err)
+  }
+  if !ok {
+    return nil, fmt.Errorf("ytdirect.GetChannel: %w", &LayoutChangedError{Page: "channel", ID: id, Path: "ytInitialData"})
+  }
+
+  const (
+    channelIDPath             = "metadata.channelMetadataRenderer.externalId"
+    channelTitlePath          = "metadata.channelMetadataRenderer.title"
+    channelVanityURLPath      = "metadata.channelMetadataRenderer.vanityChannelUrl"
+    shelfListPath             = "contents.twoColumnBrowseResultsRenderer.tabs.0.tabRenderer.content.sectionListRenderer.contents"
+    shelfTitlePath            = "itemSectionRenderer.contents.0.shelfRenderer.title.runs.0.text"
+    playlistListPath          = "itemSectionRenderer.contents.0.shelfRenderer.content.horizontalListRenderer.items"
+    playlistIDPath            = "gridPlaylistRenderer.playlistId"
+    playlistChannelIDPath     = "gridPlaylistRenderer.longBylineText.runs.0.navigationEndpoint.browseEndpoint.browseId"
+    playlistTitlePath         = "gridPlaylistRenderer.title.runs.0.text"
+    playlistPublishedTimePath = "gridPlaylistRenderer.publishedTimeText.simpleText"
+    playlistVideoCountPath    = "gridPlaylistRenderer.videoCountShortText.simpleText"
+  )
+
+  var (
+    avatarPaths = []string{
+      "header.pageHeaderRenderer.content.pageHeaderViewModel.image.decoratedAvatarViewModel.avatar.avatarViewModel.image.sources",
+      "header.c4TabbedHeaderRenderer.avatar.thumbnails",
+      "metadata.channelMetadataRenderer.avatar.thumbnails",
+    }
+    bannerPaths = []string{
+      "header.pageHeaderRenderer.content.pageHeaderViewModel.banner.imageBannerViewModel.image.sources",
+      "header.c4TabbedHeaderRenderer.banner.thumbnails",
+    }
+  )
+
   channelID := doc.Find("meta[itemprop=channelId]").AttrOr("content", "")
+  if channelID == "" {
+    if v, ok := j.Path(channelIDPath).Data().(string); ok {
+      channelID = v
+    }
+  }
+  if channelID == "" {
+    return nil, fmt.Errorf("ytdirect.GetChannel: %w", &LayoutChangedError{Page: "channel", ID: id, Path: channelIDPath})
+  }
+
   channelTitle := doc.Find("meta[property='og:title']").AttrOr("content", "")
+  if channelTitle == "" {
+    if v, ok := j.Path(channelTitlePath).Data().(string); ok {
+      channelTitle = v
+    }
+  }
+
+  var channelHandle string
+  if v, ok := j.Path(channelVanityURLPath).Data().(string); ok {
+    channelHandle = ytutil.HandleFromURL(v)
+  }
 
   ch := &Channel{
-    ID:    channelID,
-    Title: channelTitle,
+    ID:        channelID,
+    Title:     channelTitle,
+    Handle:    channelHandle,
+    AvatarURL: largestImageURL(j, avatarPaths...),
+    BannerURL: largestImageURL(j, bannerPaths...),
   }
 
-  for _, node := range doc.Find("script").Nodes {
-    if node.FirstChild == nil || node.FirstChild.Type != html.TextNode {
+  if !j.ExistsP(shelfListPath) {
+    return nil, fmt.Errorf("ytdirect.GetChannel: %w", &LayoutChangedError{Page: "channel", ID: id, Path: shelfListPath})
+  }
+
+  for _, shelf := range j.Path(shelfListPath).Children() {
+    if !shelf.ExistsP(shelfTitlePath) {
       continue
     }
+    shelfTitle := shelf.Path(shelfTitlePath).Data().(string)
 
-    jsContent := node.FirstChild.Data
+    var playlists []ChannelPlaylist
 
-    if !strings.HasPrefix(jsContent, "var ytInitialData =") {
-      continue
+    for _, playlist := range shelf.Path(playlistListPath).Children() {
+      if !playlist.ExistsP(playlistIDPath) || !playlist.ExistsP(playlistChannelIDPath) || !playlist.ExistsP(playlistTitlePath) {
+        continue
+      }
+
+      playlistID := playlist.Path(playlistIDPath).Data().(string)
+      playlistChannelID := playlist.Path(playlistChannelIDPath).Data().(string)
+      playlistTitle := playlist.Path(playlistTitlePath).Data().(string)
+      var playlistPublishedTime string
+      if playlist.ExistsP(playlistPublishedTimePath) {
+        playlistPublishedTime = playlist.Path(playlistPublishedTimePath).Data().(string)
+      }
+      var playlistVideoCount string
+      if playlist.ExistsP(playlistVideoCountPath) {
+        playlistVideoCount = playlist.Path(playlistVideoCountPath).Data().(string)
+      }
+
+      playlists = append(playlists, ChannelPlaylist{
+        ID:            playlistID,
+        ChannelID:     playlistChannelID,
+        Title:         playlistTitle,
+        PublishedTime: playlistPublishedTime,
+        VideoCount:    playlistVideoCount,
+      })
     }
 
-    jsContent = strings.TrimPrefix(jsContent, "var ytInitialData =")
-    jsContent = strings.TrimSuffix(jsContent, ";")
+    ch.Shelves = append(ch.Shelves, ChannelShelf{Title: shelfTitle, Playlists: playlists})
+  }
+
+  return ch, nil
+}
 
-    const (
-      shelfListPath             = "contents.twoColumnBrowseResultsRenderer.tabs.0.tabRenderer.content.sectionListRenderer.contents"
-      shelfTitlePath            = "itemSectionRenderer.contents.0.shelfRenderer.title.runs.0.text"
-      playlistListPath          = "itemSectionRenderer.contents.0.shelfRenderer.content.horizontalListRenderer.items"
-      playlistIDPath            = "gridPlaylistRenderer.playlistId"
-      playlistChannelIDPath     = "gridPlaylistRenderer.longBylineText.runs.0.navigationEndpoint.browseEndpoint.browseId"
-      playlistTitlePath         = "gridPlaylistRenderer.title.runs.0.text"
-      playlistPublishedTimePath = "gridPlaylistRenderer.publishedTimeText.simpleText"
-      playlistVideoCountPath    = "gridPlaylistRenderer.videoCountShortText.simpleText"
-    )
+// findChapters prefers the chapters from the description, which are what
+// people actually wrote, over the ones YouTube guessed at.
+func findChapters(j *gabs.Container) []ytutil.Chapter {
+  const markersMapPath = "playerOverlays.playerOverlayRenderer.decoratedPlayerBarRenderer.decoratedPlayerBarRenderer.playerBar.multiMarkersPlayerBarRenderer.markersMap"
 
-    j, err := gabs.ParseJSON([]byte(jsContent))
-    if err != nil {
-      return nil, fmt.Errorf("ytdirect.GetChannel: %w", err)
-    }
+  var chapters []ytutil.Chapter
 
-    for _, shelf := range j.Path(shelfListPath).Children() {
-      if !shelf.ExistsP(shelfTitlePath) {
+  for _, markers := range j.Path(markersMapPath).Children() {
+    var found []ytutil.Chapter
+    for _, chapter := range markers.Path("value.chapters").Children() {
+      title, _ := chapter.Path("chapterRenderer.title.simpleText").Data().(string)
+      start, ok := chapter.Path("chapterRenderer.timeRangeStartMillis").Data().(float64)
+      if !ok {
         continue
       }
-      shelfTitle := shelf.Path(shelfTitlePath).Data().(string)
 
-      var playlists []ChannelPlaylist
+      found = append(found, ytutil.Chapter{Title: title, Start: time.Duration(start) * time.Millisecond})
+    }
 
-      for _, playlist := range shelf.Path(playlistListPath).Children() {
-        if !playlist.ExistsP(playlistIDPath) || !playlist.ExistsP(playlistChannelIDPath) || !playlist.ExistsP(playlistTitlePath) {
-          continue
-        }
+    if len(found) == 0 {
+      continue
+    }
 
-        playlistID := playlist.Path(playlistIDPath).Data().(string)
-        playlistChannelID := playlist.Path(playlistChannelIDPath).Data().(string)
-        playlistTitle := playlist.Path(playlistTitlePath).Data().(string)
-        var playlistPublishedTime string
-        if playlist.ExistsP(playlistPublishedTimePath) {
-          playlistPublishedTime = playlist.Path(playlistPublishedTimePath).Data().(string)
-        }
-        var playlistVideoCount string
-        if playlist.ExistsP(playlistVideoCountPath) {
-          playlistVideoCount = playlist.Path(playlistVideoCountPath).Data().(string)
-        }
+    if key, _ := markers.Path("key").Data().(string); key == "DESCRIPTION_CHAPTERS" {
+      return found
+    }
 
-        playlists = append(playlists, ChannelPlaylist{
-          ID:            playlistID,
-          ChannelID:     playlistChannelID,
-          Title:         playlistTitle,
-          PublishedTime: playlistPublishedTime,
-          VideoCount:    playlistVideoCount,
-        })
+    if chapters == nil {
+      chapters = found
+    }
+  }
+
+  return chapters
+}
+
+// largestImageURL finds the first of paths that holds a list of images, and
+// returns the URL of the widest one. Channel artwork comes in a few sizes, and
+// we only want to keep one.
+func largestImageURL(j *gabs.Container, paths ...string) string {
+  for _, path := range paths {
+    var best string
+    var bestWidth float64 = -1
+
+    for _, image := range j.Path(path).Children() {
+      u, ok := image.Path("url").Data().(string)
+      if !ok || u == "" {
+        continue
       }
 
-      ch.Shelves = append(ch.Shelves, ChannelShelf{Title: shelfTitle, Playlists: playlists})
+      width, _ := image.Path("width").Data().(float64)
+      if width > bestWidth {
+        best, bestWidth = u, width
+      }
+    }
+
+    if best != "" {
+      return absoluteThumbnailURL(best)
     }
   }
 
-  return ch, nil
+  return ""
 }
 
 type Playlist struct {
   ID        string
   ChannelID string
   Title     string
-  VideoIDs  []string
+  // ThumbnailURL is the playlist's artwork. For most playlists this is just
+  // the first video's thumbnail; albums and some curated playlists have
+  // their own.
+  ThumbnailURL string
+  VideoIDs     []string
+  // Entries lines up with VideoIDs, adding YouTube's ID for each entry so
+  // that the same video appearing twice can be told apart.
+  Entries []PlaylistEntry
+  // Truncated is set when there were more entries than were fetched, so
+  // anything missing from Entries might still be in the playlist.
+  Truncated bool
+}
+
+type PlaylistEntry struct {
+  VideoID    string
+  SetVideoID string
 }
 
+// maxPlaylistPages is how many pages of entries GetPlaylist will read. A
+// page is about a hundred entries and playlists top out at 5,000, so this is
+// only reached if YouTube's continuations go around in circles.
+const maxPlaylistPages = 100
+
 func GetPlaylist(ctx context.Context, id string) (*Playlist, error) {
   doc, err := getDocument(ctx, "https://www.youtube.com/playlist?list="+id)
   if err != nil {
     return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", err)
   }
 
-  var p Playlist
+  j, ok, err := findScriptJSON(doc, "var ytInitialData =")
+  if err != nil {
+    return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", err)
+  }
+  if !ok {
+    return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", &LayoutChangedError{Page: "playlist", ID: id, Path: "ytInitialData"})
+  }
 
-  for _, node := range doc.Find("script").Nodes {
-    if node.FirstChild == nil || node.FirstChild.Type != html.TextNode {
-      continue
+  var (
+    idPaths = []string{
+      "header.playlistHeaderRenderer.playButton.buttonRenderer.navigationEndpoint.watchEndpoint.playlistId",
+      "header.playlistHeaderRenderer.playlistHeaderBanner.heroPlaylistThumbnailRenderer.onTap.watchEndpoint.playlistId",
+      "header.playlistHeaderRenderer.playlistId",
+      "header.playlistHeaderRenderer.shufflePlayButton.buttonRenderer.navigationEndpoint.watchEndpoint.playlistId",
+      "sidebar.playlistSidebarRenderer.items.0.playlistSidebarPrimaryInfoRenderer.navigationEndpoint.watchEndpoint.playlistId",
+    }
+    titlePaths = []string{
+      "header.playlistHeaderRenderer.title.simpleText",
+      "metadata.playlistMetadataRenderer.albumName",
+      "metadata.playlistMetadataRenderer.title",
+      "microformat.microformatDataRenderer.title",
+    }
+    thumbnailPaths = []string{
+      "header.pageHeaderRenderer.content.pageHeaderViewModel.heroImage.contentPreviewImageViewModel.image.sources",
+      "header.playlistHeaderRenderer.playlistHeaderBanner.heroPlaylistThumbnailRenderer.thumbnail.thumbnails",
+      "microformat.microformatDataRenderer.thumbnail.thumbnails",
     }
+    entryListPath        = "contents.twoColumnBrowseResultsRenderer.tabs.0.tabRenderer.content.sectionListRenderer.contents.0.itemSectionRenderer.contents.0.playlistVideoListRenderer.contents"
+    continuationListPath = "onResponseReceivedActions.0.appendContinuationItemsAction.continuationItems"
+    continuationPath     = "continuationItemRenderer.continuationEndpoint.continuationCommand.token"
+    channelIDPath        = "playlistVideoRenderer.shortBylineText.runs.0.navigationEndpoint.browseEndpoint.browseId"
+    videoIDPath          = "playlistVideoRenderer.videoId"
+    setVideoIDPath       = "playlistVideoRenderer.setVideoId"
+  )
 
-    jsContent := node.FirstChild.Data
+  var p Playlist
 
-    if !strings.HasPrefix(jsContent, "var ytInitialData =") {
-      continue
+  for _, path := range idPaths {
+    if j.ExistsP(path) {
+      p.ID = j.Path(path).Data().(string)
+      break
     }
+  }
 
-    jsContent = strings.TrimPrefix(jsContent, "var ytInitialData =")
-    jsContent = strings.TrimSuffix(jsContent, ";")
-
-    var (
-      idPaths = []string{
-        "header.playlistHeaderRenderer.playButton.buttonRenderer.navigationEndpoint.watchEndpoint.playlistId",
-        "header.playlistHeaderRenderer.playlistHeaderBanner.heroPlaylistThumbnailRenderer.onTap.watchEndpoint.playlistId",
-        "header.playlistHeaderRenderer.playlistId",
-        "header.playlistHeaderRenderer.shufflePlayButton.buttonRenderer.navigationEndpoint.watchEndpoint.playlistId",
-        "sidebar.playlistSidebarRenderer.items.0.playlistSidebarPrimaryInfoRenderer.navigationEndpoint.watchEndpoint.playlistId",
-      }
-      titlePaths = []string{
-        "header.playlistHeaderRenderer.title.simpleText",
-        "metadata.playlistMetadataRenderer.albumName",
-        "metadata.playlistMetadataRenderer.title",
-        "microformat.microformatDataRenderer.title",
-      }
-      entryListPath = "contents.twoColumnBrowseResultsRenderer.tabs.0.tabRenderer.content.sectionListRenderer.contents.0.itemSectionRenderer.contents.0.playlistVideoListRenderer.contents"
-      channelIDPath = "playlistVideoRenderer.shortBylineText.runs.0.navigationEndpoint.browseEndpoint.browseId"
-      videoIDPath   = "playlistVideoRenderer.videoId"
-    )
+  if p.ID == "" {
+    return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", &LayoutChangedError{Page: "playlist", ID: id, Path: strings.Join(idPaths, " or ")})
+  }
 
-    j, err := gabs.ParseJSON([]byte(jsContent))
-    if err != nil {
-      return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", err)
+  for _, path := range titlePaths {
+    if j.ExistsP(path) {
+      p.Title = j.Path(path).Data().(string)
+      break
     }
+  }
 
-    for _, path := range idPaths {
-      if j.ExistsP(path) {
-        p.ID = j.Path(path).Data().(string)
-        break
-      }
-    }
+  p.ThumbnailURL = largestImageURL(j, thumbnailPaths...)
 
-    for _, path := range titlePaths {
-      if j.ExistsP(path) {
-        p.Title = j.Path(path).Data().(string)
-        break
-      }
-    }
+  if !j.ExistsP(entryListPath) {
+    return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", &LayoutChangedError{Page: "playlist", ID: id, Path: entryListPath})
+  }
+
+  elements := j.Path(entryListPath).Children()
 
-    if j.ExistsP(entryListPath) {
-      count, err := j.ArrayCountP(entryListPath)
-      if err != nil {
-        return nil, fmt.Errorf("ytdirect.GetPlaylist: could not get number of entries: %w", err)
+  // the page only has the first hundred or so entries, and each batch ends
+  // with a continuation to fetch the next one
+  for page := 1; ; page++ {
+    var continuation string
+
+    for _, element := range elements {
+      if token, ok := element.Path(continuationPath).Data().(string); ok {
+        continuation = token
+        continue
       }
 
-      for i := 0; i < count; i++ {
-        element, err := j.ArrayElementP(i, entryListPath)
-        if err != nil {
-          return nil, fmt.Errorf("ytdirect.GetPlaylist: could not get entry %d: %w", i, err)
+      if element.ExistsP(channelIDPath) {
+        if channelID, ok := element.Path(channelIDPath).Data().(string); ok {
+          p.ChannelID = channelID
         }
+      }
 
-        if element.ExistsP(channelIDPath) {
-          if channelID, ok := element.Path(channelIDPath).Data().(string); ok {
-            p.ChannelID = channelID
-          }
+      if element.ExistsP(videoIDPath) {
+        videoID, ok := element.Path(videoIDPath).Data().(string)
+        if !ok {
+          return nil, fmt.Errorf("ytdirect.GetPlaylist: could not get video id for entry %d", len(p.Entries))
         }
 
-        if element.ExistsP(videoIDPath) {
-          videoID, ok := element.Path(videoIDPath).Data().(string)
-          if !ok {
-            return nil, fmt.Errorf("ytdirect.GetPlaylist: could not get video id for entry %d", i)
-          }
+        setVideoID, _ := element.Path(setVideoIDPath).Data().(string)
 
-          p.VideoIDs = append(p.VideoIDs, videoID)
-        }
+        p.VideoIDs = append(p.VideoIDs, videoID)
+        p.Entries = append(p.Entries, PlaylistEntry{VideoID: videoID, SetVideoID: setVideoID})
       }
     }
-  }
 
-  if p.ID == "" {
-    return nil, fmt.Errorf("ytdirect.GetPlaylist: could not find suitable data in page")
+    if continuation == "" {
+      break
+    }
+
+    if page == maxPlaylistPages {
+      p.Truncated = true
+      break
+    }
+
+    c, err := postInnertube(ctx, "browse", map[string]interface{}{"continuation": continuation})
+    if err != nil {
+      return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", err)
+    }
+
+    if !c.ExistsP(continuationListPath) {
+      return nil, fmt.Errorf("ytdirect.GetPlaylist: %w", &LayoutChangedError{Page: "playlist continuation", ID: id, Path: continuationListPath})
+    }
+
+    elements = c.Path(continuationListPath).Children()
   }
 
   return &p, nil
 }
 
 type Video struct {
-  ID          string
-  ChannelID   string
-  Title       string
-  Description string
-  PublishDate string
-  UploadDate  string
+  ID                 string
+  ChannelID          string
+  Title              string
+  Description        string
+  PublishDate        string
+  UploadDate         string
+  Availability       ytutil.Availability
+  AvailabilityReason string
+  // Chapters are the ones YouTube shows on the player's progress bar, which
+  // it makes from timestamps in the description.
+  Chapters []ytutil.Chapter
 }
 
 func GetVideo(ctx context.Context, id string) (*Video, error) {
@@ -274,58 +449,72 @@ func GetVideo(ctx context.Context, id string) (*Video, error) {
     return nil, fmt.Errorf("ytdirect.GetVideo: %w", err)
   }
 
-  var v Video
+  j, ok, err := findScriptJSON(doc, "var ytInitialPlayerResponse =")
+  if err != nil {
+    return nil, fmt.Errorf("ytdirect.GetVideo: %w", err)
+  }
+  if !ok {
+    return nil, fmt.Errorf("ytdirect.GetVideo: %w", &LayoutChangedError{Page: "video", ID: id, Path: "ytInitialPlayerResponse"})
+  }
 
-  for _, node := range doc.Find("script").Nodes {
-    if node.FirstChild == nil || node.FirstChild.Type != html.TextNode {
-      continue
-    }
+  const (
+    playabilityStatusPath = "playabilityStatus.status"
+    playabilityReasonPath = "playabilityStatus.reason"
+    videoIDPath           = "videoDetails.videoId"
+    videoChannelIDPath    = "videoDetails.channelId"
+    videoTitlePath        = "microformat.playerMicroformatRenderer.title.simpleText"
+    videoDescriptionPath  = "microformat.playerMicroformatRenderer.description.simpleText"
+    videoPublishDatePath  = "microformat.playerMicroformatRenderer.publishDate"
+    videoUploadDatePath   = "microformat.playerMicroformatRenderer.uploadDate"
+  )
+
+  status, ok := j.Path(playabilityStatusPath).Data().(string)
+  if !ok {
+    return nil, fmt.Errorf("ytdirect.GetVideo: %w", &LayoutChangedError{Page: "video", ID: id, Path: playabilityStatusPath})
+  }
 
-    jsContent := node.FirstChild.Data
+  v := Video{Availability: ytutil.Available}
 
-    if !strings.HasPrefix(jsContent, "var ytInitialPlayerResponse =") {
-      continue
-    }
+  if status != "OK" {
+    reason, _ := j.Path(playabilityReasonPath).Data().(string)
 
-    jsContent = strings.TrimPrefix(jsContent, "var ytInitialPlayerResponse =")
-    jsContent = strings.TrimSuffix(jsContent, ";")
-
-    const (
-      videoIDPath          = "videoDetails.videoId"
-      videoChannelIDPath   = "videoDetails.channelId"
-      videoTitlePath       = "microformat.playerMicroformatRenderer.title.simpleText"
-      videoDescriptionPath = "microformat.playerMicroformatRenderer.description.simpleText"
-      videoPublishDatePath = "microformat.playerMicroformatRenderer.publishDate"
-      videoUploadDatePath  = "microformat.playerMicroformatRenderer.uploadDate"
-    )
+    v.Availability = ytutil.ClassifyReason(reason)
+    v.AvailabilityReason = reason
+  }
 
-    j, err := gabs.ParseJSON([]byte(jsContent))
-    if err != nil {
-      return nil, fmt.Errorf("ytdirect.GetVideo: %w", err)
-    }
+  if j.ExistsP(videoIDPath) {
+    v.ID = j.Path(videoIDPath).Data().(string)
+  }
+  if j.ExistsP(videoChannelIDPath) {
+    v.ChannelID = j.Path(videoChannelIDPath).Data().(string)
+  }
+  if j.ExistsP(videoTitlePath) {
+    v.Title = j.Path(videoTitlePath).Data().(string)
+  }
+  if j.ExistsP(videoDescriptionPath) {
+    v.Description = j.Path(videoDescriptionPath).Data().(string)
+  }
+  if j.ExistsP(videoPublishDatePath) {
+    v.PublishDate = j.Path(videoPublishDatePath).Data().(string)
+  }
+  if j.ExistsP(videoUploadDatePath) {
+    v.UploadDate = j.Path(videoUploadDatePath).Data().(string)
+  }
 
-    if j.ExistsP(videoIDPath) {
-      v.ID = j.Path(videoIDPath).Data().(string)
-    }
-    if j.ExistsP(videoChannelIDPath) {
-      v.ChannelID = j.Path(videoChannelIDPath).Data().(string)
-    }
-    if j.ExistsP(videoTitlePath) {
-      v.Title = j.Path(videoTitlePath).Data().(string)
-    }
-    if j.ExistsP(videoDescriptionPath) {
-      v.Description = j.Path(videoDescriptionPath).Data().(string)
-    }
-    if j.ExistsP(videoPublishDatePath) {
-      v.PublishDate = j.Path(videoPublishDatePath).Data().(string)
-    }
-    if j.ExistsP(videoUploadDatePath) {
-      v.UploadDate = j.Path(videoUploadDatePath).Data().(string)
-    }
+  // chapters are nice to have, so a page without them (or with them somewhere
+  // we don't know about) isn't a layout change
+  if data, ok, err := findScriptJSON(doc, "var ytInitialData ="); err == nil && ok {
+    v.Chapters = findChapters(data)
   }
 
   if v.ID == "" {
-    return nil, fmt.Errorf("ytdirect.GetVideo: could not find suitable data in page")
+    // private and removed videos come back without any details at all, so
+    // that's only a layout change if the page claims the video is playable
+    if v.Availability != ytutil.Available {
+      return nil, fmt.Errorf("ytdirect.GetVideo: %w", &ytutil.UnavailableError{ID: id, Availability: v.Availability, Reason: v.AvailabilityReason})
+    }
+
+    return nil, fmt.Errorf("ytdirect.GetVideo: %w", &LayoutChangedError{Page: "video", ID: id, Path: videoIDPath})
   }
 
   return &v, nil
@@ -13,7 +13,14 @@ import (
 	"fknsrs.biz/p/ytmusic/internal/httpcache"
 )
 
-func withContext(ctx context.Context, fn func(ctx context.Context)) error {
+// withContext runs fn against the real site, going through the HTTP cache at
+// TEST_CACHE_PATH if one is given. Tests using it are skipped unless
+// TEST_LIVE is set; the fixtures in testdata cover the same ground offline.
+func withContext(t *testing.T, ctx context.Context, fn func(ctx context.Context)) error {
+	if os.Getenv("TEST_LIVE") == "" {
+		t.Skip("skipping live test; set TEST_LIVE=1 to run")
+	}
+
 	cachePath := os.Getenv("TEST_CACHE_PATH")
 	if cachePath != "" {
 		cacheDB, err := bbolt.Open(cachePath, 0600, nil)
@@ -33,7 +40,7 @@ func withContext(ctx context.Context, fn func(ctx context.Context)) error {
 }
 
 func TestGetChannel(t *testing.T) {
-	withContext(context.Background(), func(ctx context.Context) {
+	withContext(t, context.Background(), func(ctx context.Context) {
 		for _, tc := range []struct {
 			id  string
 			err string
@@ -0,0 +1,24 @@
+package ytdl
+
+import (
+	"regexp"
+
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+// yt-dlp reports extractor errors as "ERROR: [youtube] <id>: <reason>". Only
+// the reasons below mean the video itself can't be had; anything else (rate
+// limiting, network trouble, a broken extractor) is worth retrying.
+var unavailablePattern = regexp.MustCompile(`(?m)^ERROR: \[youtube\] [^:]+: (.*(?:Video unavailable|Private video|confirm your age|members-only|Join this channel|not made this video available|has been removed).*)$`)
+
+func classifyError(id, stderr string) error {
+	if m := unavailablePattern.FindStringSubmatch(stderr); m != nil {
+		return &ytutil.UnavailableError{
+			ID:           id,
+			Availability: ytutil.ClassifyReason(m[1]),
+			Reason:       m[1],
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,42 @@
+package ytdl
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+func TestClassifyError(t *testing.T) {
+	for _, tc := range []struct {
+		stderr       string
+		availability ytutil.Availability
+	}{
+		{"ERROR: [youtube] pR1v4t3vId0: Private video. Sign in if you've been granted access to this video", ytutil.Private},
+		{"ERROR: [youtube] r3m0v3dvId0: Video unavailable. This video has been removed by the uploader", ytutil.Removed},
+		{"ERROR: [youtube] r3m0v3dvId0: Video unavailable. This video is no longer available because the YouTube account associated with this video has been terminated.", ytutil.Removed},
+		{"WARNING: [youtube] r3g10nbl0ck: nsig extraction failed\nERROR: [youtube] r3g10nbl0ck: Video unavailable. The uploader has not made this video available in your country", ytutil.RegionBlocked},
+		{"ERROR: [youtube] 4g3r35tr1ct: Sign in to confirm your age. This video may be inappropriate for some users. Use --cookies-from-browser or --cookies for the authentication.", ytutil.AgeRestricted},
+		{"ERROR: [youtube] m3mb3r50nly: Join this channel to get access to members-only content like this video, and other exclusive perks.", ytutil.MembersOnly},
+		{"ERROR: [youtube] uN4v41lvId0: Video unavailable", ytutil.Unavailable},
+		{"ERROR: unable to download video data: HTTP Error 403: Forbidden", ""},
+		{"ERROR: [youtube] xXa1bGk4pEs: Sign in to confirm you're not a bot.", ""},
+	} {
+		t.Run(tc.stderr, func(t *testing.T) {
+			a := assert.New(t)
+
+			err := classifyError("id", tc.stderr)
+
+			if tc.availability == "" {
+				a.NoError(err)
+				return
+			}
+
+			var unavailableErr *ytutil.UnavailableError
+			if a.ErrorAs(err, &unavailableErr) {
+				a.Equal(tc.availability, unavailableErr.Availability)
+			}
+		})
+	}
+}
@@ -0,0 +1,193 @@
+package ytdl
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/ytmeta"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+// Source fetches metadata with "yt-dlp -J". It's a lot slower than scraping
+// the pages ourselves, but it keeps working when YouTube changes its layout
+// and ytdirect hasn't caught up yet.
+//
+// Some things aren't available this way: channels come back without
+// shelves, and playlist entries don't have a setVideoId.
+type Source struct{}
+
+func (Source) Name() string { return "ytdlp" }
+
+type thumbnail struct {
+	ID     string `json:"id"`
+	URL    string `json:"url"`
+	Width  int    `json:"width"`
+	Height int    `json:"height"`
+}
+
+type channelInfo struct {
+	ID         string      `json:"id"`
+	ChannelID  string      `json:"channel_id"`
+	Channel    string      `json:"channel"`
+	Title      string      `json:"title"`
+	UploaderID string      `json:"uploader_id"`
+	Thumbnails []thumbnail `json:"thumbnails"`
+}
+
+type playlistInfo struct {
+	ID         string      `json:"id"`
+	Title      string      `json:"title"`
+	ChannelID  string      `json:"channel_id"`
+	Thumbnails []thumbnail `json:"thumbnails"`
+	Entries    []struct {
+		ID string `json:"id"`
+	} `json:"entries"`
+}
+
+type videoInfo struct {
+	ID          string `json:"id"`
+	Title       string `json:"title"`
+	Description string `json:"description"`
+	ChannelID   string `json:"channel_id"`
+	UploadDate  string `json:"upload_date"`
+	ReleaseDate string `json:"release_date"`
+	Chapters    []struct {
+		StartTime float64 `json:"start_time"`
+		Title     string  `json:"title"`
+	} `json:"chapters"`
+}
+
+func (Source) GetChannel(ctx context.Context, id string) (*ytmeta.Channel, error) {
+	var info channelInfo
+	if err := runCommandAndGetJSON(ctx, id, []string{"-J", "--flat-playlist", "--playlist-items", "0", "https://www.youtube.com/channel/" + id}, &info); err != nil {
+		return nil, fmt.Errorf("ytdl.Source.GetChannel: %w", err)
+	}
+
+	return parseChannel(id, &info)
+}
+
+func parseChannel(id string, info *channelInfo) (*ytmeta.Channel, error) {
+	c := ytmeta.Channel{
+		ID:    info.ChannelID,
+		Title: info.Channel,
+	}
+
+	if c.ID == "" {
+		c.ID = info.ID
+	}
+	if c.ID != id {
+		return nil, fmt.Errorf("ytdl.Source.GetChannel: expected channel %s but got %q", id, c.ID)
+	}
+	if c.Title == "" {
+		c.Title = strings.TrimSuffix(info.Title, " - Videos")
+	}
+	if strings.HasPrefix(info.UploaderID, "@") {
+		c.Handle = info.UploaderID
+	}
+
+	for _, t := range info.Thumbnails {
+		switch t.ID {
+		case "avatar_uncropped":
+			c.AvatarURL = t.URL
+		case "banner_uncropped":
+			c.BannerURL = t.URL
+		}
+	}
+
+	return &c, nil
+}
+
+func (Source) GetPlaylist(ctx context.Context, id string) (*ytmeta.Playlist, error) {
+	var info playlistInfo
+	if err := runCommandAndGetJSON(ctx, id, []string{"-J", "--flat-playlist", "https://www.youtube.com/playlist?list=" + id}, &info); err != nil {
+		return nil, fmt.Errorf("ytdl.Source.GetPlaylist: %w", err)
+	}
+
+	return parsePlaylist(id, &info)
+}
+
+func parsePlaylist(id string, info *playlistInfo) (*ytmeta.Playlist, error) {
+	if info.ID != id {
+		return nil, fmt.Errorf("ytdl.Source.GetPlaylist: expected playlist %s but got %q", id, info.ID)
+	}
+
+	p := ytmeta.Playlist{
+		ID:           info.ID,
+		ChannelID:    info.ChannelID,
+		Title:        info.Title,
+		ThumbnailURL: largestThumbnail(info.Thumbnails),
+	}
+
+	for _, e := range info.Entries {
+		p.VideoIDs = append(p.VideoIDs, e.ID)
+		p.Entries = append(p.Entries, ytmeta.PlaylistEntry{VideoID: e.ID})
+	}
+
+	return &p, nil
+}
+
+func (Source) GetVideo(ctx context.Context, id string) (*ytmeta.Video, error) {
+	var info videoInfo
+	if err := runCommandAndGetJSON(ctx, id, []string{"-J", "--skip-download", "https://www.youtube.com/watch?v=" + id}, &info); err != nil {
+		return nil, fmt.Errorf("ytdl.Source.GetVideo: %w", err)
+	}
+
+	return parseVideo(id, &info)
+}
+
+func parseVideo(id string, info *videoInfo) (*ytmeta.Video, error) {
+	if info.ID != id {
+		return nil, fmt.Errorf("ytdl.Source.GetVideo: expected video %s but got %q", id, info.ID)
+	}
+
+	// yt-dlp fails outright on anything it can't play, so whatever makes it
+	// this far is available
+	v := ytmeta.Video{
+		ID:           info.ID,
+		ChannelID:    info.ChannelID,
+		Title:        info.Title,
+		Description:  info.Description,
+		PublishDate:  formatDate(info.ReleaseDate),
+		UploadDate:   formatDate(info.UploadDate),
+		Availability: ytutil.Available,
+	}
+
+	if v.PublishDate == "" {
+		v.PublishDate = v.UploadDate
+	}
+
+	for _, c := range info.Chapters {
+		v.Chapters = append(v.Chapters, ytutil.Chapter{
+			Title: c.Title,
+			Start: time.Duration(c.StartTime * float64(time.Second)),
+		})
+	}
+
+	return &v, nil
+}
+
+// formatDate turns yt-dlp's YYYYMMDD dates into the YYYY-MM-DD that YouTube
+// uses everywhere else.
+func formatDate(s string) string {
+	t, err := time.Parse("20060102", s)
+	if err != nil {
+		return ""
+	}
+
+	return t.Format("2006-01-02")
+}
+
+func largestThumbnail(thumbnails []thumbnail) string {
+	var url string
+	var width int
+
+	for _, t := range thumbnails {
+		if url == "" || t.Width > width {
+			url, width = t.URL, t.Width
+		}
+	}
+
+	return url
+}
@@ -0,0 +1,113 @@
+package ytdl
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ytmeta"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+func readInfo(t *testing.T, name string, v interface{}) {
+	t.Helper()
+
+	d, err := os.ReadFile(filepath.Join("testdata", name))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(d, v); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestParseVideo(t *testing.T) {
+	for _, tc := range []struct {
+		file     string
+		id       string
+		expected ytmeta.Video
+	}{
+		{"video.json", "dQw4w9WgXcQ", ytmeta.Video{
+			ID:           "dQw4w9WgXcQ",
+			ChannelID:    "UCuAXFkgsw1L7xaCfnd5JJOw",
+			Title:        "Rick Astley - Never Gonna Give You Up (Official Music Video)",
+			Description:  "The official video for “Never Gonna Give You Up” by Rick Astley.",
+			PublishDate:  "2009-10-25",
+			UploadDate:   "2009-10-25",
+			Availability: ytutil.Available,
+		}},
+		{"video_release_date.json", "r3l34s3d4t3", ytmeta.Video{
+			ID:           "r3l34s3d4t3",
+			ChannelID:    "UCt0p1cCh4nn3lXXXXXXXXXX",
+			Title:        "Track One",
+			Description:  "Provided to YouTube by Example Distribution",
+			PublishDate:  "2023-02-17",
+			UploadDate:   "2023-03-01",
+			Availability: ytutil.Available,
+			Chapters: []ytutil.Chapter{
+				{Title: "Intro", Start: 0},
+				{Title: "Track One", Start: 12500 * time.Millisecond},
+			},
+		}},
+	} {
+		t.Run(tc.file, func(t *testing.T) {
+			a := assert.New(t)
+
+			var info videoInfo
+			readInfo(t, tc.file, &info)
+
+			v, err := parseVideo(tc.id, &info)
+			if a.NoError(err) {
+				a.Equal(tc.expected, *v)
+			}
+
+			_, err = parseVideo("someotherid", &info)
+			a.Error(err)
+		})
+	}
+}
+
+func TestParsePlaylist(t *testing.T) {
+	a := assert.New(t)
+
+	var info playlistInfo
+	readInfo(t, "playlist.json", &info)
+
+	p, err := parsePlaylist("PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI", &info)
+	if a.NoError(err) {
+		a.Equal(ytmeta.Playlist{
+			ID:           "PLFgquLnL59alCl_2TQvOiD5Vgm1hCaGSI",
+			ChannelID:    "UCuAXFkgsw1L7xaCfnd5JJOw",
+			Title:        "Example Playlist",
+			ThumbnailURL: "https://i.ytimg.com/vi/dQw4w9WgXcQ/hqdefault.jpg?sqp=large",
+			VideoIDs:     []string{"dQw4w9WgXcQ", "yPYZpwSpKmA", "dQw4w9WgXcQ"},
+			Entries: []ytmeta.PlaylistEntry{
+				{VideoID: "dQw4w9WgXcQ"},
+				{VideoID: "yPYZpwSpKmA"},
+				{VideoID: "dQw4w9WgXcQ"},
+			},
+		}, *p)
+	}
+}
+
+func TestParseChannel(t *testing.T) {
+	a := assert.New(t)
+
+	var info channelInfo
+	readInfo(t, "channel.json", &info)
+
+	c, err := parseChannel("UCuAXFkgsw1L7xaCfnd5JJOw", &info)
+	if a.NoError(err) {
+		a.Equal(ytmeta.Channel{
+			ID:        "UCuAXFkgsw1L7xaCfnd5JJOw",
+			Title:     "Rick Astley",
+			Handle:    "@RickAstleyYT",
+			AvatarURL: "https://yt3.googleusercontent.com/avatar=s0",
+			BannerURL: "https://yt3.googleusercontent.com/banner=s0",
+		}, *c)
+	}
+}
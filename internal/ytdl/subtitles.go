@@ -0,0 +1,91 @@
+package ytdl
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+type Subtitle struct {
+	Language  string
+	Name      string
+	Automatic bool
+	// File is where the WebVTT version of the track was saved.
+	File string
+}
+
+type subtitleInfo struct {
+	// Subtitles lists the tracks the uploader provided. Anything requested
+	// that isn't in here came from automatic_captions instead.
+	Subtitles          map[string]json.RawMessage `json:"subtitles"`
+	RequestedSubtitles map[string]struct {
+		Ext  string `json:"ext"`
+		Name string `json:"name"`
+	} `json:"requested_subtitles"`
+}
+
+// DownloadSubtitles saves the caption tracks for languages (in yt-dlp's
+// --sub-langs syntax) to dir as <id>.<language>.vtt. yt-dlp prefers the
+// uploader's subtitles, falling back to automatic captions for languages
+// that don't have any.
+func DownloadSubtitles(ctx context.Context, id, dir string, languages []string) ([]Subtitle, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("ytdl.DownloadSubtitles: %w", err)
+	}
+
+	var info subtitleInfo
+	if err := runCommandAndGetJSON(ctx, id, []string{
+		"-j", "--no-simulate", "--skip-download",
+		"--write-subs", "--write-auto-subs",
+		"--sub-langs", strings.Join(languages, ","),
+		"--sub-format", "vtt/best",
+		"--convert-subs", "vtt",
+		"-o", filepath.Join(dir, id+".%(ext)s"),
+		"https://www.youtube.com/watch?v=" + id,
+	}, &info); err != nil {
+		return nil, fmt.Errorf("ytdl.DownloadSubtitles: %w", err)
+	}
+
+	return findSubtitles(id, dir, &info), nil
+}
+
+// findSubtitles matches what yt-dlp said it would download against what
+// actually ended up on disk, since it only warns when a track fails.
+func findSubtitles(id, dir string, info *subtitleInfo) []Subtitle {
+	var subtitles []Subtitle
+
+	for language, requested := range info.RequestedSubtitles {
+		if language == "live_chat" {
+			continue
+		}
+
+		file := filepath.Join(dir, id+"."+language+".vtt")
+		if _, err := os.Stat(file); err != nil {
+			continue
+		}
+
+		_, manual := info.Subtitles[language]
+
+		name := requested.Name
+		if name == "" {
+			name = language
+		}
+
+		subtitles = append(subtitles, Subtitle{
+			Language:  language,
+			Name:      name,
+			Automatic: !manual,
+			File:      file,
+		})
+	}
+
+	sort.Slice(subtitles, func(i, j int) bool {
+		return subtitles[i].Language < subtitles[j].Language
+	})
+
+	return subtitles
+}
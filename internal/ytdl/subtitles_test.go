@@ -0,0 +1,31 @@
+package ytdl
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindSubtitles(t *testing.T) {
+	a := assert.New(t)
+
+	var info subtitleInfo
+	readInfo(t, "subtitles.json", &info)
+
+	dir := t.TempDir()
+
+	// fr was requested but failed to download, so it shouldn't be listed
+	for _, language := range []string{"en", "de", "ja"} {
+		if err := os.WriteFile(filepath.Join(dir, "dQw4w9WgXcQ."+language+".vtt"), []byte("WEBVTT\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	a.Equal([]Subtitle{
+		{Language: "de", Name: "German", Automatic: true, File: filepath.Join(dir, "dQw4w9WgXcQ.de.vtt")},
+		{Language: "en", Name: "English", Automatic: false, File: filepath.Join(dir, "dQw4w9WgXcQ.en.vtt")},
+		{Language: "ja", Name: "ja", Automatic: true, File: filepath.Join(dir, "dQw4w9WgXcQ.ja.vtt")},
+	}, findSubtitles("dQw4w9WgXcQ", dir, &info))
+}
@@ -2,12 +2,18 @@ package ytdl
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
 	"fmt"
 	"os/exec"
+	"path/filepath"
 	"regexp"
 	"strconv"
+	"strings"
+	"sync"
+
+	"fknsrs.biz/p/ytmusic/internal/partial"
 )
 
 const (
@@ -18,9 +24,24 @@ func makeProcess(args []string) *exec.Cmd {
 	return exec.Command(ProgramName, args...)
 }
 
-func runCommandAndGetJSON(args []string, output interface{}) error {
-	stdout, err := makeProcess(args).Output()
+// runCommandAndGetJSON runs yt-dlp and decodes what it prints. Errors that
+// mean id can't be fetched at all come back as *ytutil.UnavailableError.
+func runCommandAndGetJSON(ctx context.Context, id string, args []string, output interface{}) error {
+	cmd := exec.CommandContext(ctx, ProgramName, args...)
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	stdout, err := cmd.Output()
 	if err != nil {
+		if err := classifyError(id, stderr.String()); err != nil {
+			return err
+		}
+
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("%w: %s", err, msg)
+		}
+
 		return err
 	}
 
@@ -46,18 +67,84 @@ func DownloadVideo(ctx context.Context, id string, outputFile string) error {
 }
 
 func DownloadVideoWithProgress(ctx context.Context, id string, outputFile string, progressCallback ProgressCallback) error {
-	cmd := exec.CommandContext(ctx, ProgramName,
+	if err := download(ctx, id, []string{
 		"-f", "bestvideo+bestaudio",
 		"-S", "ext:mp4:m4a",
 		"-o", outputFile,
+	}, progressCallback); err != nil {
+		return fmt.Errorf("failed to download video: %w", err)
+	}
+
+	return nil
+}
+
+// DownloadAudioWithProgress fetches only the best audio stream, as-is, into
+// dir. The video stream is never downloaded at all. YouTube decides the
+// format, so the file's name is only known afterwards and is returned. It's
+// a partial name until the caller has checked the file and committed it.
+func DownloadAudioWithProgress(ctx context.Context, id string, dir string, progressCallback ProgressCallback) (string, error) {
+	if err := download(ctx, id, []string{
+		"-f", "bestaudio",
+		"-o", partial.Name(filepath.Join(dir, id+".%(ext)s")),
+	}, progressCallback); err != nil {
+		return "", fmt.Errorf("failed to download audio: %w", err)
+	}
+
+	file, ok := findAudio(dir, id, true)
+	if !ok {
+		return "", fmt.Errorf("failed to download audio: no file for %s in %s", id, dir)
+	}
+
+	return file, nil
+}
+
+// FindDownloadedAudio finds audio that DownloadAudioWithProgress saved
+// earlier and that has been committed.
+func FindDownloadedAudio(dir, id string) (string, bool) {
+	return findAudio(dir, id, false)
+}
+
+// findAudio finds a video's audio in dir, either under its partial name or
+// its final one, skipping anything yt-dlp didn't finish.
+func findAudio(dir, id string, partialName bool) (string, bool) {
+	matches, err := filepath.Glob(filepath.Join(dir, id+".*"))
+	if err != nil {
+		return "", false
+	}
+
+	for _, m := range matches {
+		if strings.HasSuffix(m, ".part") || strings.HasSuffix(m, ".ytdl") {
+			continue
+		}
+
+		if partial.Is(m) == partialName {
+			return m, true
+		}
+	}
+
+	return "", false
+}
+
+func download(ctx context.Context, id string, args []string, progressCallback ProgressCallback) error {
+	// with --continue and --part, a download that was cut off picks up from
+	// the .part files it left behind instead of starting again
+	cmd := exec.CommandContext(ctx, ProgramName, append(args,
+		"--continue",
+		"--part",
 		"--newline",
 		"https://www.youtube.com/watch?v="+id,
-	)
+	)...)
 
 	if progressCallback == nil {
 		// Use the simple version without progress tracking
+		var stderr bytes.Buffer
+		cmd.Stderr = &stderr
+
 		if _, err := cmd.Output(); err != nil {
-			return fmt.Errorf("failed to download video: %w", err)
+			if err := classifyError(id, stderr.String()); err != nil {
+				return err
+			}
+			return err
 		}
 		return nil
 	}
@@ -79,8 +166,13 @@ func DownloadVideoWithProgress(ctx context.Context, id string, outputFile string
 	// Progress pattern for yt-dlp: [download]  45.2% of  123.45MiB at    1.23MiB/s ETA 00:12
 	progressPattern := regexp.MustCompile(`\[download\]\s+(\d+(?:\.\d+)?)%`)
 
+	var stderrOutput bytes.Buffer
+	var wg sync.WaitGroup
+	wg.Add(2)
+
 	// Monitor stdout for progress
 	go func() {
+		defer wg.Done()
 		scanner := bufio.NewScanner(stdout)
 		for scanner.Scan() {
 			line := scanner.Text()
@@ -94,9 +186,11 @@ func DownloadVideoWithProgress(ctx context.Context, id string, outputFile string
 
 	// Monitor stderr for errors
 	go func() {
+		defer wg.Done()
 		scanner := bufio.NewScanner(stderr)
 		for scanner.Scan() {
 			line := scanner.Text()
+			stderrOutput.WriteString(line + "\n")
 			// Still look for progress in stderr as yt-dlp sometimes outputs there
 			if matches := progressPattern.FindStringSubmatch(line); len(matches) > 1 {
 				if percent, err := strconv.ParseFloat(matches[1], 32); err == nil {
@@ -106,8 +200,14 @@ func DownloadVideoWithProgress(ctx context.Context, id string, outputFile string
 		}
 	}()
 
+	// Both pipes have to be drained before Wait closes them
+	wg.Wait()
+
 	if err := cmd.Wait(); err != nil {
-		return fmt.Errorf("failed to download video: %w", err)
+		if err := classifyError(id, stderrOutput.String()); err != nil {
+			return err
+		}
+		return err
 	}
 
 	return nil
@@ -1,5 +1,44 @@
 package ytdl
 
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestFindAudio(t *testing.T) {
+	a := assert.New(t)
+
+	dir := t.TempDir()
+
+	_, ok := FindDownloadedAudio(dir, "dQw4w9WgXcQ")
+	a.False(ok)
+
+	// a download that was cut off, and one that finished but wasn't checked
+	for _, name := range []string{"dQw4w9WgXcQ.partial.webm.part", "dQw4w9WgXcQ.partial.webm.ytdl", "dQw4w9WgXcQ.partial.opus"} {
+		if err := os.WriteFile(filepath.Join(dir, name), nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	_, ok = FindDownloadedAudio(dir, "dQw4w9WgXcQ")
+	a.False(ok)
+
+	file, ok := findAudio(dir, "dQw4w9WgXcQ", true)
+	a.True(ok)
+	a.Equal(filepath.Join(dir, "dQw4w9WgXcQ.partial.opus"), file)
+
+	if err := os.WriteFile(filepath.Join(dir, "dQw4w9WgXcQ.webm"), nil, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	file, ok = FindDownloadedAudio(dir, "dQw4w9WgXcQ")
+	a.True(ok)
+	a.Equal(filepath.Join(dir, "dQw4w9WgXcQ.webm"), file)
+}
+
 // TODO: Implement tests for progress callback functionality
 // These tests are disabled as they reference functions not yet implemented
 
@@ -0,0 +1,62 @@
+// Package ytmeta has the channel, playlist, and video metadata that every
+// metadata source returns, so that none of them depend on each other.
+package ytmeta
+
+import (
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
+)
+
+type Channel struct {
+	ID        string
+	Title     string
+	Handle    string
+	AvatarURL string
+	BannerURL string
+	// Shelves are the groups of playlists on the channel's page. Not every
+	// source can get them.
+	Shelves []ChannelShelf
+}
+
+type ChannelShelf struct {
+	Title     string
+	Playlists []ChannelPlaylist
+}
+
+type ChannelPlaylist struct {
+	ID            string
+	ChannelID     string
+	Title         string
+	PublishedTime string
+	VideoCount    string
+}
+
+type Playlist struct {
+	ID           string
+	ChannelID    string
+	Title        string
+	ThumbnailURL string
+	VideoIDs     []string
+	// Entries lines up with VideoIDs. SetVideoID is empty if the source
+	// didn't supply it.
+	Entries []PlaylistEntry
+	// Truncated is set when there were more entries than were fetched, so
+	// anything missing from Entries might still be in the playlist.
+	Truncated bool
+}
+
+type PlaylistEntry struct {
+	VideoID    string
+	SetVideoID string
+}
+
+type Video struct {
+	ID                 string
+	ChannelID          string
+	Title              string
+	Description        string
+	PublishDate        string
+	UploadDate         string
+	Availability       ytutil.Availability
+	AvailabilityReason string
+	Chapters           []ytutil.Chapter
+}
@@ -0,0 +1,81 @@
+package ytutil
+
+import (
+	"fmt"
+	"strings"
+)
+
+type Availability string
+
+const (
+	Available     = Availability("available")
+	Private       = Availability("private")
+	Removed       = Availability("removed")
+	RegionBlocked = Availability("region_blocked")
+	AgeRestricted = Availability("age_restricted")
+	MembersOnly   = Availability("members_only")
+	Unavailable   = Availability("unavailable")
+)
+
+func (a Availability) Label() string {
+	switch a {
+	case "", Available:
+		return ""
+	case Private:
+		return "Private video"
+	case Removed:
+		return "Removed video"
+	case RegionBlocked:
+		return "Blocked in this region"
+	case AgeRestricted:
+		return "Age restricted"
+	case MembersOnly:
+		return "Members only"
+	default:
+		return "Unavailable video"
+	}
+}
+
+// ClassifyReason maps the human-readable reasons YouTube gives for not
+// playing a video onto an Availability. It understands both the text in a
+// watch page's playabilityStatus and the messages yt-dlp prints, since the
+// latter are mostly the former with a prefix.
+func ClassifyReason(reason string) Availability {
+	s := strings.ToLower(reason)
+
+	switch {
+	case strings.Contains(s, "private video"), strings.Contains(s, "video is private"):
+		return Private
+	case strings.Contains(s, "removed"), strings.Contains(s, "terminated"), strings.Contains(s, "no longer available"):
+		return Removed
+	case strings.Contains(s, "in your country"), strings.Contains(s, "geo restriction"), strings.Contains(s, "geo-restricted"):
+		return RegionBlocked
+	case strings.Contains(s, "confirm your age"), strings.Contains(s, "age-restricted"), strings.Contains(s, "inappropriate for some users"):
+		return AgeRestricted
+	case strings.Contains(s, "members-only"), strings.Contains(s, "join this channel"):
+		return MembersOnly
+	default:
+		return Unavailable
+	}
+}
+
+var (
+	ErrUnavailable = fmt.Errorf("video unavailable")
+)
+
+// UnavailableError is returned by metadata and download functions when
+// YouTube refuses to serve a video. Callers can use errors.As to find out
+// why, and should usually record that instead of retrying.
+type UnavailableError struct {
+	ID           string
+	Availability Availability
+	Reason       string
+}
+
+func (e *UnavailableError) Error() string {
+	return fmt.Sprintf("%s: %s: %s (%s)", ErrUnavailable, e.ID, e.Availability, e.Reason)
+}
+
+func (e *UnavailableError) Unwrap() error {
+	return ErrUnavailable
+}
@@ -0,0 +1,98 @@
+package ytutil
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Chapter is a titled section of a video. It runs until the next chapter
+// starts, or the end of the video.
+type Chapter struct {
+	Title string
+	Start time.Duration
+}
+
+var (
+	chapterTimestampPattern = regexp.MustCompile(`(?:^|[\s\[(])((?:\d{1,2}:)?\d{1,2}:\d{2})(?:$|[\s\])])`)
+	chapterNumberPattern    = regexp.MustCompile(`^\d{1,3}[.)]\s+`)
+)
+
+// chapterTrim is what's left around a title once its timestamp is taken
+// out: "0:00 - Intro", "[0:00] Intro", "Intro (0:00)", and so on.
+const chapterTrim = " \t-–—|:•·[]()"
+
+// ParseChapters finds a tracklist in a video's description, the same way
+// YouTube does when it makes chapters: each line with a timestamp starts a
+// chapter, the first one has to be at 0:00, and they have to go forwards.
+// A line with a range ("0:00 - 3:45 Intro") starts at the first timestamp.
+// If the description doesn't have a tracklist like that, the result is nil.
+func ParseChapters(description string) []Chapter {
+	var chapters []Chapter
+
+	for _, line := range strings.Split(description, "\n") {
+		matches := chapterTimestampPattern.FindAllStringSubmatchIndex(line, -1)
+		if len(matches) == 0 {
+			continue
+		}
+
+		start, ok := parseChapterTimestamp(line[matches[0][2]:matches[0][3]])
+		if !ok {
+			continue
+		}
+
+		// cut all of the timestamps out, leaving the text around them
+		var title strings.Builder
+		last := 0
+		for _, m := range matches {
+			title.WriteString(line[last:m[2]])
+			title.WriteString(" ")
+			last = m[3]
+		}
+		title.WriteString(line[last:])
+
+		t := strings.Trim(strings.Join(strings.Fields(title.String()), " "), chapterTrim)
+		t = strings.Trim(chapterNumberPattern.ReplaceAllString(t, ""), chapterTrim)
+
+		chapters = append(chapters, Chapter{Title: t, Start: start})
+	}
+
+	if len(chapters) < 2 || chapters[0].Start != 0 {
+		return nil
+	}
+
+	for i := 1; i < len(chapters); i++ {
+		if chapters[i].Start <= chapters[i-1].Start {
+			return nil
+		}
+	}
+
+	for i := range chapters {
+		if chapters[i].Title == "" {
+			chapters[i].Title = "Chapter " + strconv.Itoa(i+1)
+		}
+	}
+
+	return chapters
+}
+
+func parseChapterTimestamp(s string) (time.Duration, bool) {
+	parts := strings.Split(s, ":")
+
+	var d time.Duration
+	for i, p := range parts {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			return 0, false
+		}
+		// everything but the leading part is out of 60
+		if i > 0 && n >= 60 {
+			return 0, false
+		}
+
+		d = d*60 + time.Duration(n)
+	}
+
+	return d * time.Second, true
+}
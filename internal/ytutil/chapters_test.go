@@ -0,0 +1,47 @@
+package ytutil
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseChapters(t *testing.T) {
+	for _, tc := range []struct {
+		name        string
+		description string
+		expected    []Chapter
+	}{
+		{
+			"plain",
+			"Full album.\n\nTracklist:\n0:00 Intro\n2:15 Second Song\n1:02:03 Last Song\n\nThanks for listening!",
+			[]Chapter{{"Intro", 0}, {"Second Song", 2*time.Minute + 15*time.Second}, {"Last Song", time.Hour + 2*time.Minute + 3*time.Second}},
+		},
+		{
+			"separators and numbering",
+			"1. [00:00] - Intro\n2. [03:30] – Nightjar\n3) Afterglow (07:45)",
+			[]Chapter{{"Intro", 0}, {"Nightjar", 3*time.Minute + 30*time.Second}, {"Afterglow", 7*time.Minute + 45*time.Second}},
+		},
+		{
+			"ranges",
+			"00:00 - 03:30 Intro\n03:30 - 07:45 Nightjar",
+			[]Chapter{{"Intro", 0}, {"Nightjar", 3*time.Minute + 30*time.Second}},
+		},
+		{
+			"untitled",
+			"0:00\n1:00 Second",
+			[]Chapter{{"Chapter 1", 0}, {"Second", time.Minute}},
+		},
+		{"no timestamps", "Provided to YouTube by DistroKid\n\nNightjar · Taylor Lee Czer", nil},
+		{"not starting at zero", "0:10 Intro\n2:00 Song", nil},
+		{"out of order", "0:00 Intro\n5:00 Song\n2:00 Other", nil},
+		{"only one", "0:00 Intro", nil},
+		{"bad timestamp", "0:00 Intro\n1:75 Song", nil},
+		{"not a timestamp", "0:00 Intro\nCall 555:1234 now\n1:00 Song", []Chapter{{"Intro", 0}, {"Song", time.Minute}}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			assert.Equal(t, tc.expected, ParseChapters(tc.description))
+		})
+	}
+}
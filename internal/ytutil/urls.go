@@ -0,0 +1,232 @@
+package ytutil
+
+import (
+	"net/url"
+	"regexp"
+	"strings"
+)
+
+var (
+	channelIDPattern  = regexp.MustCompile(`^UC[-_a-zA-Z0-9]{22}$`)
+	playlistIDPattern = regexp.MustCompile(`^(?:PL[-_a-zA-Z0-9]{16,32}|OLAK5uy_[-_a-zA-Z0-9]{33}|UU[-_a-zA-Z0-9]{22,24}|FL[-_a-zA-Z0-9]{22})$`)
+	videoIDPattern    = regexp.MustCompile(`^[-_a-zA-Z0-9]{11}$`)
+	handlePattern     = regexp.MustCompile(`^@[-_.\pL\pN]{3,30}$`)
+
+	// plainWordPattern matches things that fit in a video ID but are almost
+	// certainly just words: "Programming", "well-formed", "NEVERMIND", or
+	// a phone number.
+	plainWordPattern = regexp.MustCompile(`^(?:[A-Za-z][a-z]*(?:[-_][A-Za-z][a-z]*)*|[A-Z]+(?:[-_][A-Z]+)*|[0-9]+)$`)
+)
+
+func IsChannelID(s string) bool {
+	return channelIDPattern.MatchString(s)
+}
+
+// IsPlaylistID reports whether s looks like a playlist that can be fetched:
+// a user playlist, an album (OLAK5uy_), or a channel's uploads or favourites.
+// Generated mixes (RD...) are deliberately excluded, since they're different
+// every time and often turn up in the list parameter of a watch URL.
+func IsPlaylistID(s string) bool {
+	return playlistIDPattern.MatchString(s)
+}
+
+// IsVideoID reports whether s has the shape of a video ID. "videoseries"
+// does too, but it's the placeholder /embed/ URLs use for playlists.
+func IsVideoID(s string) bool {
+	return videoIDPattern.MatchString(s) && s != "videoseries"
+}
+
+func isIDOfType(idType IDType, s string) bool {
+	switch idType {
+	case ChannelID:
+		return IsChannelID(s)
+	case PlaylistID:
+		return IsPlaylistID(s)
+	case VideoID:
+		return IsVideoID(s)
+	default:
+		return false
+	}
+}
+
+func hostSet(hosts ...string) map[string]bool {
+	m := make(map[string]bool)
+	for _, host := range hosts {
+		m[host] = true
+	}
+	return m
+}
+
+var (
+	youtubeHosts = hostSet("youtube.com", "www.youtube.com", "m.youtube.com", "music.youtube.com")
+	embedHosts   = hostSet("youtube.com", "www.youtube.com", "m.youtube.com", "youtube-nocookie.com", "www.youtube-nocookie.com")
+	shortHosts   = hostSet("youtu.be")
+)
+
+func isYouTubeHost(host string) bool {
+	return youtubeHosts[host]
+}
+
+type urlRule struct {
+	hosts  map[string]bool
+	path   *regexp.Regexp
+	idType IDType
+	// query names the query parameter holding the ID; when it's empty, the
+	// ID is the first capture group of path.
+	query string
+}
+
+// urlRules are tried in order, and every rule that matches contributes an
+// ID. The first is the one the URL is "for"; any others, like the playlist
+// in watch?v=...&list=..., are marked as extras.
+var urlRules = []urlRule{
+	{youtubeHosts, regexp.MustCompile(`^/watch/?$`), VideoID, "v"},
+	{youtubeHosts, regexp.MustCompile(`^/(?:shorts|live|v)/([^/]+)/?$`), VideoID, ""},
+	{embedHosts, regexp.MustCompile(`^/embed/([^/]+)/?$`), VideoID, ""},
+	{shortHosts, regexp.MustCompile(`^/([^/]+)/?$`), VideoID, ""},
+	{youtubeHosts, regexp.MustCompile(`^/(?:watch|playlist)/?$`), PlaylistID, "list"},
+	{embedHosts, regexp.MustCompile(`^/embed/[^/]+/?$`), PlaylistID, "list"},
+	{shortHosts, regexp.MustCompile(`^/[^/]+/?$`), PlaylistID, "list"},
+	{youtubeHosts, regexp.MustCompile(`^/(?:channel|c)/([^/]+)(?:/[^/]*)?$`), ChannelID, ""},
+	{youtubeHosts, regexp.MustCompile(`^/channel/?$`), ChannelID, "channel_id"},
+}
+
+// parseURL parses s if it's a link to one of the hosts we know about. Links
+// pasted without a scheme, like "youtu.be/...", are accepted too.
+func parseURL(s string) (*url.URL, bool) {
+	if !strings.Contains(s, "://") {
+		host := strings.ToLower(strings.SplitN(s, "/", 2)[0])
+		if !youtubeHosts[host] && !embedHosts[host] && !shortHosts[host] {
+			return nil, false
+		}
+		s = "https://" + s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return nil, false
+	}
+
+	u.Host = strings.ToLower(u.Hostname())
+
+	return u, true
+}
+
+func recognizeURL(u *url.URL) []ID {
+	var ids []ID
+
+	for _, rule := range urlRules {
+		if !rule.hosts[u.Host] {
+			continue
+		}
+
+		m := rule.path.FindStringSubmatch(u.Path)
+		if m == nil {
+			continue
+		}
+
+		var value string
+		if rule.query != "" {
+			value = u.Query().Get(rule.query)
+		} else if len(m) > 1 {
+			value = m[1]
+		}
+
+		if !isIDOfType(rule.idType, value) {
+			continue
+		}
+
+		duplicate := false
+		for _, id := range ids {
+			if id.Type == rule.idType {
+				duplicate = true
+			}
+		}
+		if duplicate {
+			continue
+		}
+
+		ids = append(ids, ID{Type: rule.idType, Value: value, Extra: len(ids) > 0})
+	}
+
+	return ids
+}
+
+// IsVideoThumbnailURL reports whether u is one of the frames YouTube serves
+// for a video, as opposed to artwork someone uploaded.
+func IsVideoThumbnailURL(u string) bool {
+	parsed, err := url.Parse(u)
+	if err != nil {
+		return false
+	}
+
+	host := parsed.Hostname()
+	if host != "ytimg.com" && !strings.HasSuffix(host, ".ytimg.com") {
+		return false
+	}
+
+	return strings.HasPrefix(parsed.Path, "/vi/") || strings.HasPrefix(parsed.Path, "/vi_webp/")
+}
+
+// VideoThumbnailURL is the frame YouTube picks for a video. Unlike the larger
+// sizes, hqdefault exists for every video.
+func VideoThumbnailURL(id string) string {
+	return "https://i.ytimg.com/vi/" + id + "/hqdefault.jpg"
+}
+
+// trimFreeText strips punctuation that's likely to be stuck to a URL or ID
+// pasted in with some text, e.g. "(see https://youtu.be/...)." None of these
+// characters can appear at the ends of an ID.
+func trimFreeText(s string) string {
+	return strings.TrimLeft(strings.TrimRight(s, `.,;:!?)]}>"'`), `([{<"'`)
+}
+
+func identify(urlOrID string) ([]ID, bool) {
+	s := trimFreeText(strings.TrimSpace(urlOrID))
+
+	if u, ok := parseURL(s); ok {
+		if ids := recognizeURL(u); len(ids) > 0 {
+			return ids, true
+		}
+
+		if ref, err := ExtractChannelRef(u.String()); err == nil {
+			return []ID{{Type: ChannelRef, Value: ref}}, true
+		}
+
+		return nil, false
+	}
+
+	switch {
+	case IsChannelID(s):
+		return []ID{{Type: ChannelID, Value: s}}, true
+	case handlePattern.MatchString(s):
+		return []ID{{Type: ChannelRef, Value: s}}, true
+	case IsPlaylistID(s):
+		return []ID{{Type: PlaylistID, Value: s}}, true
+	case IsVideoID(s) && !plainWordPattern.MatchString(s):
+		return []ID{{Type: VideoID, Value: s}}, true
+	}
+
+	return nil, false
+}
+
+// channelTabs are the pages under a channel URL that can be stripped off to
+// find the channel itself, e.g. youtube.com/@someone/videos.
+var channelTabs = map[string]bool{
+	"featured": true, "videos": true, "shorts": true, "streams": true,
+	"playlists": true, "community": true, "channels": true, "about": true,
+}
+
+// reservedPaths are top-level youtube.com and music.youtube.com paths that
+// look like vanity URLs but aren't.
+var reservedPaths = map[string]bool{
+	"watch": true, "playlist": true, "channel": true, "c": true, "user": true,
+	"results": true, "feed": true, "shorts": true, "embed": true, "live": true,
+	"hashtag": true, "redirect": true, "account": true, "premium": true,
+	"gaming": true, "kids": true, "music": true, "signin": true, "logout": true,
+	"post": true, "source": true, "attribution_link": true, "about": true,
+	"t": true, "s": true, "v": true, "yt": true, "howyoutubeworks": true,
+	"browse": true, "explore": true, "library": true, "search": true,
+	"charts": true, "new_releases": true, "moods_and_genres": true,
+	"podcasts": true, "paid_memberships": true, "upload": true, "tv": true,
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"io/ioutil"
+	"net/http"
 	"net/url"
 	"regexp"
 	"strings"
@@ -16,6 +17,7 @@ type IDType string
 const (
 	InvalidID  = IDType("invalid")
 	ChannelID  = IDType("channel")
+	ChannelRef = IDType("channel_ref")
 	PlaylistID = IDType("playlist")
 	VideoID    = IDType("video")
 )
@@ -23,16 +25,19 @@ const (
 type ID struct {
 	Type  IDType
 	Value string
+	// Extra is set on IDs that came along with another one, like the
+	// playlist in a watch?v=...&list=... URL.
+	Extra bool
 }
 
 func ExtractAndIdentifyIDs(text string, ignoreInvalid bool) ([]ID, error) {
 	var ids []ID
 
 	for _, urlOrID := range strings.Fields(text) {
-		if idType, id, err := ExtractAndIdentifyID(urlOrID); err == nil {
-			ids = append(ids, ID{idType, id})
+		if found, ok := identify(urlOrID); ok {
+			ids = append(ids, found...)
 		} else if !ignoreInvalid {
-			return nil, fmt.Errorf("ytutil.ExtractAndIdentifyIDs: could not identify %q: %w", urlOrID, err)
+			return nil, fmt.Errorf("ytutil.ExtractAndIdentifyIDs: could not identify %q", urlOrID)
 		}
 	}
 
@@ -40,105 +45,87 @@ func ExtractAndIdentifyIDs(text string, ignoreInvalid bool) ([]ID, error) {
 }
 
 func ExtractAndIdentifyID(urlOrID string) (IDType, string, error) {
-	if channelID, err := ExtractChannelID(urlOrID); err == nil {
-		return ChannelID, channelID, nil
-	}
-
-	if playlistID, err := ExtractPlaylistID(urlOrID); err == nil {
-		return PlaylistID, playlistID, nil
-	}
-
-	if videoID, err := ExtractVideoID(urlOrID); err == nil {
-		return VideoID, videoID, nil
+	if ids, ok := identify(urlOrID); ok {
+		return ids[0].Type, ids[0].Value, nil
 	}
 
 	return InvalidID, "", fmt.Errorf("ytutil.ExtractAndIdentifyID: could not extract a known ID type")
 }
 
-func ExtractChannelID(urlOrID string) (string, error) {
-	if len(urlOrID) == 24 {
-		return urlOrID, nil
+func extractIDOfType(urlOrID string, idType IDType) (string, bool) {
+	ids, _ := identify(urlOrID)
+	for _, id := range ids {
+		if id.Type == idType {
+			return id.Value, true
+		}
 	}
 
-	if parsed, err := url.Parse(urlOrID); err == nil {
-		if parsed.Path == "/channel" || strings.HasPrefix(parsed.Path, "/channel/") || parsed.Path == "/c" || strings.HasPrefix(parsed.Path, "/c/") {
-			id := parsed.Query().Get("channel_id")
-
-			if id == "" {
-				parts := strings.Split(parsed.Path, "/")
-				if len(parts) >= 3 {
-					id = parts[2]
-				}
-			}
-
-			if len(id) != 24 {
-				return "", fmt.Errorf("ytutil.ExtractChannelID: invalid channel id; length should be 24")
-			}
+	return "", false
+}
 
-			return id, nil
-		}
+func ExtractChannelID(urlOrID string) (string, error) {
+	if id, ok := extractIDOfType(urlOrID, ChannelID); ok {
+		return id, nil
 	}
 
 	return "", fmt.Errorf("ytutil.ExtractChannelID: invalid url or id; could not find a known pattern")
 }
 
 func ExtractPlaylistID(urlOrID string) (string, error) {
-	u, err := url.Parse(urlOrID)
-	if err == nil && u.Scheme != "" && u.Host == "www.youtube.com" && u.Path == "/playlist" {
-		return ExtractPlaylistID(u.Query().Get("list"))
-	}
-
-	playlistID := strings.TrimSpace(urlOrID)
-	if len(playlistID) == 0 {
-		return "", fmt.Errorf("ytutil.ExtractPlaylistID: empty input")
-	}
-
-	if strings.HasPrefix(playlistID, "PL") || strings.HasPrefix(playlistID, "UU") || strings.HasPrefix(playlistID, "FL") {
-		return playlistID, nil
-	}
-
-	if len(playlistID) == 34 || len(playlistID) == 41 {
-		return playlistID, nil
+	if id, ok := extractIDOfType(urlOrID, PlaylistID); ok {
+		return id, nil
 	}
 
 	return "", fmt.Errorf("ytutil.ExtractPlaylistID: invalid url or id; could not find a known pattern")
 }
 
 func ExtractVideoID(urlOrID string) (string, error) {
-	if len(urlOrID) == 11 {
-		return urlOrID, nil
-	}
-
-	parsed, err := url.Parse(urlOrID)
-	if err != nil {
-		return "", err
+	if id, ok := extractIDOfType(urlOrID, VideoID); ok {
+		return id, nil
 	}
 
-	if parsed.Host == "www.youtube.com" && parsed.Path == "/watch" {
-		if id := parsed.Query().Get("v"); id != "" {
-			if len(id) != 11 {
-				return "", fmt.Errorf("invalid video id for v parameter in youtube.com url; length should be 11")
-			}
+	return "", fmt.Errorf("ytutil.ExtractVideoID: invalid url or id; could not find a known pattern")
+}
 
-			return id, nil
+// ExtractChannelRef recognises the ways of pointing at a channel that don't
+// contain its ID: @handles (bare or as a URL), /user/ URLs, and /c/ or
+// top-level vanity URLs. The result is the path to request from youtube.com
+// to find the ID, e.g. "@someone", "user/someone", or "c/someone"; pass it to
+// ResolveChannel.
+func ExtractChannelRef(urlOrRef string) (string, error) {
+	if strings.HasPrefix(urlOrRef, "@") {
+		if !handlePattern.MatchString(urlOrRef) {
+			return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid handle")
 		}
+		return urlOrRef, nil
+	}
 
-		return "", fmt.Errorf("no v query parameter in youtube.com url")
+	parsed, ok := parseURL(urlOrRef)
+	if !ok || !isYouTubeHost(parsed.Host) {
+		return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid url; could not find a known pattern")
 	}
 
-	if parsed.Host == "youtu.be" {
-		if id := strings.TrimPrefix(parsed.Path, "/"); id != "" {
-			if len(id) != 11 {
-				return "", fmt.Errorf("invalid video id for youtu.be url; length should be 11")
-			}
+	parts := strings.Split(strings.Trim(parsed.Path, "/"), "/")
 
-			return id, nil
+	switch {
+	case strings.HasPrefix(parts[0], "@"):
+		if !handlePattern.MatchString(parts[0]) || (len(parts) > 1 && !channelTabs[parts[1]]) {
+			return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid handle url")
 		}
-
-		return "", fmt.Errorf("no path content found in youtu.be url")
+		return parts[0], nil
+	case parts[0] == "user" || parts[0] == "c":
+		if len(parts) < 2 || parts[1] == "" || (len(parts) > 2 && !channelTabs[parts[2]]) {
+			return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid %s url", parts[0])
+		}
+		return parts[0] + "/" + parts[1], nil
+	case parts[0] != "" && !reservedPaths[parts[0]]:
+		if len(parts) > 1 && !channelTabs[parts[1]] {
+			return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid vanity url")
+		}
+		return parts[0], nil
 	}
 
-	return "", fmt.Errorf("invalid url or id; could not find a known pattern")
+	return "", fmt.Errorf("ytutil.ExtractChannelRef: invalid url; could not find a known pattern")
 }
 
 func FindChannelID(ctx context.Context, urlOrID string) (string, error) {
@@ -146,6 +133,12 @@ func FindChannelID(ctx context.Context, urlOrID string) (string, error) {
 		switch idType {
 		case ChannelID:
 			return id, nil
+		case ChannelRef:
+			channel, err := ResolveChannel(ctx, id)
+			if err != nil {
+				return "", fmt.Errorf("ytutil.FindChannelID: %w", err)
+			}
+			return channel.ID, nil
 		case PlaylistID:
 			channelID, err := getChannelIDFromURL(ctx, "https://www.youtube.com/playlist?list="+id)
 			if err != nil {
@@ -188,3 +181,76 @@ func getChannelIDFromURL(ctx context.Context, url string) (string, error) {
 
 	return "", fmt.Errorf("ytutil.getChannelIDFromURL: could not find channel id in response")
 }
+
+// HandleFromURL returns the @handle from a channel URL like the
+// vanityChannelUrl that YouTube puts in channel metadata, or an empty string
+// if the URL doesn't contain one.
+func HandleFromURL(s string) string {
+	parsed, ok := parseURL(s)
+	if !ok || !isYouTubeHost(parsed.Host) {
+		return ""
+	}
+
+	handle := strings.Split(strings.TrimPrefix(parsed.Path, "/"), "/")[0]
+	if !handlePattern.MatchString(handle) {
+		return ""
+	}
+
+	return handle
+}
+
+type ResolvedChannel struct {
+	ID     string
+	Handle string
+}
+
+var (
+	resolveCanonicalPattern  = regexp.MustCompile(`<link rel="canonical" href="https://www\.youtube\.com/channel/(UC[-_a-zA-Z0-9]{22})"`)
+	resolveExternalIDPattern = regexp.MustCompile(`"externalId":"(UC[-_a-zA-Z0-9]{22})"`)
+	resolveVanityURLPattern  = regexp.MustCompile(`"vanityChannelUrl":"([^"]+)"`)
+)
+
+// ResolveChannel looks up the channel ID for a reference returned by
+// ExtractChannelRef by fetching its page. The request goes through the
+// context's HTTP client, so lookups are cached like any other page. Handle is
+// the channel's current @handle if the page lists one.
+func ResolveChannel(ctx context.Context, ref string) (*ResolvedChannel, error) {
+	parts := strings.Split(ref, "/")
+	for i := range parts {
+		parts[i] = url.PathEscape(parts[i])
+	}
+
+	res, err := ctxhttpclient.GetHTTPClient(ctx).Get("https://www.youtube.com/" + strings.Join(parts, "/"))
+	if err != nil {
+		return nil, fmt.Errorf("ytutil.ResolveChannel: could not perform request: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("ytutil.ResolveChannel: could not resolve %q: unexpected status %s", ref, res.Status)
+	}
+
+	body, err := ioutil.ReadAll(res.Body)
+	if err != nil {
+		return nil, fmt.Errorf("ytutil.ResolveChannel: could not read response: %w", err)
+	}
+
+	var channel ResolvedChannel
+
+	if m := resolveCanonicalPattern.FindSubmatch(body); m != nil {
+		channel.ID = string(m[1])
+	} else if m := resolveExternalIDPattern.FindSubmatch(body); m != nil {
+		channel.ID = string(m[1])
+	} else {
+		return nil, fmt.Errorf("ytutil.ResolveChannel: could not find channel id for %q in response", ref)
+	}
+
+	if m := resolveVanityURLPattern.FindSubmatch(body); m != nil {
+		channel.Handle = HandleFromURL(string(m[1]))
+	}
+	if channel.Handle == "" && strings.HasPrefix(ref, "@") {
+		channel.Handle = ref
+	}
+
+	return &channel, nil
+}
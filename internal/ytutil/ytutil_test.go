@@ -0,0 +1,229 @@
+package ytutil
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ctxhttpclient"
+)
+
+func TestExtractAndIdentifyIDChannels(t *testing.T) {
+	for _, tc := range []struct {
+		in     string
+		idType IDType
+		id     string
+	}{
+		{"UCpNvmbdtY8WAzhdNUDxbT2g", ChannelID, "UCpNvmbdtY8WAzhdNUDxbT2g"},
+		{"https://www.youtube.com/channel/UCpNvmbdtY8WAzhdNUDxbT2g", ChannelID, "UCpNvmbdtY8WAzhdNUDxbT2g"},
+		{"https://www.youtube.com/c/UCpNvmbdtY8WAzhdNUDxbT2g", ChannelID, "UCpNvmbdtY8WAzhdNUDxbT2g"},
+		{"@taylorleeczer-topic", ChannelRef, "@taylorleeczer-topic"},
+		{"https://www.youtube.com/@taylorleeczer-topic", ChannelRef, "@taylorleeczer-topic"},
+		{"https://youtube.com/@taylorleeczer-topic/videos", ChannelRef, "@taylorleeczer-topic"},
+		{"https://m.youtube.com/@taylorleeczer-topic", ChannelRef, "@taylorleeczer-topic"},
+		{"https://www.youtube.com/user/TaylorLeeCzer", ChannelRef, "user/TaylorLeeCzer"},
+		{"https://www.youtube.com/user/TaylorLeeCzer/playlists", ChannelRef, "user/TaylorLeeCzer"},
+		{"https://www.youtube.com/c/TaylorLeeCzer", ChannelRef, "c/TaylorLeeCzer"},
+		{"https://www.youtube.com/TaylorLeeCzer", ChannelRef, "TaylorLeeCzer"},
+		{"https://www.youtube.com/TaylorLeeCzer/about", ChannelRef, "TaylorLeeCzer"},
+		{"https://www.youtube.com/watch?v=xXa1bGk4pEs", VideoID, "xXa1bGk4pEs"},
+		{"https://www.youtube.com/playlist?list=PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE", PlaylistID, "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE"},
+		{"@x", InvalidID, ""},
+		{"https://www.youtube.com/", InvalidID, ""},
+		{"https://www.youtube.com/results?search_query=taylor", InvalidID, ""},
+		{"https://www.youtube.com/TaylorLeeCzer/something", InvalidID, ""},
+		{"https://example.com/@taylorleeczer-topic", InvalidID, ""},
+		{"https://example.com/user/TaylorLeeCzer", InvalidID, ""},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			a := assert.New(t)
+
+			idType, id, err := ExtractAndIdentifyID(tc.in)
+			a.Equal(tc.idType, idType)
+			a.Equal(tc.id, id)
+			a.Equal(tc.idType == InvalidID, err != nil)
+		})
+	}
+}
+
+func TestExtractAndIdentifyIDURLs(t *testing.T) {
+	const (
+		v  = "xXa1bGk4pEs"
+		pl = "PLq3UZa7STrbo9t6ruM6cr5TFdYiFpC3mE"
+		al = "OLAK5uy_kS4hVUk3lvmSWsa8bXXkLqPq2BYc5Sp2c"
+		ch = "UCpNvmbdtY8WAzhdNUDxbT2g"
+	)
+
+	video := ID{Type: VideoID, Value: v}
+	playlist := ID{Type: PlaylistID, Value: pl}
+
+	for _, tc := range []struct {
+		in  string
+		ids []ID
+	}{
+		{"https://www.youtube.com/watch?v=" + v, []ID{video}},
+		{"http://youtube.com/watch?v=" + v + "&t=42s", []ID{video}},
+		{"https://m.youtube.com/watch?v=" + v, []ID{video}},
+		{"https://music.youtube.com/watch?v=" + v + "&feature=share", []ID{video}},
+		{"https://WWW.YouTube.com/watch?v=" + v, []ID{video}},
+		{"https://www.youtube.com/shorts/" + v, []ID{video}},
+		{"https://www.youtube.com/live/" + v + "?si=abc", []ID{video}},
+		{"https://www.youtube.com/embed/" + v, []ID{video}},
+		{"https://www.youtube-nocookie.com/embed/" + v + "?start=10", []ID{video}},
+		{"https://youtu.be/" + v, []ID{video}},
+		{"https://youtu.be/" + v + "?t=1", []ID{video}},
+		{"youtu.be/" + v, []ID{video}},
+		{"www.youtube.com/watch?v=" + v, []ID{video}},
+		{"(https://youtu.be/" + v + ").", []ID{video}},
+		{"https://www.youtube.com/playlist?list=" + pl, []ID{playlist}},
+		{"https://m.youtube.com/playlist?list=" + pl, []ID{playlist}},
+		{"https://music.youtube.com/playlist?list=" + al, []ID{{Type: PlaylistID, Value: al}}},
+		{"https://www.youtube.com/embed/videoseries?list=" + pl, []ID{playlist}},
+		{"https://www.youtube.com/watch?v=" + v + "&list=" + pl + "&index=3", []ID{video, {Type: PlaylistID, Value: pl, Extra: true}}},
+		{"https://youtu.be/" + v + "?list=" + pl, []ID{video, {Type: PlaylistID, Value: pl, Extra: true}}},
+		{"https://music.youtube.com/watch?v=" + v + "&list=RDAMVM" + v, []ID{video}},
+		{"https://music.youtube.com/channel/" + ch, []ID{{Type: ChannelID, Value: ch}}},
+		{"https://www.youtube.com/channel/" + ch + "/videos", []ID{{Type: ChannelID, Value: ch}}},
+		{v, []ID{video}},
+		{pl, []ID{playlist}},
+		{al, []ID{{Type: PlaylistID, Value: al}}},
+		{"https://www.youtube.com/watch?v=tooshort", nil},
+		{"https://www.youtube.com/watch?list=RDAMVM" + v, nil},
+		{"https://www.youtube.com/shorts/", nil},
+		{"https://music.youtube.com/explore", nil},
+		{"https://example.com/watch?v=" + v, nil},
+		{"https://youtu.be.example.com/" + v, nil},
+		{"ftp://youtu.be/" + v, nil},
+	} {
+		t.Run(tc.in, func(t *testing.T) {
+			a := assert.New(t)
+
+			ids, err := ExtractAndIdentifyIDs(tc.in, false)
+			if tc.ids == nil {
+				a.Error(err)
+			} else {
+				a.NoError(err)
+			}
+			a.Equal(tc.ids, ids)
+		})
+	}
+}
+
+func TestExtractAndIdentifyIDsFreeText(t *testing.T) {
+	a := assert.New(t)
+
+	text := `Hey, have a listen to https://youtu.be/xXa1bGk4pEs (recommended!) and
+the album at music.youtube.com/playlist?list=OLAK5uy_kS4hVUk3lvmSWsa8bXXkLqPq2BYc5Sp2c.
+Programming, well-formed, NEVERMIND, 04123456789, PLEASE, @, and UCLA
+shouldn't show up, but @taylorleeczer-topic should.`
+
+	ids, err := ExtractAndIdentifyIDs(text, true)
+	a.NoError(err)
+	a.Equal([]ID{
+		{Type: VideoID, Value: "xXa1bGk4pEs"},
+		{Type: PlaylistID, Value: "OLAK5uy_kS4hVUk3lvmSWsa8bXXkLqPq2BYc5Sp2c"},
+		{Type: ChannelRef, Value: "@taylorleeczer-topic"},
+	}, ids)
+
+	_, err = ExtractAndIdentifyIDs(text, false)
+	a.Error(err)
+}
+
+func TestHandleFromURL(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("@taylorleeczer-topic", HandleFromURL("http://www.youtube.com/@taylorleeczer-topic"))
+	a.Equal("", HandleFromURL("http://www.youtube.com/c/TaylorLeeCzer"))
+	a.Equal("", HandleFromURL("http://example.com/@taylorleeczer-topic"))
+}
+
+func TestIsVideoThumbnailURL(t *testing.T) {
+	a := assert.New(t)
+
+	a.True(IsVideoThumbnailURL("https://i.ytimg.com/vi/xXa1bGk4pEs/hqdefault.jpg?sqp=-oaymwEXCOADEI4CSFryq4qpAwkIARUAAIhCGAE="))
+	a.True(IsVideoThumbnailURL("https://i9.ytimg.com/vi_webp/xXa1bGk4pEs/mqdefault.webp"))
+	a.False(IsVideoThumbnailURL("https://yt3.googleusercontent.com/aVaTaR0x1=s160-c-k-c0x00ffffff-no-rj"))
+	a.False(IsVideoThumbnailURL("https://i.ytimg.com/an/pNvmbdtY8WAzhdNUDxbT2g/featured_channel.jpg"))
+	a.False(IsVideoThumbnailURL("https://notytimg.com/vi/xXa1bGk4pEs/hqdefault.jpg"))
+	a.False(IsVideoThumbnailURL(""))
+}
+
+func TestVideoThumbnailURL(t *testing.T) {
+	a := assert.New(t)
+
+	a.Equal("https://i.ytimg.com/vi/xXa1bGk4pEs/hqdefault.jpg", VideoThumbnailURL("xXa1bGk4pEs"))
+	a.True(IsVideoThumbnailURL(VideoThumbnailURL("xXa1bGk4pEs")))
+}
+
+func TestResolveChannel(t *testing.T) {
+	pages := map[string]string{
+		"/@taylorleeczer-topic": `<link rel="canonical" href="https://www.youtube.com/channel/UCpNvmbdtY8WAzhdNUDxbT2g"><script>var ytInitialData = {"metadata":{"channelMetadataRenderer":{"externalId":"UCpNvmbdtY8WAzhdNUDxbT2g","vanityChannelUrl":"http://www.youtube.com/@taylorleeczer-topic"}}};</script>`,
+		"/user/TaylorLeeCzer":   `<script>var ytInitialData = {"metadata":{"channelMetadataRenderer":{"externalId":"UCpNvmbdtY8WAzhdNUDxbT2g","vanityChannelUrl":"http://www.youtube.com/@taylorleeczer-topic"}}};</script>`,
+		"/c/TaylorLeeCzer":      `<script>var ytInitialData = {"metadata":{"channelMetadataRenderer":{"externalId":"UCpNvmbdtY8WAzhdNUDxbT2g"}}};</script>`,
+		"/@nochannel":           `<html></html>`,
+	}
+
+	s := httptest.NewServer(http.HandlerFunc(func(rw http.ResponseWriter, r *http.Request) {
+		page, ok := pages[r.URL.Path]
+		if !ok {
+			http.NotFound(rw, r)
+			return
+		}
+
+		rw.Write([]byte(page))
+	}))
+	defer s.Close()
+
+	target, err := url.Parse(s.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx := ctxhttpclient.WithHTTPClient(context.Background(), &http.Client{
+		Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+			r = r.Clone(r.Context())
+			r.URL.Scheme = target.Scheme
+			r.URL.Host = target.Host
+			return http.DefaultTransport.RoundTrip(r)
+		}),
+	})
+
+	for _, tc := range []struct {
+		ref    string
+		id     string
+		handle string
+		err    bool
+	}{
+		{"@taylorleeczer-topic", "UCpNvmbdtY8WAzhdNUDxbT2g", "@taylorleeczer-topic", false},
+		{"user/TaylorLeeCzer", "UCpNvmbdtY8WAzhdNUDxbT2g", "@taylorleeczer-topic", false},
+		{"c/TaylorLeeCzer", "UCpNvmbdtY8WAzhdNUDxbT2g", "", false},
+		{"@nochannel", "", "", true},
+		{"@missing", "", "", true},
+	} {
+		t.Run(tc.ref, func(t *testing.T) {
+			a := assert.New(t)
+
+			channel, err := ResolveChannel(ctx, tc.ref)
+			if tc.err {
+				a.Error(err)
+				a.Nil(channel)
+				return
+			}
+
+			if a.NoError(err) {
+				a.Equal(tc.id, channel.ID)
+				a.Equal(tc.handle, channel.Handle)
+			}
+		})
+	}
+}
+
+type roundTripperFunc func(r *http.Request) (*http.Response, error)
+
+func (fn roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return fn(r)
+}
@@ -1,16 +1,26 @@
 package main
 
 import (
+	"bytes"
 	"context"
 	"database/sql"
 	"embed"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"html/template"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"io"
+	"mime"
 	"net"
 	"net/http"
+	"net/url"
 	"os"
+	"path/filepath"
 	"reflect"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -27,6 +37,8 @@ import (
 	"go.etcd.io/bbolt"
 
 	"fknsrs.biz/p/ytmusic/handlers"
+	"fknsrs.biz/p/ytmusic/internal/audioprofile"
+	"fknsrs.biz/p/ytmusic/internal/collage"
 	"fknsrs.biz/p/ytmusic/internal/config"
 	"fknsrs.biz/p/ytmusic/internal/configreader"
 	"fknsrs.biz/p/ytmusic/internal/ctxclock"
@@ -38,16 +50,24 @@ import (
 	"fknsrs.biz/p/ytmusic/internal/ctxtemplate"
 	"fknsrs.biz/p/ytmusic/internal/ctxtimer"
 	"fknsrs.biz/p/ytmusic/internal/ffmpeg"
+	"fknsrs.biz/p/ytmusic/internal/hls"
 	"fknsrs.biz/p/ytmusic/internal/httpcache"
 	"fknsrs.biz/p/ytmusic/internal/jobqueue"
+	"fknsrs.biz/p/ytmusic/internal/libraryscan"
 	"fknsrs.biz/p/ytmusic/internal/logrusstackhook"
+	"fknsrs.biz/p/ytmusic/internal/metadatasource"
+	"fknsrs.biz/p/ytmusic/internal/partial"
+	"fknsrs.biz/p/ytmusic/internal/playlistsync"
 	"fknsrs.biz/p/ytmusic/internal/ptr"
 	"fknsrs.biz/p/ytmusic/internal/queuenames"
 	"fknsrs.biz/p/ytmusic/internal/sqlitelogger"
+	"fknsrs.biz/p/ytmusic/internal/storyboard"
 	"fknsrs.biz/p/ytmusic/internal/stringutil"
 	"fknsrs.biz/p/ytmusic/internal/templatecollection"
-	"fknsrs.biz/p/ytmusic/internal/ytdirect"
+	"fknsrs.biz/p/ytmusic/internal/waveform"
+	"fknsrs.biz/p/ytmusic/internal/webvtt"
 	"fknsrs.biz/p/ytmusic/internal/ytdl"
+	"fknsrs.biz/p/ytmusic/internal/ytutil"
 	"fknsrs.biz/p/ytmusic/models"
 )
 
@@ -66,6 +86,15 @@ var cfg = config.Config{
 	ApplicationDataPath:  "data",
 	ApplicationMinify:    true,
 	BackgroundWorkers:    1,
+	MetadataChannel:      config.StringList{"ytdirect", "ytdlp"},
+	MetadataPlaylist:     config.StringList{"ytdirect", "ytdlp"},
+	MetadataVideo:        config.StringList{"ytdirect", "ytdlp"},
+	SubtitleLanguages:    config.StringList{"en"},
+	SplitChapters:        false,
+	AudioProfiles:        config.AudioProfileList{{Name: "mp3", Codec: "mp3", Quality: "2"}},
+	DownloadProfile:      models.DownloadProfileVideo,
+	NormalizeAudio:       false,
+	Renditions:           config.RenditionList{},
 }
 
 //go:embed templates
@@ -117,7 +146,8 @@ func (s *simpleQueryLogger) LogQueryAfter(query string, args []interface{}, dura
 func main() {
 	ctx := context.Background()
 
-	if err := configreader.Read(os.Args[0], os.Args[1:], os.Environ(), &cfg); err != nil {
+	args, err := configreader.Read(os.Args[0], os.Args[1:], os.Environ(), &cfg)
+	if err != nil {
 		panic(err)
 	}
 
@@ -147,6 +177,15 @@ func main() {
 		"config.application_data_path":  cfg.ApplicationDataPath,
 		"config.application_minify":     cfg.ApplicationMinify,
 		"config.background_workers":     cfg.BackgroundWorkers,
+		"config.metadata_channel":       cfg.MetadataChannel,
+		"config.metadata_playlist":      cfg.MetadataPlaylist,
+		"config.metadata_video":         cfg.MetadataVideo,
+		"config.subtitle_languages":     cfg.SubtitleLanguages,
+		"config.split_chapters":         cfg.SplitChapters,
+		"config.audio_profiles":         cfg.AudioProfiles,
+		"config.download_profile":       cfg.DownloadProfile,
+		"config.normalize_audio":        cfg.NormalizeAudio,
+		"config.renditions":             cfg.Renditions,
 	}).Info("program starting")
 
 	if cfg.LogSORM {
@@ -216,6 +255,17 @@ func main() {
 		panic(err)
 	}
 
+	// a command runs once in the foreground instead of starting the server
+	// and the background workers
+	if len(args) > 0 {
+		if err := runCommand(ctx, args[0], args[1:]); err != nil {
+			fmt.Fprintf(os.Stderr, "%s: %v\n", args[0], err)
+			os.Exit(1)
+		}
+
+		return
+	}
+
 	workers := []worker{
 		{
 			name: "application",
@@ -239,6 +289,41 @@ func main() {
 	}
 }
 
+// runCommand runs one of the commands that can be given after the flags.
+func runCommand(ctx context.Context, name string, args []string) error {
+	switch name {
+	case "verify":
+		if len(args) > 0 {
+			return fmt.Errorf("verify doesn't take any arguments")
+		}
+
+		return runVerifyCommand(ctx)
+	default:
+		return fmt.Errorf("unrecognised command; valid options are verify")
+	}
+}
+
+// runVerifyCommand does what a library_verify job does and prints what it
+// found. It fails if anything was wrong, so that it can be run from cron.
+func runVerifyCommand(ctx context.Context) error {
+	verification, issues, err := verifyLibrary(ctx, nil)
+	if err != nil {
+		return err
+	}
+
+	for _, i := range issues {
+		fmt.Printf("%s\t%s\t%s\n", i.Kind, i.Path(), i.Detail)
+	}
+
+	fmt.Printf("checked %d files and found %d problems\n", verification.FilesChecked, len(issues))
+
+	if len(issues) > 0 {
+		return fmt.Errorf("found %d problems", len(issues))
+	}
+
+	return nil
+}
+
 type worker struct {
 	name string
 	run  func(ctx context.Context) error
@@ -434,6 +519,9 @@ func runApplicationWorker(ctx context.Context, addr string) error {
 		"make_string_list": func(items ...string) []string {
 			return items
 		},
+		"availability_label": func(s string) string {
+			return ytutil.Availability(s).Label()
+		},
 	}
 
 	var templates templatecollection.Collection
@@ -461,10 +549,12 @@ func runApplicationWorker(ctx context.Context, addr string) error {
 	m.Methods(http.MethodPost).Path("/add").HandlerFunc(handlers.AddAction)
 	m.Methods(http.MethodGet).Path("/channels").HandlerFunc(handlers.Channels)
 	m.Methods(http.MethodGet).Path("/channels/{id}").HandlerFunc(handlers.Channel)
+	m.Methods(http.MethodPost).Path("/channels/{id}/download-profile").HandlerFunc(handlers.ChannelDownloadProfileAction)
 	m.Methods(http.MethodGet).Path("/channels/{id}/audio").HandlerFunc(handlers.ChannelAudio)
 	m.Methods(http.MethodGet).Path("/channels/{id}/audio-zip").HandlerFunc(handlers.ChannelAudioZip)
 	m.Methods(http.MethodGet).Path("/playlists").HandlerFunc(handlers.Playlists)
 	m.Methods(http.MethodGet).Path("/playlists/{id}").HandlerFunc(handlers.Playlist)
+	m.Methods(http.MethodPost).Path("/playlists/{id}/download-profile").HandlerFunc(handlers.PlaylistDownloadProfileAction)
 	m.Methods(http.MethodGet).Path("/playlists/{id}/audio").HandlerFunc(handlers.PlaylistAudio)
 	m.Methods(http.MethodGet).Path("/playlists/{id}/audio-zip").HandlerFunc(handlers.PlaylistAudioZip)
 	m.Methods(http.MethodGet).Path("/playlists/{id}/{index}").HandlerFunc(handlers.PlaylistVideo)
@@ -472,8 +562,17 @@ func runApplicationWorker(ctx context.Context, addr string) error {
 	m.Methods(http.MethodGet).Path("/videos/audio").HandlerFunc(handlers.VideosAudio)
 	m.Methods(http.MethodGet).Path("/videos/audio-zip").HandlerFunc(handlers.VideosAudioZip)
 	m.Methods(http.MethodGet).Path("/videos/{id}").HandlerFunc(handlers.Video)
+	m.Methods(http.MethodGet).Path("/videos/{id}/waveform").HandlerFunc(handlers.VideoWaveform)
+	m.Methods(http.MethodPost).Path("/videos/{id}/split-chapters").HandlerFunc(handlers.VideoSplitChaptersAction)
+	m.Methods(http.MethodPost).Path("/videos/{id}/transcode").HandlerFunc(handlers.VideoTranscodeAction)
+	m.Methods(http.MethodGet).Path("/revisions/{id}").HandlerFunc(handlers.Revision)
 	m.Methods(http.MethodGet).Path("/jobs").HandlerFunc(handlers.Jobs)
 	m.Methods(http.MethodGet).Path("/jobs/updates").HandlerFunc(handlers.JobsSSE)
+	m.Methods(http.MethodGet).Path("/storage").HandlerFunc(handlers.Storage)
+	m.Methods(http.MethodGet).Path("/library").HandlerFunc(handlers.Library)
+	m.Methods(http.MethodPost).Path("/library/verify").HandlerFunc(handlers.LibraryVerifyAction)
+	m.Methods(http.MethodPost).Path("/library/requeue").HandlerFunc(handlers.LibraryRequeueAction)
+	m.Methods(http.MethodPost).Path("/library/issues/{id}/requeue").HandlerFunc(handlers.LibraryIssueRequeueAction)
 
 	if directoryExists("static") {
 		l.Info("using live filesystem for static files")
@@ -483,6 +582,19 @@ func runApplicationWorker(ctx context.Context, addr string) error {
 		m.Methods(http.MethodGet).PathPrefix("/static/").Handler(http.StripPrefix("/static/", http.FileServer(http.FS(staticFS))))
 	}
 
+	// HLS playlists and segments, and WebVTT tracks, aren't in Go's list of
+	// types, and the system's list can have .ts as something else entirely
+	for ext, contentType := range map[string]string{
+		".m3u8": "application/vnd.apple.mpegurl",
+		".ts":   "video/mp2t",
+		".m4s":  "video/iso.segment",
+		".vtt":  "text/vtt",
+	} {
+		if err := mime.AddExtensionType(ext, contentType); err != nil {
+			return fmt.Errorf("runApplicationWorker: %w", err)
+		}
+	}
+
 	m.Methods(http.MethodGet).PathPrefix("/data/").Handler(http.StripPrefix("/data/", http.FileServer(http.Dir(ctxconfig.GetConfig(ctx).ApplicationDataPath))))
 
 	min := minify.New()
@@ -546,6 +658,29 @@ func runApplicationWorker(ctx context.Context, addr string) error {
 	}
 }
 
+func makeMetadataSource(cfg config.Config) (metadatasource.MetadataSource, error) {
+	var c metadatasource.Chain
+
+	for _, e := range []struct {
+		name    string
+		names   []string
+		sources *[]metadatasource.MetadataSource
+	}{
+		{"metadata_channel", cfg.MetadataChannel, &c.Channel},
+		{"metadata_playlist", cfg.MetadataPlaylist, &c.Playlist},
+		{"metadata_video", cfg.MetadataVideo, &c.Video},
+	} {
+		sources, err := metadatasource.Lookup(e.names)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", e.name, err)
+		}
+
+		*e.sources = sources
+	}
+
+	return &c, nil
+}
+
 func registerJobQueueWorkerFunctions(ctx context.Context) error {
 	l := ctxlogger.GetLogger(ctx)
 
@@ -556,6 +691,19 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 		return fmt.Errorf("job queue worker not available in context")
 	}
 
+	meta, err := makeMetadataSource(cfg)
+	if err != nil {
+		return err
+	}
+
+	if len(cfg.AudioProfiles) == 0 {
+		return fmt.Errorf("at least one audio profile is needed")
+	}
+
+	if !models.IsDownloadProfile(cfg.DownloadProfile) {
+		return fmt.Errorf("unrecognised download profile %q; valid options are %s", cfg.DownloadProfile, strings.Join(models.DownloadProfiles, ", "))
+	}
+
 	return w.RegisterAll(map[string]jobqueue.WorkerFunction{
 		queuenames.ChannelUpdateMetadata: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
 			externalID, _, err := jobqueue.ParsePayload(j.Payload)
@@ -563,11 +711,29 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			channelData, err := ytdirect.GetChannel(ctx, externalID)
+			var output, handle string
+
+			// channels added by @handle, /user/, or vanity URL need their ID
+			// looked up first
+			if !ytutil.IsChannelID(externalID) {
+				resolved, err := ytutil.ResolveChannel(ctx, externalID)
+				if err != nil {
+					return "", err
+				}
+
+				output = fmt.Sprintf("resolved %s to %s", externalID, resolved.ID)
+				externalID, handle = resolved.ID, resolved.Handle
+			}
+
+			channelData, err := meta.GetChannel(ctx, externalID)
 			if err != nil {
 				return "", err
 			}
 
+			if channelData.Handle != "" {
+				handle = channelData.Handle
+			}
+
 			if err := ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
 				var channel models.Channel
 				if err := sorm.FindFirstWhere(ctx, tx, &channel, "where external_id = ?", externalID); err != nil {
@@ -578,20 +744,105 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					channel.CreatedAt = time.Now()
 					channel.ExternalID = externalID
 					channel.Title = channelData.Title
+					channel.Handle = handle
 					channel.MetadataUpdatedAt = ptr.Time(time.Now())
 
-					return sorm.CreateRecord(ctx, tx, &channel)
+					if err := sorm.CreateRecord(ctx, tx, &channel); err != nil {
+						return err
+					}
+
+					return ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+						QueueName: queuenames.ChannelUpdateThumbnail,
+						Payload:   externalID,
+					})
 				} else {
+					newHandle := channel.Handle
+					if handle != "" {
+						newHandle = handle
+					}
+
+					if err := recordRevisions(ctx, tx, j, models.RevisionObjectChannel, externalID,
+						fieldChange{"title", channel.Title, channelData.Title},
+						fieldChange{"handle", channel.Handle, newHandle},
+					); err != nil {
+						return err
+					}
+
+					titleChanged := channel.Title != channelData.Title
+
 					channel.Title = channelData.Title
+					channel.Handle = newHandle
 					channel.MetadataUpdatedAt = ptr.Time(time.Now())
 
-					return sorm.SaveRecord(ctx, tx, &channel)
+					if err := sorm.SaveRecord(ctx, tx, &channel); err != nil {
+						return err
+					}
+
+					// the channel is the artist in its videos' audio tags
+					if titleChanged {
+						if err := enqueueRetag(ctx, tx, "v.channel_external_id = ?", externalID); err != nil {
+							return err
+						}
+					}
+
+					if channel.ThumbnailUpdatedAt == nil {
+						return ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queuenames.ChannelUpdateThumbnail,
+							Payload:   externalID,
+						})
+					}
+
+					return nil
 				}
 			}); err != nil {
 				return "", err
 			}
 
-			return "", nil
+			return output, nil
+		},
+		queuenames.ChannelUpdateThumbnail: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			channelData, err := meta.GetChannel(ctx, externalID)
+			if err != nil {
+				return "", err
+			}
+
+			if channelData.AvatarURL == "" {
+				return "", fmt.Errorf("no avatar found on channel page")
+			}
+
+			if err := downloadImage(ctx, channelData.AvatarURL, cfg.DataFile("thumbnails", externalID+".jpg")); err != nil {
+				return "", err
+			}
+
+			output := "downloaded avatar"
+
+			// plenty of channels never set a banner
+			if channelData.BannerURL != "" {
+				if err := downloadImage(ctx, channelData.BannerURL, cfg.DataFile("banners", externalID+".jpg")); err != nil {
+					return output, err
+				}
+
+				output += " and banner"
+			}
+
+			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				var channel models.Channel
+				if err := sorm.FindFirstWhere(ctx, tx, &channel, "where external_id = ?", externalID); err != nil {
+					return err
+				}
+
+				channel.ThumbnailUpdatedAt = ptr.Time(time.Now())
+				if channelData.BannerURL != "" {
+					channel.BannerUpdatedAt = ptr.Time(time.Now())
+				}
+
+				return sorm.SaveRecord(ctx, tx, &channel)
+			})
 		},
 		queuenames.ChannelUpdatePlaylists: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
 			id, _, err := jobqueue.ParsePayload(j.Payload)
@@ -604,7 +855,7 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			channelData, err := ytdirect.GetChannel(ctx, channel.ExternalID)
+			channelData, err := meta.GetChannel(ctx, channel.ExternalID)
 			if err != nil {
 				return "", err
 			}
@@ -628,6 +879,12 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 								return err
 							}
 						} else {
+							if err := recordRevisions(ctx, tx, j, models.RevisionObjectPlaylist, playlist.ExternalID,
+								fieldChange{"title", playlist.Title, channelPlaylist.Title},
+							); err != nil {
+								return err
+							}
+
 							playlist.ExternalID = channelPlaylist.ID
 							playlist.ChannelID = &channel.ID
 							playlist.ChannelExternalID = channel.ExternalID
@@ -654,11 +911,13 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			playlistData, err := ytdirect.GetPlaylist(ctx, externalID)
+			playlistData, err := meta.GetPlaylist(ctx, externalID)
 			if err != nil {
 				return "", err
 			}
 
+			var output string
+
 			if err := ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
 				var channelID *int
 
@@ -677,6 +936,8 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					}
 				}
 
+				var titleChanged bool
+
 				var playlist models.Playlist
 				if err := sorm.FindFirstWhere(ctx, tx, &playlist, "where external_id = ?", externalID); err != nil {
 					if err != sql.ErrNoRows {
@@ -693,26 +954,6 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					if err := sorm.CreateRecord(ctx, tx, &playlist); err != nil {
 						return err
 					}
-
-					for i, videoID := range playlistData.VideoIDs {
-						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-							QueueName: queuenames.VideoUpdateMetadata,
-							Payload:   videoID,
-						}); err != nil {
-							return err
-						}
-
-						if err := sorm.CreateRecord(ctx, tx, &models.PlaylistVideo{
-							CreatedAt:          time.Now(),
-							PlaylistID:         playlist.ID,
-							PlaylistExternalID: playlist.ExternalID,
-							VideoID:            nil,
-							VideoExternalID:    videoID,
-							Position:           i,
-						}); err != nil {
-							return err
-						}
-					}
 				} else {
 					playlist.ExternalID = externalID
 					if channelID != nil {
@@ -722,6 +963,14 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 						playlist.ChannelExternalID = playlistData.ChannelID
 					}
 					if playlistData.Title != "" {
+						titleChanged = playlist.Title != playlistData.Title
+
+						if err := recordRevisions(ctx, tx, j, models.RevisionObjectPlaylist, externalID,
+							fieldChange{"title", playlist.Title, playlistData.Title},
+						); err != nil {
+							return err
+						}
+
 						playlist.Title = playlistData.Title
 					}
 					playlist.MetadataUpdatedAt = ptr.Time(time.Now())
@@ -729,51 +978,49 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					if err := sorm.SaveRecord(ctx, tx, &playlist); err != nil {
 						return err
 					}
+				}
 
-					for i, videoID := range playlistData.VideoIDs {
-						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-							QueueName: queuenames.VideoUpdateMetadata,
-							Payload:   videoID,
-						}); err != nil {
-							return err
-						}
+				for _, videoID := range playlistData.VideoIDs {
+					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+						QueueName: queuenames.VideoUpdateMetadata,
+						Payload:   videoID,
+					}); err != nil {
+						return err
+					}
+				}
 
-						var video models.Video
-						if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", videoID); err != nil && err != sql.ErrNoRows {
-							return err
-						}
+				var items []playlistsync.Item
+				for _, entry := range playlistData.Entries {
+					items = append(items, playlistsync.Item{VideoID: entry.VideoID, SetVideoID: entry.SetVideoID})
+				}
 
-						var playlistVideo models.PlaylistVideo
-						if err := sorm.FindFirstWhere(ctx, tx, &playlistVideo, "where playlist_external_id = ? and video_external_id = ?", playlist.ExternalID, videoID); err != nil {
-							if err != sql.ErrNoRows {
-								return err
-							}
+				changes, err := syncPlaylistVideos(ctx, tx, &playlist, items, playlistData.Truncated)
+				if err != nil {
+					return err
+				}
 
-							playlistVideo.CreatedAt = time.Now()
-							playlistVideo.PlaylistID = playlist.ID
-							playlistVideo.PlaylistExternalID = playlist.ExternalID
-							if video.ID != 0 {
-								playlistVideo.VideoID = &video.ID
-							}
-							playlistVideo.VideoExternalID = videoID
-							playlistVideo.Position = i
+				output = playlistsync.Summarize(changes)
+				if playlistData.Truncated {
+					output += fmt.Sprintf("\nonly the first %d entries were fetched, so none were removed", len(items))
+				}
 
-							if err := sorm.CreateRecord(ctx, tx, &playlistVideo); err != nil {
-								return err
-							}
-						} else {
-							playlistVideo.PlaylistID = playlist.ID
-							playlistVideo.PlaylistExternalID = playlist.ExternalID
-							if video.ID != 0 {
-								playlistVideo.VideoID = &video.ID
-							}
-							playlistVideo.VideoExternalID = videoID
-							playlistVideo.Position = i
+				// the playlist is the album in its videos' audio tags, and their
+				// positions are the track numbers
+				rearranged := playlistsync.Rearranged(changes)
+				if titleChanged || rearranged {
+					if err := enqueueRetag(ctx, tx, "v.external_id in (select video_external_id from playlist_videos where playlist_external_id = ?)", externalID); err != nil {
+						return err
+					}
+				}
 
-							if err := sorm.SaveRecord(ctx, tx, &playlistVideo); err != nil {
-								return err
-							}
-						}
+				// the artwork might be a collage of the first few videos, so
+				// any change to what's in the playlist could change it
+				if rearranged || playlist.ThumbnailUpdatedAt == nil {
+					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+						QueueName: queuenames.PlaylistUpdateThumbnail,
+						Payload:   externalID,
+					}); err != nil {
+						return err
 					}
 				}
 
@@ -782,7 +1029,78 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			return "", nil
+			return output, nil
+		},
+		queuenames.PlaylistUpdateThumbnail: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			playlistData, err := meta.GetPlaylist(ctx, externalID)
+			if err != nil {
+				return "", err
+			}
+
+			thumbnailFile := cfg.DataFile("thumbnails", externalID+".jpg")
+
+			var output string
+
+			// most playlists' artwork is just a frame of their first video, which
+			// a collage beats; anything else was picked on purpose, so use it
+			if playlistData.ThumbnailURL != "" && !ytutil.IsVideoThumbnailURL(playlistData.ThumbnailURL) {
+				if err := downloadImage(ctx, playlistData.ThumbnailURL, thumbnailFile); err != nil {
+					return "", err
+				}
+
+				output = "downloaded playlist artwork"
+			} else {
+				var videos []models.VideoInPlaylist
+				if err := sorm.FindWhere(ctx, ctxdb.GetDB(ctx), &videos, "where playlist_external_id = ? and playlist_video_removed_at is null and video_thumbnail_updated_at is not null order by playlist_video_position asc limit 4", externalID); err != nil {
+					return "", err
+				}
+
+				var images []image.Image
+				for _, video := range videos {
+					img, err := collage.Load(cfg.DataFile("thumbnails", video.VideoExternalID+".jpg"))
+					if err != nil {
+						return "", err
+					}
+
+					images = append(images, img)
+				}
+
+				switch {
+				case len(images) > 0:
+					if err := collage.Save(partial.Name(thumbnailFile), collage.Make(images, 480)); err != nil {
+						return "", err
+					}
+					if err := commitImageFile(partial.Name(thumbnailFile)); err != nil {
+						return "", err
+					}
+
+					output = fmt.Sprintf("made collage from %d video thumbnails", len(images))
+				case playlistData.ThumbnailURL != "":
+					if err := downloadImage(ctx, playlistData.ThumbnailURL, thumbnailFile); err != nil {
+						return "", err
+					}
+
+					output = "no video thumbnails yet; downloaded playlist artwork"
+				default:
+					return "", fmt.Errorf("no artwork or video thumbnails to use yet")
+				}
+			}
+
+			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				var playlist models.Playlist
+				if err := sorm.FindFirstWhere(ctx, tx, &playlist, "where external_id = ?", externalID); err != nil {
+					return err
+				}
+
+				playlist.ThumbnailUpdatedAt = ptr.Time(time.Now())
+
+				return sorm.SaveRecord(ctx, tx, &playlist)
+			})
 		},
 		queuenames.VideoUpdateMetadata: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
 			externalID, _, err := jobqueue.ParsePayload(j.Payload)
@@ -790,8 +1108,13 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			videoData, err := ytdirect.GetVideo(ctx, externalID)
+			videoData, err := meta.GetVideo(ctx, externalID)
 			if err != nil {
+				var unavailableErr *ytutil.UnavailableError
+				if errors.As(err, &unavailableErr) {
+					return unavailableErr.Error(), markVideoUnavailable(ctx, externalID, unavailableErr)
+				}
+
 				return "", err
 			}
 
@@ -804,6 +1127,8 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				uploadDate = &t
 			}
 
+			var output string
+
 			if err := ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
 				var channelID *int
 				if err := tx.QueryRowContext(ctx, "select id from channels where external_id = ?", videoData.ChannelID).Scan(&channelID); err != nil {
@@ -833,19 +1158,45 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					video.Description = videoData.Description
 					video.PublishDate = publishDate
 					video.UploadDate = uploadDate
+					video.Availability = string(videoData.Availability)
+					video.AvailabilityReason = videoData.AvailabilityReason
 					video.MetadataUpdatedAt = ptr.Time(time.Now())
 
 					if err := sorm.CreateRecord(ctx, tx, &video); err != nil {
 						return err
 					}
 
-					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-						QueueName: queuenames.VideoDownload,
-						Payload:   externalID,
-					}); err != nil {
-						return err
+					if videoData.Availability == ytutil.Available {
+						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queuenames.VideoDownload,
+							Payload:   externalID,
+						}); err != nil {
+							return err
+						}
 					}
 				} else {
+					wasAvailable := video.Availability == string(ytutil.Available)
+
+					// tags only carry the day, so ignore time-of-day changes
+					day := func(t *time.Time) string {
+						if t == nil {
+							return ""
+						}
+						return t.Format("2006-01-02")
+					}
+
+					tagsChanged := video.Title != videoData.Title ||
+						video.ChannelExternalID != videoData.ChannelID ||
+						day(video.PublishDate) != day(publishDate) ||
+						day(video.UploadDate) != day(uploadDate)
+
+					if err := recordRevisions(ctx, tx, j, models.RevisionObjectVideo, externalID,
+						fieldChange{"title", video.Title, videoData.Title},
+						fieldChange{"description", video.Description, videoData.Description},
+					); err != nil {
+						return err
+					}
+
 					video.ExternalID = externalID
 					video.ChannelID = channelID
 					video.ChannelExternalID = videoData.ChannelID
@@ -853,11 +1204,52 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					video.Description = videoData.Description
 					video.PublishDate = publishDate
 					video.UploadDate = uploadDate
+					video.Availability = string(videoData.Availability)
+					video.AvailabilityReason = videoData.AvailabilityReason
 					video.MetadataUpdatedAt = ptr.Time(time.Now())
 
 					if err := sorm.SaveRecord(ctx, tx, &video); err != nil {
 						return err
 					}
+
+					if !wasAvailable && videoData.Availability == ytutil.Available && video.DownloadedAt == nil {
+						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queuenames.VideoDownload,
+							Payload:   externalID,
+						}); err != nil {
+							return err
+						}
+					}
+
+					if tagsChanged {
+						if err := enqueueRetag(ctx, tx, "v.external_id = ?", externalID); err != nil {
+							return err
+						}
+					}
+				}
+
+				chapters, source := videoData.Chapters, models.VideoChapterSourceYouTube
+				if len(chapters) == 0 {
+					chapters, source = ytutil.ParseChapters(videoData.Description), models.VideoChapterSourceDescription
+				}
+
+				changed, err := syncVideoChapters(ctx, tx, &video, chapters, source)
+				if err != nil {
+					return err
+				}
+
+				if changed {
+					output = fmt.Sprintf("%d chapters from %s", len(chapters), source)
+
+					// files split from the old chapters don't line up any more
+					if cfg.SplitChapters && len(chapters) > 0 && video.AudioExtractedAt != nil {
+						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queuenames.VideoSplitChapters,
+							Payload:   externalID,
+						}); err != nil {
+							return err
+						}
+					}
 				}
 
 				return nil
@@ -865,7 +1257,7 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			return "", nil
+			return output, nil
 		},
 		queuenames.VideoDownload: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
 			externalID, _, err := jobqueue.ParsePayload(j.Payload)
@@ -882,22 +1274,96 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", nil
 			}
 
-			if _, err := os.Stat(cfg.DataFile("videos", externalID+".mp4")); err != nil {
-				// Create progress callback for real-time updates
-				progressCallback := func(progress int) {
-					if err := w.UpdateProgress(ctx, j, progress); err != nil {
-						ctxlogger.GetLogger(ctx).WithError(err).Warn("failed to update progress")
-					}
-				}
-				
-				// Use the new progress-enabled download function
-				if err := ytdl.DownloadVideoWithProgress(ctx, externalID, cfg.DataFile("videos", externalID+".mp4"), progressCallback); err != nil {
-					return "", err
+			profile, err := videoDownloadProfile(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			// Create progress callback for real-time updates
+			progressCallback := func(progress int) {
+				if err := w.UpdateProgress(ctx, j, progress); err != nil {
+					ctxlogger.GetLogger(ctx).WithError(err).Warn("failed to update progress")
 				}
 			}
 
-			return "", ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
-				var video models.Video
+			if profile == models.DownloadProfileAudio {
+				if video.AudioExtractedAt != nil {
+					return "audio already downloaded", nil
+				}
+
+				dir := cfg.DataFile("audio", audioprofile.Original)
+
+				file, ok := ytdl.FindDownloadedAudio(dir, externalID)
+				if !ok || !mediaFileReady(ctx, file) {
+					partialFile, err := ytdl.DownloadAudioWithProgress(ctx, externalID, dir, progressCallback)
+					if err != nil {
+						var unavailableErr *ytutil.UnavailableError
+						if errors.As(err, &unavailableErr) {
+							return unavailableErr.Error(), markVideoUnavailable(ctx, externalID, unavailableErr)
+						}
+
+						return "", err
+					}
+
+					if s, err := commitMediaFile(ctx, partialFile, 0); err != nil {
+						return s, err
+					}
+
+					// a file left under the same name in another format
+					// would be found first next time
+					if ok && file != partial.Final(partialFile) {
+						if err := os.Remove(file); err != nil {
+							return "", err
+						}
+					}
+
+					file = partial.Final(partialFile)
+				}
+
+				// there's no video to take a thumbnail from or to transcode, so
+				// downloaded_at stays empty and the audio profiles are encoded
+				// from the original audio instead
+				return "downloaded audio only", ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+					var video models.Video
+					if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
+						return err
+					}
+
+					if err := saveVideoAudio(ctx, tx, &video, audioprofile.Original, audioprofile.Original+"/"+filepath.Base(file)); err != nil {
+						return err
+					}
+
+					for _, queueName := range []string{queuenames.VideoUpdateThumbnail, queuenames.VideoExtractAudio, queuenames.VideoDownloadSubtitles} {
+						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queueName,
+							Payload:   externalID,
+						}); err != nil {
+							return err
+						}
+					}
+
+					return nil
+				})
+			}
+
+			if videoFile := cfg.DataFile("videos", externalID+".mp4"); !mediaFileReady(ctx, videoFile) {
+				// Use the new progress-enabled download function
+				if err := ytdl.DownloadVideoWithProgress(ctx, externalID, partial.Name(videoFile), progressCallback); err != nil {
+					var unavailableErr *ytutil.UnavailableError
+					if errors.As(err, &unavailableErr) {
+						return unavailableErr.Error(), markVideoUnavailable(ctx, externalID, unavailableErr)
+					}
+
+					return "", err
+				}
+
+				if s, err := commitMediaFile(ctx, partial.Name(videoFile), 0); err != nil {
+					return s, err
+				}
+			}
+
+			return "", ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				var video models.Video
 				if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
 					return err
 				}
@@ -908,24 +1374,34 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					return err
 				}
 
-				if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-					QueueName: queuenames.VideoUpdateThumbnail,
-					Payload:   externalID,
-				}); err != nil {
+				for _, queueName := range []string{queuenames.VideoUpdateThumbnail, queuenames.VideoStoryboard} {
+					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+						QueueName: queueName,
+						Payload:   externalID,
+					}); err != nil {
+						return err
+					}
+				}
+
+				if err := enqueueMissingRenditions(ctx, tx, externalID); err != nil {
+					return err
+				}
+
+				if err := enqueueProbe(ctx, tx, externalID, "videos", externalID+".mp4"); err != nil {
 					return err
 				}
 
-				for _, size := range []string{} {
+				for _, p := range cfg.AudioProfiles {
 					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-						QueueName: queuenames.VideoTranscode,
-						Payload:   externalID + "?size=" + size,
+						QueueName: queuenames.VideoExtractAudio,
+						Payload:   externalID + "?profile=" + p.Name,
 					}); err != nil {
 						return err
 					}
 				}
 
 				if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
-					QueueName: queuenames.VideoExtractAudio,
+					QueueName: queuenames.VideoDownloadSubtitles,
 					Payload:   externalID,
 				}); err != nil {
 					return err
@@ -945,13 +1421,26 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			if video.DownloadedAt == nil {
-				return "", fmt.Errorf("video has not been downloaded")
-			}
+			var output string
 
-			output, err := ffmpeg.MakeThumbnail(ctx, cfg.DataFile("videos", externalID+".mp4"), cfg.DataFile("thumbnails", externalID+".jpg"))
-			if err != nil {
-				return output, err
+			if video.DownloadedAt != nil {
+				thumbnailFile := cfg.DataFile("thumbnails", externalID+".jpg")
+
+				s, err := ffmpeg.MakeThumbnail(ctx, cfg.DataFile("videos", externalID+".mp4"), partial.Name(thumbnailFile))
+				if err != nil {
+					return s, err
+				}
+				output = s
+
+				if err := commitImageFile(partial.Name(thumbnailFile)); err != nil {
+					return output, err
+				}
+			} else {
+				// audio-only downloads have no frames of their own
+				if err := downloadImage(ctx, ytutil.VideoThumbnailURL(externalID), cfg.DataFile("thumbnails", externalID+".jpg")); err != nil {
+					return "", err
+				}
+				output = "downloaded thumbnail from youtube"
 			}
 
 			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
@@ -966,7 +1455,37 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					return err
 				}
 
-				return nil
+				// playlists that start with this video might have a collage
+				// that's waiting on its thumbnail
+				rows, err := tx.QueryContext(ctx, "select distinct playlist_external_id from playlist_videos where video_external_id = ? and removed_at is null and position < 4", externalID)
+				if err != nil {
+					return err
+				}
+				defer rows.Close()
+
+				var playlistIDs []string
+				for rows.Next() {
+					var playlistID string
+					if err := rows.Scan(&playlistID); err != nil {
+						return err
+					}
+					playlistIDs = append(playlistIDs, playlistID)
+				}
+				if err := rows.Err(); err != nil {
+					return err
+				}
+
+				for _, playlistID := range playlistIDs {
+					if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+						QueueName: queuenames.PlaylistUpdateThumbnail,
+						Payload:   playlistID,
+					}); err != nil {
+						return err
+					}
+				}
+
+				// the thumbnail is the audio's cover art
+				return enqueueRetag(ctx, tx, "v.external_id = ?", externalID)
 			})
 		},
 		queuenames.VideoTranscode: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
@@ -975,11 +1494,24 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", err
 			}
 
-			size := params.Get("size")
-			switch size {
-			case "360", "720":
-			default:
-				return "", fmt.Errorf("transcode size should be 360 or 720")
+			// jobs queued before renditions were configurable name them
+			// by size
+			name := params.Get("rendition")
+			if name == "" {
+				name = params.Get("size")
+			}
+
+			// without a rendition, it's every configured one the video
+			// doesn't have yet, which is how tools/jobs.sql asks for them
+			if name == "" {
+				return "queued missing renditions", ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+					return enqueueMissingRenditions(ctx, tx, externalID)
+				})
+			}
+
+			r, ok := cfg.Renditions.Find(name)
+			if !ok {
+				return "", fmt.Errorf("rendition %q is not configured", name)
 			}
 
 			var video models.Video
@@ -993,7 +1525,7 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 
 			var output string
 
-			if _, err := os.Stat(cfg.DataFile("videos", externalID+"_"+size+".mp4")); err != nil {
+			if outputFile := cfg.DataFile("videos", r.File(externalID)); !mediaFileReady(ctx, outputFile) {
 				// Create progress callback for real-time updates
 				progressCallback := func(progress int) {
 					if err := w.UpdateProgress(ctx, j, progress); err != nil {
@@ -1001,13 +1533,24 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					}
 				}
 				
+				source, s, err := ffmpeg.Probe(ctx, cfg.DataFile("videos", externalID+".mp4"))
+				if err != nil {
+					return s, err
+				}
+
 				// Use the new progress-enabled transcode function
-				s, err := ffmpeg.TranscodeWithProgress(ctx, cfg.DataFile("videos", externalID+".mp4"), size+":-2", cfg.DataFile("videos", externalID+"_"+size+".mp4"), progressCallback)
+				s, err = ffmpeg.TranscodeWithProgress(ctx, cfg.DataFile("videos", externalID+".mp4"), partial.Name(outputFile), r.FFmpegArgs(), progressCallback)
 				if err != nil {
 					return s, err
 				}
 
 				output = s
+
+				s, err = commitMediaFile(ctx, partial.Name(outputFile), source.Duration)
+				output += s
+				if err != nil {
+					return output, err
+				}
 			}
 
 			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
@@ -1016,23 +1559,117 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 					return err
 				}
 
-				switch size {
-				case "360":
-					video.Transcoded360At = ptr.Time(time.Now())
-				case "720":
-					video.Transcoded720At = ptr.Time(time.Now())
-				default:
-					return fmt.Errorf("transcode size should be 360 or 720")
+				if err := saveVideoRendition(ctx, tx, &video, r.Name, r.File(externalID)); err != nil {
+					return err
 				}
 
-				if err := sorm.SaveRecord(ctx, tx, &video); err != nil {
-					return err
+				return ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+					QueueName: queuenames.VideoPackageHLS,
+					Payload:   externalID,
+				})
+			})
+		},
+		queuenames.VideoPackageHLS: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			var renditions []models.VideoRendition
+			if err := sorm.FindWhere(ctx, ctxdb.GetDB(ctx), &renditions, "where video_external_id = ? order by name asc", externalID); err != nil {
+				return "", err
+			}
+
+			var output strings.Builder
+			var variants []hls.Variant
+
+			for _, rendition := range renditions {
+				dir := cfg.DataFile("hls", filepath.Join(externalID, rendition.Name))
+
+				mediaFile, s, err := renditionMediaFile(ctx, &video, rendition.File)
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
 				}
 
-				return nil
+				// players only take hevc over hls in fragmented mp4 segments,
+				// which come with an init.mp4; anything packaged the other way
+				// is packaged again
+				fragmented := mediaFile.VideoCodec == "hevc"
+				_, initErr := os.Stat(filepath.Join(dir, "init.mp4"))
+
+				// renditions are packaged next to where they'll go, so a
+				// failed attempt never leaves a partial playlist behind
+				if _, err := os.Stat(filepath.Join(dir, "index.m3u8")); err != nil || fragmented != (initErr == nil) {
+					tempDir := dir + ".packaging"
+
+					if err := os.RemoveAll(tempDir); err != nil {
+						return output.String(), err
+					}
+					if err := os.MkdirAll(tempDir, 0755); err != nil {
+						return output.String(), err
+					}
+
+					s, err := ffmpeg.PackageHLS(ctx, cfg.DataFile("videos", rendition.File), tempDir, fragmented)
+					output.WriteString(s)
+					if err != nil {
+						return output.String(), err
+					}
+
+					if err := os.RemoveAll(dir); err != nil {
+						return output.String(), err
+					}
+					if err := os.Rename(tempDir, dir); err != nil {
+						return output.String(), err
+					}
+				}
+
+				v, err := hls.MeasureVariant(cfg.DataFile("hls", externalID), rendition.Name+"/index.m3u8")
+				if err != nil {
+					return output.String(), err
+				}
+
+				v.Resolution = mediaFile.Resolution()
+
+				// players can do without the codecs, so one we can't name
+				// shouldn't stop the rest being served
+				if v.Codecs, err = hls.Codecs(mediaFile.VideoCodec, mediaFile.VideoProfile, mediaFile.VideoLevel, mediaFile.AudioCodec, mediaFile.AudioProfile); err != nil {
+					fmt.Fprintf(&output, "%s: leaving out codecs: %s\n", rendition.Name, err)
+				}
+
+				variants = append(variants, *v)
+			}
+
+			if len(variants) == 0 {
+				return output.String(), fmt.Errorf("video has not been transcoded")
+			}
+
+			var master bytes.Buffer
+			if err := hls.WriteMaster(&master, variants); err != nil {
+				return output.String(), err
+			}
+
+			masterFile := cfg.DataFile("hls", filepath.Join(externalID, "master.m3u8"))
+			if err := os.WriteFile(masterFile+".tmp", master.Bytes(), 0644); err != nil {
+				return output.String(), err
+			}
+			if err := os.Rename(masterFile+".tmp", masterFile); err != nil {
+				return output.String(), err
+			}
+
+			fmt.Fprintf(&output, "packaged %d renditions", len(variants))
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "update videos set hls_packaged_at = ? where id = ?", time.Now(), video.ID)
+				return err
 			})
 		},
-		queuenames.VideoExtractAudio: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+		queuenames.VideoStoryboard: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
 			externalID, _, err := jobqueue.ParsePayload(j.Payload)
 			if err != nil {
 				return "", err
@@ -1047,32 +1684,1620 @@ func registerJobQueueWorkerFunctions(ctx context.Context) error {
 				return "", fmt.Errorf("video has not been downloaded")
 			}
 
-			var output string
+			videoFile := cfg.DataFile("videos", externalID+".mp4")
+
+			info, output, err := ffmpeg.Probe(ctx, videoFile)
+			if err != nil {
+				return output, err
+			}
+
+			// like HLS packaging, the storyboard is made next to where it'll
+			// go and swapped in when it's done
+			dir := cfg.DataFile("storyboards", externalID)
+			tempDir := dir + ".making"
+			framesDir := filepath.Join(tempDir, "frames")
+
+			if err := os.RemoveAll(tempDir); err != nil {
+				return output, err
+			}
+			if err := os.MkdirAll(framesDir, 0755); err != nil {
+				return output, err
+			}
+
+			s, err := ffmpeg.ExtractFrames(ctx, videoFile, storyboard.Interval, storyboard.TileWidth, framesDir)
+			output += s
+			if err != nil {
+				return output, err
+			}
 
-			if _, err := os.Stat(cfg.DataFile("audio", externalID+".mp3")); err != nil {
-				s, err := ffmpeg.ExtractAudio(ctx, cfg.DataFile("videos", externalID+".mp4"), cfg.DataFile("audio", externalID+".mp3"))
+			frameFiles, err := filepath.Glob(filepath.Join(framesDir, "*.jpg"))
+			if err != nil {
+				return output, err
+			}
+			if len(frameFiles) == 0 {
+				return output, fmt.Errorf("no frames were taken from the video")
+			}
+			sort.Strings(frameFiles)
+
+			var frames []image.Image
+			for _, f := range frameFiles {
+				img, err := collage.Load(f)
 				if err != nil {
-					return s, err
+					return output, err
 				}
-				output = s
+
+				frames = append(frames, img)
+			}
+
+			sheets := storyboard.Default.Tile(frames)
+			for i, sheet := range sheets {
+				if err := collage.Save(filepath.Join(tempDir, fmt.Sprintf("%03d.jpg", i)), sheet); err != nil {
+					return output, err
+				}
+			}
+
+			var vtt bytes.Buffer
+			if err := storyboard.Default.WriteVTT(&vtt, len(frames), frames[0].Bounds().Size(), storyboard.Interval, info.Duration, func(sheet int) string {
+				return fmt.Sprintf("%03d.jpg", sheet)
+			}); err != nil {
+				return output, err
+			}
+
+			if err := os.WriteFile(filepath.Join(tempDir, "storyboard.vtt"), vtt.Bytes(), 0644); err != nil {
+				return output, err
+			}
+
+			if err := os.RemoveAll(framesDir); err != nil {
+				return output, err
+			}
+			if err := os.RemoveAll(dir); err != nil {
+				return output, err
+			}
+			if err := os.Rename(tempDir, dir); err != nil {
+				return output, err
 			}
 
+			output += fmt.Sprintf("%d frames in %d sheets", len(frames), len(sheets))
+
 			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "update videos set storyboard_made_at = ? where id = ?", time.Now(), video.ID)
+				return err
+			})
+		},
+		queuenames.VideoExtractAudio: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, params, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			// jobs without a profile make every one of them
+			profiles := cfg.AudioProfiles
+			if name := params.Get("profile"); name != "" {
+				p, ok := cfg.AudioProfiles.Find(name)
+				if !ok {
+					return "", fmt.Errorf("audio profile %q is not configured", name)
+				}
+				profiles = config.AudioProfileList{p}
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			sourceFile, err := videoAudioSource(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			source, s, err := ffmpeg.Probe(ctx, sourceFile)
+			if err != nil {
+				return s, err
+			}
+
+			var output strings.Builder
+
+			for _, p := range profiles {
+				if err := os.MkdirAll(cfg.DataFile("audio", p.Name), 0755); err != nil {
+					return output.String(), err
+				}
+
+				if audioFile := cfg.DataFile("audio", p.File(externalID)); !mediaFileReady(ctx, audioFile) {
+					s, err := ffmpeg.ExtractAudio(ctx, sourceFile, partial.Name(audioFile), p.FFmpegArgs())
+					output.WriteString(s)
+					if err != nil {
+						return output.String(), err
+					}
+
+					s, err = commitMediaFile(ctx, partial.Name(audioFile), source.Duration)
+					output.WriteString(s)
+					if err != nil {
+						return output.String(), err
+					}
+				}
+			}
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
 				var video models.Video
 				if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
 					return err
 				}
 
-				video.AudioExtractedAt = ptr.Time(time.Now())
+				for _, p := range profiles {
+					if err := saveVideoAudio(ctx, tx, &video, p.Name, p.File(externalID)); err != nil {
+						return err
+					}
 
-				if err := sorm.SaveRecord(ctx, tx, &video); err != nil {
-					return err
+					if p.Name != cfg.AudioProfiles[0].Name {
+						continue
+					}
+
+					// the lists only know about the default profile
+					video.AudioExtractedAt = ptr.Time(time.Now())
+
+					if err := sorm.SaveRecord(ctx, tx, &video); err != nil {
+						return err
+					}
+
+					if err := enqueueSplitChapters(ctx, tx, externalID); err != nil {
+						return err
+					}
+
+					for _, queueName := range []string{queuenames.VideoAnalyzeLoudness, queuenames.VideoWaveform} {
+						if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+							QueueName: queueName,
+							Payload:   externalID,
+						}); err != nil {
+							return err
+						}
+					}
 				}
 
-				return nil
+				return enqueueRetag(ctx, tx, "v.external_id = ?", externalID)
 			})
 		},
-	})
+		queuenames.VideoDownloadSubtitles: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			subtitles, err := ytdl.DownloadSubtitles(ctx, externalID, filepath.Join(cfg.ApplicationDataPath, "subtitles"), cfg.SubtitleLanguages)
+			if err != nil {
+				var unavailableErr *ytutil.UnavailableError
+				if errors.As(err, &unavailableErr) {
+					return unavailableErr.Error(), markVideoUnavailable(ctx, externalID, unavailableErr)
+				}
+
+				return "", err
+			}
+
+			output := "no subtitles available"
+			if len(subtitles) > 0 {
+				var languages []string
+				for _, s := range subtitles {
+					languages = append(languages, s.Language)
+				}
+
+				output = "downloaded " + strings.Join(languages, ", ")
+			}
+
+			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				var video models.Video
+				if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
+					return err
+				}
+
+				// tracks from earlier runs stay put; their files are still there
+				// even if the configured languages have changed since
+				for _, s := range subtitles {
+					var subtitle models.VideoSubtitle
+					if err := sorm.FindFirstWhere(ctx, tx, &subtitle, "where video_external_id = ? and language = ?", externalID, s.Language); err != nil {
+						if err != sql.ErrNoRows {
+							return err
+						}
+
+						subtitle.CreatedAt = time.Now()
+						subtitle.VideoID = video.ID
+						subtitle.VideoExternalID = externalID
+						subtitle.Language = s.Language
+					}
+
+					subtitle.Name = s.Name
+					subtitle.Automatic = s.Automatic
+
+					if subtitle.ID == 0 {
+						if err := sorm.CreateRecord(ctx, tx, &subtitle); err != nil {
+							return err
+						}
+					} else {
+						if err := sorm.SaveRecord(ctx, tx, &subtitle); err != nil {
+							return err
+						}
+					}
+
+					if err := indexSubtitleCues(ctx, tx, &subtitle, s.File); err != nil {
+						return err
+					}
+				}
+
+				video.SubtitlesUpdatedAt = ptr.Time(time.Now())
+
+				return sorm.SaveRecord(ctx, tx, &video)
+			})
+		},
+		queuenames.VideoRetag: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			tags, err := videoTags(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			var coverFile string
+			if video.ThumbnailUpdatedAt != nil {
+				coverFile = cfg.DataFile("thumbnails", externalID+".jpg")
+			}
+
+			coverFor := func(file string) string {
+				if !audioprofile.SupportsCoverArt(filepath.Ext(file)) {
+					return ""
+				}
+				return coverFile
+			}
+
+			var audio []models.VideoAudio
+			if err := sorm.FindWhere(ctx, ctxdb.GetDB(ctx), &audio, "where video_external_id = ? and profile != ?", externalID, audioprofile.Original); err != nil {
+				return "", err
+			}
+
+			var chapters []models.VideoChapter
+			if err := sorm.FindWhere(ctx, ctxdb.GetDB(ctx), &chapters, "where video_external_id = ? and audio_split_at is not null order by position asc", externalID); err != nil {
+				return "", err
+			}
+
+			loudness := video.Loudness()
+
+			var output strings.Builder
+
+			// the tagged copy is written under a partial name and only
+			// replaces the file once it's known to be as long as it was
+			tag := func(file string, tags ffmpeg.Tags) error {
+				info, s, err := ffmpeg.Probe(ctx, file)
+				if err != nil {
+					output.WriteString(s)
+					return err
+				}
+
+				s, err = ffmpeg.WriteTags(ctx, file, coverFor(file), tags, partial.Name(file))
+				output.WriteString(s)
+				if err != nil {
+					return err
+				}
+
+				s, err = commitMediaFile(ctx, partial.Name(file), info.Duration)
+				output.WriteString(s)
+
+				return err
+			}
+
+			for _, a := range audio {
+				tags := tags
+				// normalized audio was measured before it was normalized, so
+				// the gain doesn't apply to it
+				if a.Profile != audioprofile.Normalized {
+					tags.TrackGain = loudness
+				}
+
+				if err := tag(cfg.DataFile("audio", a.File), tags); err != nil {
+					return output.String(), err
+				}
+			}
+
+			// each chapter is a track on an album named after the video
+			for _, c := range chapters {
+				if err := tag(cfg.DataFile("chapters", c.AudioFile), ffmpeg.Tags{
+					Title:     c.Title,
+					Artist:    tags.Artist,
+					Album:     video.Title,
+					Track:     c.Position + 1,
+					Date:      tags.Date,
+					AlbumGain: loudness,
+				}); err != nil {
+					return output.String(), err
+				}
+			}
+
+			fmt.Fprintf(&output, "tagged %d files", len(audio)+len(chapters))
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "update videos set audio_tagged_at = ? where id = ?", time.Now(), video.ID)
+				return err
+			})
+		},
+		queuenames.VideoAnalyzeLoudness: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			sourceFile, err := videoAudioSource(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			loudness, s, err := ffmpeg.MeasureLoudness(ctx, sourceFile)
+			if err != nil {
+				return s, err
+			}
+
+			var output strings.Builder
+
+			// the normalized copy is made like the default profile, from the
+			// same source that was measured
+			normalized := cfg.AudioProfiles[0]
+			normalized.Name = audioprofile.Normalized
+
+			if cfg.NormalizeAudio {
+				if err := w.UpdateProgress(ctx, j, 50); err != nil {
+					ctxlogger.GetLogger(ctx).WithError(err).Warn("failed to update progress")
+				}
+
+				if err := os.MkdirAll(cfg.DataFile("audio", normalized.Name), 0755); err != nil {
+					return output.String(), err
+				}
+
+				source, s, err := ffmpeg.Probe(ctx, sourceFile)
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+				if source.SampleRate == 0 {
+					return output.String(), fmt.Errorf("couldn't find the sample rate of %s", filepath.Base(sourceFile))
+				}
+
+				audioFile := cfg.DataFile("audio", normalized.File(externalID))
+
+				s, err = ffmpeg.Normalize(ctx, sourceFile, partial.Name(audioFile), *loudness, normalized.SampleRate(source.SampleRate), normalized.FFmpegArgs())
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+
+				s, err = commitMediaFile(ctx, partial.Name(audioFile), source.Duration)
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+			}
+
+			fmt.Fprintf(&output, "%.2f LUFS, %.2f dBTP, %.2f LU", loudness.Integrated, loudness.TruePeak, loudness.Range)
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				var video models.Video
+				if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
+					return err
+				}
+
+				video.LoudnessIntegrated = &loudness.Integrated
+				video.LoudnessTruePeak = &loudness.TruePeak
+				video.LoudnessRange = &loudness.Range
+				video.LoudnessMeasuredAt = ptr.Time(time.Now())
+
+				if err := sorm.SaveRecord(ctx, tx, &video); err != nil {
+					return err
+				}
+
+				if cfg.NormalizeAudio {
+					if err := saveVideoAudio(ctx, tx, &video, normalized.Name, normalized.File(externalID)); err != nil {
+						return err
+					}
+				}
+
+				return enqueueRetag(ctx, tx, "v.external_id = ?", externalID)
+			})
+		},
+		queuenames.VideoWaveform: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			sourceFile, err := videoAudioSource(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			var peaks *waveform.Waveform
+
+			output, err := ffmpeg.DecodeAudio(ctx, sourceFile, waveform.SampleRate, func(r io.Reader) error {
+				p, err := waveform.Read(r, waveform.SampleRate, waveform.SamplesPerPixel)
+				if err != nil {
+					return err
+				}
+
+				peaks = p
+
+				return nil
+			})
+			if err != nil {
+				return output, err
+			}
+
+			b, err := json.Marshal(peaks)
+			if err != nil {
+				return output, err
+			}
+
+			if err := os.MkdirAll(filepath.Join(cfg.ApplicationDataPath, "waveforms"), 0755); err != nil {
+				return output, err
+			}
+
+			waveformFile := cfg.DataFile("waveforms", externalID+".json")
+			if err := os.WriteFile(partial.Name(waveformFile), b, 0644); err != nil {
+				return output, err
+			}
+			if _, err := partial.Commit(partial.Name(waveformFile)); err != nil {
+				return output, err
+			}
+
+			output += fmt.Sprintf("%d peaks", peaks.Length)
+
+			return output, ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				_, err := tx.ExecContext(ctx, "update videos set waveform_updated_at = ? where id = ?", time.Now(), video.ID)
+				return err
+			})
+		},
+		queuenames.VideoProbe: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, params, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			// directory and file narrow the job down to some of the video's
+			// files; without them, all of them are probed
+			directory, file := params.Get("directory"), params.Get("file")
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			files, err := videoMediaFiles(ctx, ctxdb.GetDB(ctx), &video)
+			if err != nil {
+				return "", err
+			}
+
+			var output strings.Builder
+			var probed []models.MediaFile
+
+			for _, f := range files {
+				if (directory != "" && f.Directory != directory) || (file != "" && f.File != file) {
+					continue
+				}
+
+				if _, err := os.Stat(cfg.DataFile(f.Directory, f.File)); err != nil {
+					if os.IsNotExist(err) {
+						fmt.Fprintf(&output, "%s is missing\n", f.Path())
+						continue
+					}
+
+					return output.String(), err
+				}
+
+				s, err := probeMediaFile(ctx, &f)
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+
+				probed = append(probed, f)
+			}
+
+			fmt.Fprintf(&output, "probed %d files", len(probed))
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				return saveMediaFiles(ctx, tx, &video, directory, file, probed)
+			})
+		},
+		queuenames.LibraryVerify: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			verification, issues, err := verifyLibrary(ctx, func(progress int) {
+				if err := w.UpdateProgress(ctx, j, progress); err != nil {
+					ctxlogger.GetLogger(ctx).WithError(err).Warn("failed to update progress")
+				}
+			})
+			if err != nil {
+				return "", err
+			}
+
+			return fmt.Sprintf("checked %d files and found %d problems", verification.FilesChecked, len(issues)), nil
+		},
+		queuenames.VideoSplitChapters: func(ctx context.Context, w *jobqueue.Worker, j *jobqueue.Job) (string, error) {
+			externalID, _, err := jobqueue.ParsePayload(j.Payload)
+			if err != nil {
+				return "", err
+			}
+
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &video, "where external_id = ?", externalID); err != nil {
+				return "", err
+			}
+
+			if video.AudioExtractedAt == nil {
+				return "", fmt.Errorf("audio has not been extracted")
+			}
+
+			var chapters []models.VideoChapter
+			if err := sorm.FindWhere(ctx, ctxdb.GetDB(ctx), &chapters, "where video_external_id = ? order by position asc", externalID); err != nil {
+				return "", err
+			}
+
+			if len(chapters) == 0 {
+				return "no chapters to split", nil
+			}
+
+			var audio models.VideoAudio
+			if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &audio, "where video_external_id = ? and profile = ?", externalID, cfg.AudioProfiles[0].Name); err != nil {
+				if err == sql.ErrNoRows {
+					return "", fmt.Errorf("there's no %s audio to split", cfg.AudioProfiles[0].Name)
+				}
+
+				return "", err
+			}
+
+			if err := os.MkdirAll(filepath.Join(cfg.ApplicationDataPath, "chapters"), 0755); err != nil {
+				return "", err
+			}
+
+			source, s, err := ffmpeg.Probe(ctx, cfg.DataFile("audio", audio.File))
+			if err != nil {
+				return s, err
+			}
+
+			// chapters that start past the end would come out empty and fail
+			// the check every time, so they're left unsplit
+			all := chapters
+			chapters = models.FitChapters(all, source.Duration)
+			if len(chapters) == 0 {
+				return fmt.Sprintf("none of the %d chapters start before the audio ends at %s", len(all), source.Duration), nil
+			}
+
+			var output strings.Builder
+
+			for i := range chapters {
+				c := &chapters[i]
+
+				start := time.Duration(c.StartMS) * time.Millisecond
+
+				// the last chapter runs to the end of the audio
+				var end time.Duration
+				expected := source.Duration - start
+				if c.EndMS != nil {
+					end = time.Duration(*c.EndMS) * time.Millisecond
+					expected = end - start
+				}
+
+				// chapters are copied rather than encoded, so they keep the
+				// format of the audio they came from
+				c.AudioFile = c.SplitFile(audio.Extension())
+				chapterFile := cfg.DataFile("chapters", c.AudioFile)
+
+				s, err := ffmpeg.CutAudio(ctx, cfg.DataFile("audio", audio.File), start, end, partial.Name(chapterFile))
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+
+				s, err = commitMediaFile(ctx, partial.Name(chapterFile), expected)
+				output.WriteString(s)
+				if err != nil {
+					return output.String(), err
+				}
+
+				if err := w.UpdateProgress(ctx, j, (i+1)*100/len(chapters)); err != nil {
+					ctxlogger.GetLogger(ctx).WithError(err).Warn("failed to update progress")
+				}
+			}
+
+			// files from an earlier split that had more chapters, or was cut
+			// from audio in another format, aren't part of this set
+			current := make(map[string]bool)
+			for _, c := range chapters {
+				current[c.AudioFile] = true
+			}
+
+			existing, err := filepath.Glob(cfg.DataFile("chapters", externalID+".*"))
+			if err != nil {
+				return output.String(), err
+			}
+
+			var removed int
+			for _, file := range existing {
+				if current[filepath.Base(file)] {
+					continue
+				}
+
+				if err := os.Remove(file); err != nil && !os.IsNotExist(err) {
+					return output.String(), err
+				}
+
+				removed++
+			}
+
+			fmt.Fprintf(&output, "split into %d tracks", len(chapters))
+			if skipped := len(all) - len(chapters); skipped > 0 {
+				fmt.Fprintf(&output, ", skipped %d that start after the audio ends", skipped)
+			}
+			if removed > 0 {
+				fmt.Fprintf(&output, ", removed %d old files", removed)
+			}
+
+			return output.String(), ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+				for _, c := range chapters {
+					if _, err := tx.ExecContext(ctx, "update video_chapters set audio_split_at = ?, audio_file = ? where id = ?", time.Now(), c.AudioFile, c.ID); err != nil {
+						return err
+					}
+				}
+
+				// the ones that didn't fit are the last few, and anything they had
+				// from an earlier split was removed above
+				for _, c := range all[len(chapters):] {
+					if _, err := tx.ExecContext(ctx, "update video_chapters set audio_split_at = null, audio_file = '' where id = ?", c.ID); err != nil {
+						return err
+					}
+				}
+
+				if err := enqueueProbe(ctx, tx, externalID, "chapters", ""); err != nil {
+					return err
+				}
+
+				return enqueueRetag(ctx, tx, "v.external_id = ?", externalID)
+			})
+		},
+	})
+}
+
+// mediaFileReady reports whether file was made before and is still whole
+// enough for ffprobe to read. Files that aren't are made again, and the new
+// one replaces the old when it's committed.
+func mediaFileReady(ctx context.Context, file string) bool {
+	if _, err := os.Stat(file); err != nil {
+		return false
+	}
+
+	info, _, err := ffmpeg.Probe(ctx, file)
+	if err != nil {
+		return false
+	}
+
+	return info.Check(0) == nil
+}
+
+// commitMediaFile checks what a step wrote under a partial name and moves it
+// into place if it's whole. If it isn't, it's removed so that the next
+// attempt starts over. expected is how long the file should be, or zero if
+// that isn't known.
+func commitMediaFile(ctx context.Context, partialFile string, expected time.Duration) (string, error) {
+	info, output, err := ffmpeg.Probe(ctx, partialFile)
+	if err == nil {
+		err = info.Check(expected)
+	}
+	if err != nil {
+		if err := os.Remove(partialFile); err != nil && !os.IsNotExist(err) {
+			return output, fmt.Errorf("commitMediaFile: %w", err)
+		}
+
+		return output, fmt.Errorf("commitMediaFile: %s: %w", filepath.Base(partialFile), err)
+	}
+
+	if _, err := partial.Commit(partialFile); err != nil {
+		return output, fmt.Errorf("commitMediaFile: %w", err)
+	}
+
+	return output, nil
+}
+
+// commitImageFile is commitMediaFile for images, which ffprobe can't tell
+// are cut short. The partial file is moved into place if it decodes, and
+// removed if it doesn't.
+func commitImageFile(partialFile string) error {
+	fd, err := os.Open(partialFile)
+	if err != nil {
+		return fmt.Errorf("commitImageFile: %w", err)
+	}
+
+	_, _, err = image.Decode(fd)
+	fd.Close()
+	if err != nil {
+		if err := os.Remove(partialFile); err != nil && !os.IsNotExist(err) {
+			return fmt.Errorf("commitImageFile: %w", err)
+		}
+
+		return fmt.Errorf("commitImageFile: %s: %w", filepath.Base(partialFile), err)
+	}
+
+	if _, err := partial.Commit(partialFile); err != nil {
+		return fmt.Errorf("commitImageFile: %w", err)
+	}
+
+	return nil
+}
+
+// saveVideoAudio records a video's audio file for a profile, replacing
+// whatever was made for that profile before.
+func saveVideoAudio(ctx context.Context, tx *sql.Tx, video *models.Video, profile, file string) error {
+	var audio models.VideoAudio
+	if err := sorm.FindFirstWhere(ctx, tx, &audio, "where video_external_id = ? and profile = ?", video.ExternalID, profile); err != nil {
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("saveVideoAudio: %w", err)
+		}
+
+		audio.CreatedAt = time.Now()
+		audio.VideoID = video.ID
+		audio.VideoExternalID = video.ExternalID
+		audio.Profile = profile
+	}
+
+	audio.File = file
+	audio.ExtractedAt = time.Now()
+
+	if err := enqueueProbe(ctx, tx, video.ExternalID, "audio", file); err != nil {
+		return fmt.Errorf("saveVideoAudio: %w", err)
+	}
+
+	if audio.ID == 0 {
+		if err := sorm.CreateRecord(ctx, tx, &audio); err != nil {
+			return fmt.Errorf("saveVideoAudio: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := sorm.SaveRecord(ctx, tx, &audio); err != nil {
+		return fmt.Errorf("saveVideoAudio: %w", err)
+	}
+
+	return nil
+}
+
+// saveVideoRendition records a video's transcoded copy for a rendition,
+// replacing whatever was made for that rendition before.
+func saveVideoRendition(ctx context.Context, tx *sql.Tx, video *models.Video, name, file string) error {
+	var rendition models.VideoRendition
+	if err := sorm.FindFirstWhere(ctx, tx, &rendition, "where video_external_id = ? and name = ?", video.ExternalID, name); err != nil {
+		if err != sql.ErrNoRows {
+			return fmt.Errorf("saveVideoRendition: %w", err)
+		}
+
+		rendition.CreatedAt = time.Now()
+		rendition.VideoID = video.ID
+		rendition.VideoExternalID = video.ExternalID
+		rendition.Name = name
+	}
+
+	rendition.File = file
+	rendition.TranscodedAt = time.Now()
+
+	if err := enqueueProbe(ctx, tx, video.ExternalID, "videos", file); err != nil {
+		return fmt.Errorf("saveVideoRendition: %w", err)
+	}
+
+	if rendition.ID == 0 {
+		if err := sorm.CreateRecord(ctx, tx, &rendition); err != nil {
+			return fmt.Errorf("saveVideoRendition: %w", err)
+		}
+
+		return nil
+	}
+
+	if err := sorm.SaveRecord(ctx, tx, &rendition); err != nil {
+		return fmt.Errorf("saveVideoRendition: %w", err)
+	}
+
+	return nil
+}
+
+// enqueueMissingRenditions queues a transcode for each configured rendition
+// that a video doesn't have yet.
+func enqueueMissingRenditions(ctx context.Context, tx *sql.Tx, externalID string) error {
+	var existing []models.VideoRendition
+	if err := sorm.FindWhere(ctx, tx, &existing, "where video_external_id = ?", externalID); err != nil {
+		return fmt.Errorf("enqueueMissingRenditions: %w", err)
+	}
+
+	have := make(map[string]bool)
+	for _, r := range existing {
+		have[r.Name] = true
+	}
+
+	for _, r := range cfg.Renditions {
+		if have[r.Name] {
+			continue
+		}
+
+		if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+			QueueName: queuenames.VideoTranscode,
+			Payload:   externalID + "?rendition=" + r.Name,
+		}); err != nil {
+			return fmt.Errorf("enqueueMissingRenditions: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// enqueueProbe queues an ffprobe run over a video's files. An empty
+// directory or file matches everything.
+func enqueueProbe(ctx context.Context, tx *sql.Tx, externalID, directory, file string) error {
+	params := url.Values{}
+	if directory != "" {
+		params.Set("directory", directory)
+	}
+	if file != "" {
+		params.Set("file", file)
+	}
+
+	payload := externalID
+	if len(params) > 0 {
+		payload += "?" + params.Encode()
+	}
+
+	if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+		QueueName: queuenames.VideoProbe,
+		Payload:   payload,
+	}); err != nil {
+		return fmt.Errorf("enqueueProbe: %w", err)
+	}
+
+	return nil
+}
+
+// videoMediaFiles lists the files that have been made for a video: the
+// download, its renditions, its audio, and the tracks split from it. Only
+// where each file is gets filled in.
+func videoMediaFiles(ctx context.Context, db *sql.DB, video *models.Video) ([]models.MediaFile, error) {
+	var files []models.MediaFile
+
+	add := func(directory, file string) {
+		files = append(files, models.MediaFile{
+			VideoID:         video.ID,
+			VideoExternalID: video.ExternalID,
+			Directory:       directory,
+			File:            file,
+		})
+	}
+
+	if video.DownloadedAt != nil {
+		add("videos", video.ExternalID+".mp4")
+	}
+
+	var renditions []models.VideoRendition
+	if err := sorm.FindWhere(ctx, db, &renditions, "where video_external_id = ? order by name asc", video.ExternalID); err != nil {
+		return nil, fmt.Errorf("videoMediaFiles: %w", err)
+	}
+	for _, r := range renditions {
+		add("videos", r.File)
+	}
+
+	var audio []models.VideoAudio
+	if err := sorm.FindWhere(ctx, db, &audio, "where video_external_id = ? order by profile asc", video.ExternalID); err != nil {
+		return nil, fmt.Errorf("videoMediaFiles: %w", err)
+	}
+	for _, a := range audio {
+		add("audio", a.File)
+	}
+
+	var chapters []models.VideoChapter
+	if err := sorm.FindWhere(ctx, db, &chapters, "where video_external_id = ? and audio_split_at is not null order by position asc", video.ExternalID); err != nil {
+		return nil, fmt.Errorf("videoMediaFiles: %w", err)
+	}
+	for _, c := range chapters {
+		add("chapters", c.AudioFile)
+	}
+
+	return files, nil
+}
+
+// probeMediaFile fills in what ffprobe finds in f's file.
+func probeMediaFile(ctx context.Context, f *models.MediaFile) (string, error) {
+	info, s, err := ffmpeg.Probe(ctx, cfg.DataFile(f.Directory, f.File))
+	if err != nil {
+		return s, fmt.Errorf("%s: %w", f.Path(), err)
+	}
+
+	f.Format = info.Format
+	f.Size = info.Size
+	f.DurationMS = int(info.Duration / time.Millisecond)
+	f.BitRate = info.BitRate
+	f.VideoCodec = info.VideoCodec
+	f.VideoProfile = info.VideoProfile
+	f.VideoLevel = info.VideoLevel
+	f.Width = info.Width
+	f.Height = info.Height
+	f.AudioCodec = info.AudioCodec
+	f.AudioProfile = info.AudioProfile
+	f.SampleRate = info.SampleRate
+	f.Channels = info.Channels
+
+	return s, nil
+}
+
+// renditionMediaFile gets what was probed from one of a video's renditions,
+// probing it now if it never was or was probed before profiles and levels
+// were recorded.
+func renditionMediaFile(ctx context.Context, video *models.Video, file string) (*models.MediaFile, string, error) {
+	var f models.MediaFile
+	if err := sorm.FindFirstWhere(ctx, ctxdb.GetDB(ctx), &f, "where directory = ? and file = ?", "videos", file); err != nil {
+		if err != sql.ErrNoRows {
+			return nil, "", fmt.Errorf("renditionMediaFile: %w", err)
+		}
+
+		f = models.MediaFile{
+			VideoID:         video.ID,
+			VideoExternalID: video.ExternalID,
+			Directory:       "videos",
+			File:            file,
+		}
+	} else if f.VideoCodec == "" || f.VideoProfile != "" {
+		return &f, "", nil
+	}
+
+	s, err := probeMediaFile(ctx, &f)
+	if err != nil {
+		return nil, s, fmt.Errorf("renditionMediaFile: %w", err)
+	}
+
+	if err := ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		return saveMediaFiles(ctx, tx, video, "videos", file, []models.MediaFile{f})
+	}); err != nil {
+		return nil, s, fmt.Errorf("renditionMediaFile: %w", err)
+	}
+
+	return &f, s, nil
+}
+
+// saveMediaFiles records what was probed from a video's files. Records for
+// files in the same directory and file scope that weren't probed, because
+// they're gone or no longer belong to the video, are removed.
+func saveMediaFiles(ctx context.Context, tx *sql.Tx, video *models.Video, directory, file string, probed []models.MediaFile) error {
+	var existing []models.MediaFile
+	if err := sorm.FindWhere(ctx, tx, &existing, "where video_external_id = ?", video.ExternalID); err != nil {
+		return fmt.Errorf("saveMediaFiles: %w", err)
+	}
+
+	byPath := make(map[string]models.MediaFile)
+	for _, f := range existing {
+		if (directory != "" && f.Directory != directory) || (file != "" && f.File != file) {
+			continue
+		}
+
+		byPath[f.Path()] = f
+	}
+
+	for _, f := range probed {
+		f.ProbedAt = time.Now()
+
+		if e, ok := byPath[f.Path()]; ok {
+			delete(byPath, f.Path())
+
+			f.ID = e.ID
+			f.CreatedAt = e.CreatedAt
+
+			if err := sorm.SaveRecord(ctx, tx, &f); err != nil {
+				return fmt.Errorf("saveMediaFiles: %w", err)
+			}
+
+			continue
+		}
+
+		// a file can move between videos when it's named after something
+		// else, so clear out whatever was recorded for it before
+		if _, err := tx.ExecContext(ctx, "delete from media_files where directory = ? and file = ?", f.Directory, f.File); err != nil {
+			return fmt.Errorf("saveMediaFiles: %w", err)
+		}
+
+		f.CreatedAt = time.Now()
+
+		if err := sorm.CreateRecord(ctx, tx, &f); err != nil {
+			return fmt.Errorf("saveMediaFiles: %w", err)
+		}
+	}
+
+	for _, f := range byPath {
+		if _, err := tx.ExecContext(ctx, "delete from media_files where id = ?", f.ID); err != nil {
+			return fmt.Errorf("saveMediaFiles: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// libraryDirectories are the data directories that library_verify checks.
+// They hold the files made for videos; the others hold artwork and data
+// that's made again whenever it's missing.
+var libraryDirectories = []string{"videos", "audio", "chapters"}
+
+// verifyLibrary checks that every file made for a video is still there and
+// that ffprobe can read it, and looks for files that nothing refers to. What
+// it finds replaces what the last run found. progress is called with how far
+// through the probing it is.
+func verifyLibrary(ctx context.Context, progress func(int)) (*models.LibraryVerification, []models.LibraryIssue, error) {
+	verification := models.LibraryVerification{CreatedAt: time.Now()}
+
+	expected, err := libraryExpectedFiles(ctx, ctxdb.GetDB(ctx))
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifyLibrary: %w", err)
+	}
+
+	result, err := libraryscan.Compare(cfg.ApplicationDataPath, libraryDirectories, expected)
+	if err != nil {
+		return nil, nil, fmt.Errorf("verifyLibrary: %w", err)
+	}
+
+	var issues []models.LibraryIssue
+
+	add := func(kind string, e libraryscan.Expected, detail string) {
+		issues = append(issues, models.LibraryIssue{
+			CreatedAt:       time.Now(),
+			Kind:            kind,
+			Directory:       e.Directory,
+			File:            e.File,
+			VideoExternalID: e.VideoExternalID,
+			Detail:          detail,
+			QueueName:       e.QueueName,
+			Payload:         e.Payload,
+		})
+	}
+
+	for _, e := range result.Missing {
+		add(models.LibraryIssueMissing, e, "the file isn't there")
+	}
+
+	for i, e := range result.Present {
+		file := cfg.DataFile(e.Directory, e.File)
+
+		info, s, err := ffmpeg.Probe(ctx, file)
+		if err == nil {
+			err = info.Check(0)
+		}
+		if err != nil {
+			// ffprobe's last words are usually a better explanation than
+			// its exit status
+			detail := err.Error()
+			if lines := strings.Split(strings.TrimSpace(s), "\n"); lines[len(lines)-1] != "" {
+				detail = strings.TrimPrefix(lines[len(lines)-1], file+": ")
+			}
+
+			add(models.LibraryIssueCorrupt, e, detail)
+		}
+
+		if progress != nil {
+			progress((i + 1) * 100 / len(result.Present))
+		}
+	}
+
+	for _, path := range result.Orphaned {
+		directory, file, _ := strings.Cut(path, "/")
+
+		// yt-dlp keeps its own .part and .ytdl files next to the partial name
+		detail := "nothing refers to the file"
+		if partial.Is(file) || partial.Is(strings.TrimSuffix(file, filepath.Ext(file))) {
+			detail = "the file is from a step that didn't finish, or that's still running"
+		}
+
+		add(models.LibraryIssueOrphaned, libraryscan.Expected{Directory: directory, File: file}, detail)
+	}
+
+	verification.FilesChecked = len(result.Present)
+	verification.FinishedAt = time.Now()
+
+	if err := ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		if err := sorm.CreateRecord(ctx, tx, &verification); err != nil {
+			return err
+		}
+
+		if _, err := tx.ExecContext(ctx, "delete from library_issues"); err != nil {
+			return err
+		}
+
+		for i := range issues {
+			issues[i].VerificationID = verification.ID
+
+			if err := sorm.CreateRecord(ctx, tx, &issues[i]); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}); err != nil {
+		return nil, nil, fmt.Errorf("verifyLibrary: %w", err)
+	}
+
+	return &verification, issues, nil
+}
+
+// libraryExpectedFiles lists the files that have been made for every video,
+// like videoMediaFiles does for one, along with the job that makes each of
+// them again.
+func libraryExpectedFiles(ctx context.Context, db *sql.DB) ([]libraryscan.Expected, error) {
+	var files []libraryscan.Expected
+
+	add := func(directory, file, externalID, queueName, payload string) {
+		files = append(files, libraryscan.Expected{
+			Directory:       directory,
+			File:            file,
+			VideoExternalID: externalID,
+			QueueName:       queueName,
+			Payload:         payload,
+		})
+	}
+
+	var videos []models.Video
+	if err := sorm.FindWhere(ctx, db, &videos, "where downloaded_at is not null order by external_id asc"); err != nil {
+		return nil, fmt.Errorf("libraryExpectedFiles: %w", err)
+	}
+	for _, v := range videos {
+		add("videos", v.ExternalID+".mp4", v.ExternalID, queuenames.VideoDownload, v.ExternalID)
+	}
+
+	var renditions []models.VideoRendition
+	if err := sorm.FindWhere(ctx, db, &renditions, "order by video_external_id asc, name asc"); err != nil {
+		return nil, fmt.Errorf("libraryExpectedFiles: %w", err)
+	}
+	for _, r := range renditions {
+		add("videos", r.File, r.VideoExternalID, queuenames.VideoTranscode, r.VideoExternalID+"?rendition="+r.Name)
+	}
+
+	var audio []models.VideoAudio
+	if err := sorm.FindWhere(ctx, db, &audio, "order by video_external_id asc, profile asc"); err != nil {
+		return nil, fmt.Errorf("libraryExpectedFiles: %w", err)
+	}
+	for _, a := range audio {
+		switch a.Profile {
+		case audioprofile.Original:
+			add("audio", a.File, a.VideoExternalID, queuenames.VideoDownload, a.VideoExternalID)
+		case audioprofile.Normalized:
+			add("audio", a.File, a.VideoExternalID, queuenames.VideoAnalyzeLoudness, a.VideoExternalID)
+		default:
+			add("audio", a.File, a.VideoExternalID, queuenames.VideoExtractAudio, a.VideoExternalID+"?profile="+a.Profile)
+		}
+	}
+
+	var chapters []models.VideoChapter
+	if err := sorm.FindWhere(ctx, db, &chapters, "where audio_split_at is not null order by video_external_id asc, position asc"); err != nil {
+		return nil, fmt.Errorf("libraryExpectedFiles: %w", err)
+	}
+	for _, c := range chapters {
+		add("chapters", c.AudioFile, c.VideoExternalID, queuenames.VideoSplitChapters, c.VideoExternalID)
+	}
+
+	return files, nil
+}
+
+// videoAudioSource finds the file to encode a video's audio from: the video
+// itself, or the original audio when only that was downloaded.
+func videoAudioSource(ctx context.Context, db *sql.DB, video *models.Video) (string, error) {
+	if video.DownloadedAt != nil {
+		return cfg.DataFile("videos", video.ExternalID+".mp4"), nil
+	}
+
+	var original models.VideoAudio
+	if err := sorm.FindFirstWhere(ctx, db, &original, "where video_external_id = ? and profile = ?", video.ExternalID, audioprofile.Original); err != nil {
+		if err == sql.ErrNoRows {
+			return "", fmt.Errorf("video has not been downloaded")
+		}
+
+		return "", fmt.Errorf("videoAudioSource: %w", err)
+	}
+
+	return cfg.DataFile("audio", original.File), nil
+}
+
+// videoTags works out what to tag a video's audio with. A video in more than
+// one playlist gets the album and track number of the one it was added to
+// first.
+func videoTags(ctx context.Context, db *sql.DB, video *models.Video) (ffmpeg.Tags, error) {
+	tags := ffmpeg.Tags{Title: video.Title}
+
+	if err := db.QueryRowContext(ctx, "select title from channels where external_id = ?", video.ChannelExternalID).Scan(&tags.Artist); err != nil && err != sql.ErrNoRows {
+		return tags, fmt.Errorf("videoTags: %w", err)
+	}
+
+	var position int
+	if err := db.QueryRowContext(ctx, "select p.title, pv.position from playlist_videos pv join playlists p on p.id = pv.playlist_id or p.external_id = pv.playlist_external_id where pv.video_external_id = ? and pv.removed_at is null and p.title != '' order by pv.created_at asc, pv.id asc limit 1", video.ExternalID).Scan(&tags.Album, &position); err != nil {
+		if err != sql.ErrNoRows {
+			return tags, fmt.Errorf("videoTags: %w", err)
+		}
+	} else {
+		tags.Track = position + 1
+	}
+
+	switch {
+	case video.PublishDate != nil:
+		tags.Date = video.PublishDate.Format("2006-01-02")
+	case video.UploadDate != nil:
+		tags.Date = video.UploadDate.Format("2006-01-02")
+	}
+
+	return tags, nil
+}
+
+// enqueueRetag queues a retag for each video matching condition, which is
+// about videos v, that has audio files to tag.
+func enqueueRetag(ctx context.Context, tx *sql.Tx, condition string, args ...interface{}) error {
+	rows, err := tx.QueryContext(ctx, "select v.external_id from videos v where exists (select 1 from video_audio a where a.video_external_id = v.external_id and a.profile != ?) and "+condition, append([]interface{}{audioprofile.Original}, args...)...)
+	if err != nil {
+		return fmt.Errorf("enqueueRetag: %w", err)
+	}
+	defer rows.Close()
+
+	var ids []string
+	for rows.Next() {
+		var id string
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("enqueueRetag: %w", err)
+		}
+		ids = append(ids, id)
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("enqueueRetag: %w", err)
+	}
+
+	for _, id := range ids {
+		if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+			QueueName: queuenames.VideoRetag,
+			Payload:   id,
+		}); err != nil {
+			return fmt.Errorf("enqueueRetag: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// enqueueSplitChapters queues a split for a video whose audio has just
+// arrived, if splitting is turned on and there's anything to split.
+func enqueueSplitChapters(ctx context.Context, tx *sql.Tx, externalID string) error {
+	if !cfg.SplitChapters {
+		return nil
+	}
+
+	var chapters int
+	if err := tx.QueryRowContext(ctx, "select count(*) from video_chapters where video_external_id = ?", externalID).Scan(&chapters); err != nil {
+		return fmt.Errorf("enqueueSplitChapters: %w", err)
+	}
+
+	if chapters == 0 {
+		return nil
+	}
+
+	if err := ctxjobqueue.Add(ctx, tx, &jobqueue.Job{
+		QueueName: queuenames.VideoSplitChapters,
+		Payload:   externalID,
+	}); err != nil {
+		return fmt.Errorf("enqueueSplitChapters: %w", err)
+	}
+
+	return nil
+}
+
+// videoDownloadProfile works out how a video should be downloaded. Playlists
+// are the most specific choice, then the channel, then the library default.
+// When playlists disagree the full video wins, since the audio can still be
+// extracted from it.
+func videoDownloadProfile(ctx context.Context, db *sql.DB, video *models.Video) (string, error) {
+	rows, err := db.QueryContext(ctx, "select distinct p.download_profile from playlist_videos pv join playlists p on p.id = pv.playlist_id or p.external_id = pv.playlist_external_id where pv.video_external_id = ? and pv.removed_at is null and p.download_profile != ''", video.ExternalID)
+	if err != nil {
+		return "", fmt.Errorf("videoDownloadProfile: %w", err)
+	}
+	defer rows.Close()
+
+	var profile string
+	for rows.Next() {
+		var p string
+		if err := rows.Scan(&p); err != nil {
+			return "", fmt.Errorf("videoDownloadProfile: %w", err)
+		}
+		if profile == "" || p == models.DownloadProfileVideo {
+			profile = p
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return "", fmt.Errorf("videoDownloadProfile: %w", err)
+	}
+	if profile != "" {
+		return profile, nil
+	}
+
+	if err := db.QueryRowContext(ctx, "select download_profile from channels where external_id = ?", video.ChannelExternalID).Scan(&profile); err != nil && err != sql.ErrNoRows {
+		return "", fmt.Errorf("videoDownloadProfile: %w", err)
+	}
+	if profile != "" {
+		return profile, nil
+	}
+
+	return cfg.DownloadProfile, nil
+}
+
+// syncVideoChapters replaces a video's chapters when they've changed, and
+// reports whether they did. Each chapter ends where the next one starts.
+func syncVideoChapters(ctx context.Context, tx *sql.Tx, video *models.Video, chapters []ytutil.Chapter, source string) (bool, error) {
+	var existing []models.VideoChapter
+	if err := sorm.FindWhere(ctx, tx, &existing, "where video_external_id = ? order by position asc", video.ExternalID); err != nil {
+		return false, fmt.Errorf("syncVideoChapters: %w", err)
+	}
+
+	same := len(existing) == len(chapters)
+	for i := 0; same && i < len(chapters); i++ {
+		same = existing[i].Title == chapters[i].Title && existing[i].StartMS == int(chapters[i].Start/time.Millisecond) && existing[i].Source == source
+	}
+	if same {
+		return false, nil
+	}
+
+	if _, err := tx.ExecContext(ctx, "delete from video_chapters where video_external_id = ?", video.ExternalID); err != nil {
+		return false, fmt.Errorf("syncVideoChapters: %w", err)
+	}
+
+	for i, c := range chapters {
+		var endMS *int
+		if i+1 < len(chapters) {
+			endMS = ptr.Int(int(chapters[i+1].Start / time.Millisecond))
+		}
+
+		if err := sorm.CreateRecord(ctx, tx, &models.VideoChapter{
+			CreatedAt:       time.Now(),
+			VideoID:         video.ID,
+			VideoExternalID: video.ExternalID,
+			Position:        i,
+			Title:           c.Title,
+			StartMS:         int(c.Start / time.Millisecond),
+			EndMS:           endMS,
+			Source:          source,
+		}); err != nil {
+			return false, fmt.Errorf("syncVideoChapters: %w", err)
+		}
+	}
+
+	return true, nil
+}
+
+// indexSubtitleCues replaces the searchable cues for a caption track with
+// what's in its file.
+func indexSubtitleCues(ctx context.Context, tx *sql.Tx, subtitle *models.VideoSubtitle, file string) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("indexSubtitleCues: %w", err)
+	}
+	defer f.Close()
+
+	cues, err := webvtt.Parse(f)
+	if err != nil {
+		return fmt.Errorf("indexSubtitleCues: %s: %w", file, err)
+	}
+
+	if subtitle.Automatic {
+		cues = webvtt.Collapse(cues)
+	}
+
+	if _, err := tx.ExecContext(ctx, "delete from video_subtitle_cues where video_subtitle_id = ?", subtitle.ID); err != nil {
+		return fmt.Errorf("indexSubtitleCues: %w", err)
+	}
+
+	for _, c := range cues {
+		if err := sorm.CreateRecord(ctx, tx, &models.VideoSubtitleCue{
+			VideoSubtitleID: subtitle.ID,
+			VideoExternalID: subtitle.VideoExternalID,
+			Language:        subtitle.Language,
+			StartMS:         int(c.Start / time.Millisecond),
+			EndMS:           int(c.End / time.Millisecond),
+			Text:            strings.ReplaceAll(c.Text, "\n", " "),
+		}); err != nil {
+			return fmt.Errorf("indexSubtitleCues: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// downloadImage saves the image at url to path, creating the directory if
+// it's the first file of its kind. It's written under a partial name and only
+// moved into place once it decodes, so a download that's cut short never
+// replaces a good image.
+func downloadImage(ctx context.Context, url, path string) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+
+	res, err := ctxhttpclient.GetHTTPClient(ctx).Do(req)
+	if err != nil {
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return fmt.Errorf("downloadImage: status code: %d", res.StatusCode)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+
+	fd, err := os.Create(partial.Name(path))
+	if err != nil {
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+	defer fd.Close()
+
+	if _, err := io.Copy(fd, res.Body); err != nil {
+		os.Remove(partial.Name(path))
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+
+	if err := fd.Close(); err != nil {
+		os.Remove(partial.Name(path))
+		return fmt.Errorf("downloadImage: %w", err)
+	}
+
+	return commitImageFile(partial.Name(path))
+}
+
+type fieldChange struct {
+	field    string
+	oldValue string
+	newValue string
+}
+
+// recordRevisions saves the old value of every field that's about to be
+// overwritten with something different, so a refresh never loses text. Fields
+// being filled in for the first time aren't worth a revision.
+func recordRevisions(ctx context.Context, tx *sql.Tx, j *jobqueue.Job, objectType, externalID string, changes ...fieldChange) error {
+	for _, change := range changes {
+		if change.oldValue == change.newValue || change.oldValue == "" {
+			continue
+		}
+
+		if err := sorm.CreateRecord(ctx, tx, &models.Revision{
+			CreatedAt:        time.Now(),
+			JobID:            &j.ID,
+			ObjectType:       objectType,
+			ObjectExternalID: externalID,
+			Field:            change.field,
+			OldValue:         change.oldValue,
+			NewValue:         change.newValue,
+		}); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// markVideoUnavailable records that YouTube won't serve a video, creating a
+// placeholder row if we've never seen it so that playlist entries pointing at
+// it have something to show.
+func markVideoUnavailable(ctx context.Context, externalID string, e *ytutil.UnavailableError) error {
+	return ctxdb.UsingTx(ctx, nil, func(ctx context.Context, tx *sql.Tx) error {
+		var video models.Video
+		if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", externalID); err != nil {
+			if err != sql.ErrNoRows {
+				return err
+			}
+
+			video.CreatedAt = time.Now()
+			video.ExternalID = externalID
+			video.Availability = string(e.Availability)
+			video.AvailabilityReason = e.Reason
+			video.MetadataUpdatedAt = ptr.Time(time.Now())
+
+			return sorm.CreateRecord(ctx, tx, &video)
+		}
+
+		video.Availability = string(e.Availability)
+		video.AvailabilityReason = e.Reason
+		video.MetadataUpdatedAt = ptr.Time(time.Now())
+
+		return sorm.SaveRecord(ctx, tx, &video)
+	})
+}
+
+// syncPlaylistVideos brings a playlist's entries in line with the list of
+// entries YouTube gave us. Entries for videos that have gone are marked as
+// removed rather than deleted, so the videos stay reachable from the
+// playlist. If partial is set, items might be only the start of the playlist
+// and nothing is removed. It returns what changed.
+func syncPlaylistVideos(ctx context.Context, tx *sql.Tx, playlist *models.Playlist, items []playlistsync.Item, partial bool) ([]playlistsync.Change, error) {
+	var playlistVideos []models.PlaylistVideo
+	if err := sorm.FindWhere(ctx, tx, &playlistVideos, "where playlist_external_id = ? order by position asc, id asc", playlist.ExternalID); err != nil {
+		return nil, err
+	}
+
+	var entries []playlistsync.Entry
+	byID := make(map[int]*models.PlaylistVideo)
+	for i := range playlistVideos {
+		pv := &playlistVideos[i]
+		byID[pv.ID] = pv
+		entries = append(entries, playlistsync.Entry{
+			ID:         pv.ID,
+			VideoID:    pv.VideoExternalID,
+			SetVideoID: pv.SetVideoID,
+			Position:   pv.Position,
+			Removed:    pv.RemovedAt != nil,
+		})
+	}
+
+	diff := playlistsync.Diff
+	if partial {
+		diff = playlistsync.DiffPartial
+	}
+
+	changes := diff(entries, items)
+
+	for _, change := range changes {
+		switch change.Kind {
+		case playlistsync.Added:
+			var video models.Video
+			if err := sorm.FindFirstWhere(ctx, tx, &video, "where external_id = ?", change.VideoID); err != nil && err != sql.ErrNoRows {
+				return nil, err
+			}
+
+			playlistVideo := models.PlaylistVideo{
+				CreatedAt:          time.Now(),
+				PlaylistID:         playlist.ID,
+				PlaylistExternalID: playlist.ExternalID,
+				VideoExternalID:    change.VideoID,
+				Position:           change.To,
+				SetVideoID:         change.SetVideoID,
+			}
+			if video.ID != 0 {
+				playlistVideo.VideoID = &video.ID
+			}
+
+			if err := sorm.CreateRecord(ctx, tx, &playlistVideo); err != nil {
+				return nil, err
+			}
+		case playlistsync.Removed:
+			pv := byID[change.EntryID]
+			pv.RemovedAt = ptr.Time(time.Now())
+
+			if err := sorm.SaveRecord(ctx, tx, pv); err != nil {
+				return nil, err
+			}
+		case playlistsync.Restored, playlistsync.Moved, playlistsync.Shifted:
+			pv := byID[change.EntryID]
+			pv.PlaylistID = playlist.ID
+			pv.Position = change.To
+			pv.RemovedAt = nil
+			if change.SetVideoID != "" {
+				pv.SetVideoID = change.SetVideoID
+			}
+
+			if err := sorm.SaveRecord(ctx, tx, pv); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return changes, nil
 }
 
 func runJobQueueWorker(ctx context.Context) error {
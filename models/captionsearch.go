@@ -0,0 +1,34 @@
+package models
+
+import (
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	CaptionSearchTable *sqlbuilderutil.Table
+)
+
+func init() {
+	CaptionSearchTable = sqlbuilderutil.MustMakeTable(CaptionSearch{})
+}
+
+type CaptionSearch struct {
+	VideoID         int `sql:",table:caption_search"`
+	VideoExternalID string
+	VideoTitle      string
+	ChannelTitle    string
+	CueID           int
+	CueLanguage     string
+	CueStartMS      int `sql:"cue_start_ms"`
+	CueEndMS        int `sql:"cue_end_ms"`
+	CueText         string
+}
+
+// CueStartSeconds is where to seek the video to for this cue.
+func (c CaptionSearch) CueStartSeconds() int {
+	return c.CueStartMS / 1000
+}
+
+func (c CaptionSearch) CueTimestamp() string {
+	return formatTimestamp(c.CueStartMS)
+}
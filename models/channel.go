@@ -19,9 +19,14 @@ type Channel struct {
 	CreatedAt  time.Time
 	ExternalID string
 	Title      string
+	Handle     string
 
 	MetadataUpdatedAt  *time.Time
 	ThumbnailUpdatedAt *time.Time
 	PlaylistsUpdatedAt *time.Time
 	VideosUpdatedAt    *time.Time
+	BannerUpdatedAt    *time.Time
+
+	// DownloadProfile overrides the library default when it isn't empty.
+	DownloadProfile string
 }
@@ -21,8 +21,10 @@ type ChannelSearch struct {
 	ChannelCreatedAt          time.Time
 	ChannelExternalID         string
 	ChannelTitle              string
+	ChannelHandle             string
 	ChannelMetadataUpdatedAt  *time.Time
 	ChannelThumbnailUpdatedAt *time.Time
+	ChannelBannerUpdatedAt    *time.Time
 }
 
 func (s *ChannelSearch) OverrideScan(names []string, scanners []sql.Scanner) error {
@@ -34,6 +36,8 @@ func (s *ChannelSearch) OverrideScan(names []string, scanners []sql.Scanner) err
 			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.ChannelMetadataUpdatedAt}
 		case "ChannelThumbnailUpdatedAt":
 			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.ChannelThumbnailUpdatedAt}
+		case "ChannelBannerUpdatedAt":
+			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.ChannelBannerUpdatedAt}
 		}
 	}
 
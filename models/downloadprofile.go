@@ -0,0 +1,22 @@
+package models
+
+const (
+	// DownloadProfileVideo downloads the whole video and extracts the audio
+	// from it.
+	DownloadProfileVideo = "video"
+	// DownloadProfileAudio downloads only the best audio stream, for when the
+	// picture isn't wanted.
+	DownloadProfileAudio = "audio"
+)
+
+var DownloadProfiles = []string{DownloadProfileVideo, DownloadProfileAudio}
+
+func IsDownloadProfile(s string) bool {
+	for _, p := range DownloadProfiles {
+		if s == p {
+			return true
+		}
+	}
+
+	return false
+}
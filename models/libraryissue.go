@@ -0,0 +1,66 @@
+package models
+
+import (
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+const (
+	// LibraryIssueMissing is a file that the database refers to but that
+	// isn't on disk.
+	LibraryIssueMissing = "missing"
+	// LibraryIssueCorrupt is a file that's on disk but that ffprobe can't
+	// read, or that has no audio or video in it.
+	LibraryIssueCorrupt = "corrupt"
+	// LibraryIssueOrphaned is a file on disk that the database doesn't refer
+	// to.
+	LibraryIssueOrphaned = "orphaned"
+)
+
+var LibraryIssueKinds = []string{LibraryIssueMissing, LibraryIssueCorrupt, LibraryIssueOrphaned}
+
+var (
+	LibraryVerificationTable *sqlbuilderutil.Table
+	LibraryIssueTable        *sqlbuilderutil.Table
+)
+
+func init() {
+	LibraryVerificationTable = sqlbuilderutil.MustMakeTable(LibraryVerification{})
+	LibraryIssueTable = sqlbuilderutil.MustMakeTable(LibraryIssue{})
+}
+
+// LibraryVerification is one run of library_verify.
+type LibraryVerification struct {
+	ID           int `sql:",table:library_verifications"`
+	CreatedAt    time.Time
+	FilesChecked int
+	FinishedAt   time.Time
+}
+
+// LibraryIssue is something wrong that a library_verify run found with
+// File in the Directory data directory. QueueName and Payload are the job
+// that makes the file again; they're empty for orphaned files.
+type LibraryIssue struct {
+	ID              int `sql:",table:library_issues"`
+	CreatedAt       time.Time
+	VerificationID  int
+	Kind            string
+	Directory       string
+	File            string
+	VideoExternalID string
+	Detail          string
+	QueueName       string
+	Payload         string
+	RequeuedAt      *time.Time
+}
+
+func (i LibraryIssue) Path() string {
+	return i.Directory + "/" + i.File
+}
+
+// CanRequeue reports whether there's a job to fix the issue that hasn't
+// been queued yet.
+func (i LibraryIssue) CanRequeue() bool {
+	return i.QueueName != "" && i.RequeuedAt == nil
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	MediaFileTable *sqlbuilderutil.Table
+)
+
+func init() {
+	MediaFileTable = sqlbuilderutil.MustMakeTable(MediaFile{})
+}
+
+// MediaFile is what ffprobe found in one of a video's files, which is File
+// in the Directory data directory. Size is in bytes and BitRate is in bits
+// per second.
+type MediaFile struct {
+	ID              int `sql:",table:media_files"`
+	CreatedAt       time.Time
+	VideoID         int
+	VideoExternalID string
+	Directory       string
+	File            string
+	Format          string
+	Size            int64
+	DurationMS      int `sql:"duration_ms"`
+	BitRate         int
+	VideoCodec      string
+	VideoProfile    string
+	VideoLevel      int
+	Width           int
+	Height          int
+	AudioCodec      string
+	AudioProfile    string
+	SampleRate      int
+	Channels        int
+	ProbedAt        time.Time
+}
+
+func (f MediaFile) Path() string {
+	return f.Directory + "/" + f.File
+}
+
+func (f MediaFile) Duration() string {
+	return formatTimestamp(f.DurationMS)
+}
+
+func (f MediaFile) SizeLabel() string {
+	return formatSize(f.Size)
+}
+
+// Resolution is the video's width and height, or nothing for audio.
+func (f MediaFile) Resolution() string {
+	if f.Width == 0 || f.Height == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf("%dx%d", f.Width, f.Height)
+}
+
+func (f MediaFile) BitRateLabel() string {
+	return fmt.Sprintf("%d kb/s", f.BitRate/1000)
+}
+
+// MediaFileUsage is how much space the probed files in one data directory
+// take up.
+type MediaFileUsage struct {
+	Directory string
+	Files     int
+	Size      int64
+}
+
+func (u MediaFileUsage) SizeLabel() string {
+	return formatSize(u.Size)
+}
+
+// formatSize formats a number of bytes in the largest unit that keeps it
+// at or above one.
+func formatSize(n int64) string {
+	if n < 1024 {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	f := float64(n) / 1024
+	for _, unit := range []string{"KB", "MB", "GB"} {
+		if f < 1024 {
+			return fmt.Sprintf("%.1f %s", f, unit)
+		}
+		f /= 1024
+	}
+
+	return fmt.Sprintf("%.1f TB", f)
+}
@@ -24,4 +24,7 @@ type Playlist struct {
 
 	MetadataUpdatedAt  *time.Time
 	ThumbnailUpdatedAt *time.Time
+
+	// DownloadProfile overrides the library default when it isn't empty.
+	DownloadProfile string
 }
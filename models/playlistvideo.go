@@ -22,4 +22,6 @@ type PlaylistVideo struct {
 	VideoID            *int
 	VideoExternalID    string
 	Position           int
+	RemovedAt          *time.Time
+	SetVideoID         string
 }
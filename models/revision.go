@@ -0,0 +1,34 @@
+package models
+
+import (
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	RevisionTable *sqlbuilderutil.Table
+)
+
+func init() {
+	RevisionTable = sqlbuilderutil.MustMakeTable(Revision{})
+}
+
+const (
+	RevisionObjectChannel  = "channel"
+	RevisionObjectPlaylist = "playlist"
+	RevisionObjectVideo    = "video"
+)
+
+// Revision records one field of a channel, playlist, or video changing when
+// its metadata was refreshed. JobID is the job that did the refresh.
+type Revision struct {
+	ID               int `sql:",table:revisions"`
+	CreatedAt        time.Time
+	JobID            *int
+	ObjectType       string
+	ObjectExternalID string
+	Field            string
+	OldValue         string
+	NewValue         string
+}
@@ -3,6 +3,7 @@ package models
 import (
 	"time"
 
+	"fknsrs.biz/p/ytmusic/internal/ffmpeg"
 	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
 )
 
@@ -25,10 +26,37 @@ type Video struct {
 	PublishDate       *time.Time
 	UploadDate        *time.Time
 
+	Availability       string
+	AvailabilityReason string
+
 	MetadataUpdatedAt  *time.Time
 	ThumbnailUpdatedAt *time.Time
 	DownloadedAt       *time.Time
-	Transcoded360At    *time.Time `sql:"transcoded_360_at"`
-	Transcoded720At    *time.Time `sql:"transcoded_720_at"`
 	AudioExtractedAt   *time.Time
+	SubtitlesUpdatedAt *time.Time
+	AudioTaggedAt      *time.Time
+
+	LoudnessIntegrated *float64
+	LoudnessTruePeak   *float64
+	LoudnessRange      *float64
+	LoudnessMeasuredAt *time.Time
+
+	WaveformUpdatedAt *time.Time
+	HLSPackagedAt     *time.Time `sql:"hls_packaged_at"`
+	StoryboardMadeAt  *time.Time
+}
+
+// Loudness is what was measured of the video's audio, or nil if it hasn't
+// been measured yet.
+func (v Video) Loudness() *ffmpeg.Loudness {
+	if v.LoudnessIntegrated == nil || v.LoudnessTruePeak == nil {
+		return nil
+	}
+
+	l := ffmpeg.Loudness{Integrated: *v.LoudnessIntegrated, TruePeak: *v.LoudnessTruePeak}
+	if v.LoudnessRange != nil {
+		l.Range = *v.LoudnessRange
+	}
+
+	return &l
 }
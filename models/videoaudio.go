@@ -0,0 +1,37 @@
+package models
+
+import (
+	"path/filepath"
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/audioprofile"
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	VideoAudioTable *sqlbuilderutil.Table
+)
+
+func init() {
+	VideoAudioTable = sqlbuilderutil.MustMakeTable(VideoAudio{})
+}
+
+// VideoAudio is a video's audio encoded with one of the configured audio
+// profiles. File is relative to the audio data directory.
+type VideoAudio struct {
+	ID              int `sql:",table:video_audio"`
+	CreatedAt       time.Time
+	VideoID         int
+	VideoExternalID string
+	Profile         string
+	File            string
+	ExtractedAt     time.Time
+}
+
+func (a VideoAudio) Extension() string {
+	return filepath.Ext(a.File)
+}
+
+func (a VideoAudio) MimeType() string {
+	return audioprofile.MimeType(a.Extension())
+}
@@ -0,0 +1,92 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	VideoChapterTable *sqlbuilderutil.Table
+)
+
+func init() {
+	VideoChapterTable = sqlbuilderutil.MustMakeTable(VideoChapter{})
+}
+
+const (
+	VideoChapterSourceYouTube     = "youtube"
+	VideoChapterSourceDescription = "description"
+)
+
+// VideoChapter is one section of a video. EndMS is nil for the last chapter,
+// which runs until the end of the video. AudioSplitAt is set once the
+// chapter has been cut out of the video's audio into AudioFile, which is in
+// the chapters data directory.
+type VideoChapter struct {
+	ID              int `sql:",table:video_chapters"`
+	CreatedAt       time.Time
+	VideoID         int
+	VideoExternalID string
+	Position        int
+	Title           string
+	StartMS         int  `sql:"start_ms"`
+	EndMS           *int `sql:"end_ms"`
+	Source          string
+	AudioSplitAt    *time.Time
+	AudioFile       string
+}
+
+// SplitFile is the name for the chapter's file when it's cut from audio
+// with the given extension.
+func (c VideoChapter) SplitFile(extension string) string {
+	return fmt.Sprintf("%s.%02d%s", c.VideoExternalID, c.Position+1, extension)
+}
+
+// FitChapters drops the chapters that start at or after length, and lets
+// any that would run past it run to the end instead. A tracklist in a
+// description is written by hand, so it can go on past the end of the video.
+// If length isn't known, chapters are returned as they are.
+func FitChapters(chapters []VideoChapter, length time.Duration) []VideoChapter {
+	if length <= 0 {
+		return chapters
+	}
+
+	lengthMS := int(length / time.Millisecond)
+
+	var fitted []VideoChapter
+	for _, c := range chapters {
+		if c.StartMS >= lengthMS {
+			continue
+		}
+
+		if c.EndMS != nil && *c.EndMS > lengthMS {
+			c.EndMS = nil
+		}
+
+		fitted = append(fitted, c)
+	}
+
+	return fitted
+}
+
+func (c VideoChapter) StartSeconds() int {
+	return c.StartMS / 1000
+}
+
+func (c VideoChapter) Timestamp() string {
+	return formatTimestamp(c.StartMS)
+}
+
+// formatTimestamp formats a position in a video like YouTube does: m:ss, or
+// h:mm:ss for long videos.
+func formatTimestamp(ms int) string {
+	s := ms / 1000
+
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
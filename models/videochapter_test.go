@@ -0,0 +1,34 @@
+package models
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"fknsrs.biz/p/ytmusic/internal/ptr"
+)
+
+func TestFitChapters(t *testing.T) {
+	a := assert.New(t)
+
+	chapters := []VideoChapter{
+		{Position: 0, StartMS: 0, EndMS: ptr.Int(60000)},
+		{Position: 1, StartMS: 60000, EndMS: ptr.Int(180000)},
+		{Position: 2, StartMS: 180000, EndMS: ptr.Int(240000)},
+		{Position: 3, StartMS: 240000},
+	}
+
+	// the tracklist runs past the end of a 2:30 video
+	a.Equal([]VideoChapter{
+		{Position: 0, StartMS: 0, EndMS: ptr.Int(60000)},
+		{Position: 1, StartMS: 60000},
+	}, FitChapters(chapters, 150*time.Second))
+
+	// a chapter can't start right at the end
+	a.Len(FitChapters(chapters, 180*time.Second), 2)
+
+	a.Equal(chapters, FitChapters(chapters, 5*time.Minute))
+	a.Equal(chapters, FitChapters(chapters, 0))
+	a.Nil(FitChapters(chapters[1:], 30*time.Second))
+}
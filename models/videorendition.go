@@ -0,0 +1,27 @@
+package models
+
+import (
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	VideoRenditionTable *sqlbuilderutil.Table
+)
+
+func init() {
+	VideoRenditionTable = sqlbuilderutil.MustMakeTable(VideoRendition{})
+}
+
+// VideoRendition is a smaller copy of a video transcoded for one of the
+// configured renditions. File is relative to the videos data directory.
+type VideoRendition struct {
+	ID              int `sql:",table:video_renditions"`
+	CreatedAt       time.Time
+	VideoID         int
+	VideoExternalID string
+	Name            string
+	File            string
+	TranscodedAt    time.Time
+}
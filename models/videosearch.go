@@ -28,12 +28,29 @@ type VideoSearch struct {
 	VideoExternalID           string
 	VideoTitle                string
 	VideoDescription          string
+	VideoAvailability         string
+	VideoAvailabilityReason   string
 	VideoMetadataUpdatedAt    *time.Time
 	VideoThumbnailUpdatedAt   *time.Time
 	VideoDownloadedAt         *time.Time
-	VideoTranscoded360At      *time.Time `sql:"video_transcoded_360_at"`
-	VideoTranscoded720At      *time.Time `sql:"video_transcoded_720_at"`
 	VideoAudioExtractedAt     *time.Time
+	VideoDurationMS           int `sql:"video_duration_ms"`
+	VideoSize                 int64
+}
+
+// VideoDuration is the length of the video's longest file, as probed. It's
+// empty until something has been probed.
+func (s VideoSearch) VideoDuration() string {
+	if s.VideoDurationMS == 0 {
+		return ""
+	}
+
+	return formatTimestamp(s.VideoDurationMS)
+}
+
+// VideoSizeLabel is how much space all of the video's probed files take up.
+func (s VideoSearch) VideoSizeLabel() string {
+	return formatSize(s.VideoSize)
 }
 
 func (s *VideoSearch) OverrideScan(names []string, scanners []sql.Scanner) error {
@@ -53,10 +70,6 @@ func (s *VideoSearch) OverrideScan(names []string, scanners []sql.Scanner) error
 			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.VideoThumbnailUpdatedAt}
 		case "VideoDownloadedAt":
 			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.VideoDownloadedAt}
-		case "VideoTranscoded360At":
-			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.VideoTranscoded360At}
-		case "VideoTranscoded720At":
-			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.VideoTranscoded720At}
 		case "VideoAudioExtractedAt":
 			scanners[i] = &sqltypes.TimePointerScanner{Value: &s.VideoAudioExtractedAt}
 		}
@@ -0,0 +1,28 @@
+package models
+
+import (
+	"time"
+
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	VideoSubtitleTable *sqlbuilderutil.Table
+)
+
+func init() {
+	VideoSubtitleTable = sqlbuilderutil.MustMakeTable(VideoSubtitle{})
+}
+
+// VideoSubtitle is one caption track for a video. Automatic is set for
+// YouTube's speech recognition captions, as opposed to ones the uploader
+// provided.
+type VideoSubtitle struct {
+	ID              int `sql:",table:video_subtitles"`
+	CreatedAt       time.Time
+	VideoID         int
+	VideoExternalID string
+	Language        string
+	Name            string
+	Automatic       bool
+}
@@ -0,0 +1,25 @@
+package models
+
+import (
+	"fknsrs.biz/p/ytmusic/internal/sqlbuilderutil"
+)
+
+var (
+	VideoSubtitleCueTable *sqlbuilderutil.Table
+)
+
+func init() {
+	VideoSubtitleCueTable = sqlbuilderutil.MustMakeTable(VideoSubtitleCue{})
+}
+
+// VideoSubtitleCue is one timed piece of text from a caption track. Cues are
+// replaced wholesale whenever their track is downloaded again.
+type VideoSubtitleCue struct {
+	ID              int `sql:",table:video_subtitle_cues"`
+	VideoSubtitleID int
+	VideoExternalID string
+	Language        string
+	StartMS         int `sql:"start_ms"`
+	EndMS           int `sql:"end_ms"`
+	Text            string
+}
@@ -0,0 +1,10 @@
+-- record why a video can't be fetched instead of retrying it forever
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql
+
+begin;
+
+alter table videos add column availability text not null default 'available';
+alter table videos add column availability_reason text not null default '';
+
+commit;
@@ -0,0 +1,9 @@
+-- remember the @handle a channel was added by, or the one its page lists
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql
+
+begin;
+
+alter table channels add column handle text not null default '';
+
+commit;
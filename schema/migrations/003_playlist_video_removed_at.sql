@@ -0,0 +1,9 @@
+-- keep entries for videos that have left a playlist instead of deleting them
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql
+
+begin;
+
+alter table playlist_videos add column removed_at timestamp;
+
+commit;
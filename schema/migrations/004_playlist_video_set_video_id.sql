@@ -0,0 +1,10 @@
+-- remember YouTube's own id for each playlist entry, so duplicates of the same
+-- video can be told apart
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql
+
+begin;
+
+alter table playlist_videos add column set_video_id text not null default '';
+
+commit;
@@ -0,0 +1,21 @@
+-- keep a history of titles, descriptions and handles as refreshes change them
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql,
+-- which also fixes the search triggers so that those changes can be saved
+
+begin;
+
+create table revisions (
+  id                 integer not null primary key,
+  created_at         timestamp not null,
+  job_id             integer,
+  object_type        text not null,
+  object_external_id text not null,
+  field              text not null,
+  old_value          text not null,
+  new_value          text not null
+);
+
+create index revisions__object on revisions (object_type, object_external_id, created_at);
+
+commit;
@@ -0,0 +1,10 @@
+-- note when a channel's banner was downloaded; its avatar uses the existing
+-- thumbnail_updated_at
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql
+
+begin;
+
+alter table channels add column banner_updated_at timestamp;
+
+commit;
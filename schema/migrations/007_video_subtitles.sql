@@ -0,0 +1,18 @@
+-- keep track of which caption tracks have been downloaded for each video
+
+begin;
+
+alter table videos add column subtitles_updated_at timestamp;
+
+create table video_subtitles (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  language          text not null,
+  name              text not null,
+  automatic         boolean not null,
+  unique (video_external_id, language)
+);
+
+commit;
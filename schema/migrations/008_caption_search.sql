@@ -0,0 +1,21 @@
+-- split downloaded caption tracks into cues so they can be searched
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql,
+-- which adds the caption search index. tracks downloaded before this change
+-- are only indexed once video_download_subtitles runs for them again.
+
+begin;
+
+create table video_subtitle_cues (
+  id                integer not null primary key,
+  video_subtitle_id integer not null references video_subtitles (id),
+  video_external_id text not null,
+  language          text not null,
+  start_ms          integer not null,
+  end_ms            integer not null,
+  text              text not null
+);
+
+create index video_subtitle_cues__video_subtitle_id on video_subtitle_cues (video_subtitle_id);
+
+commit;
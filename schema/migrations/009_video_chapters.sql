@@ -0,0 +1,19 @@
+-- keep chapters for videos, so that full albums can be split into tracks
+
+begin;
+
+create table video_chapters (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  position          integer not null,
+  title             text not null,
+  start_ms          integer not null,
+  end_ms            integer,
+  source            text not null,
+  audio_split_at    timestamp,
+  unique (video_external_id, position)
+);
+
+commit;
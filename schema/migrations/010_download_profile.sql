@@ -0,0 +1,9 @@
+-- let channels and playlists choose whether their videos are downloaded in
+-- full or as audio only; an empty profile falls back to the library default
+
+begin;
+
+alter table channels add column download_profile text not null default '';
+alter table playlists add column download_profile text not null default '';
+
+commit;
@@ -0,0 +1,32 @@
+-- keep track of each audio file made for a video, one per audio profile.
+-- videos.audio_extracted_at stays, and means the default profile is ready.
+--
+-- audio extracted before this lived at audio/<video_external_id>.mp3, which is
+-- what the old fixed settings now called "mp3" made, so it's recorded under
+-- that profile. chapters split from it were mp3 too.
+
+begin;
+
+create table video_audio (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  profile           text not null,
+  file              text not null,
+  extracted_at      timestamp not null,
+  unique (video_external_id, profile)
+);
+
+insert into video_audio (created_at, video_id, video_external_id, profile, file, extracted_at)
+  select current_timestamp, id, external_id, 'mp3', external_id || '.mp3', audio_extracted_at
+  from videos
+  where audio_extracted_at is not null;
+
+alter table video_chapters add column audio_file text not null default '';
+
+update video_chapters
+  set audio_file = video_external_id || '.' || printf('%02d', position + 1) || '.mp3'
+  where audio_split_at is not null;
+
+commit;
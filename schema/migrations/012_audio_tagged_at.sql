@@ -0,0 +1,7 @@
+-- note when the tags and cover art in a video's audio files were last written
+
+begin;
+
+alter table videos add column audio_tagged_at timestamp;
+
+commit;
@@ -0,0 +1,11 @@
+-- loudness measured from each video's audio, for ReplayGain tags and the
+-- player. integrated is in LUFS, true peak in dBTP, and range in LU.
+
+begin;
+
+alter table videos add column loudness_integrated real;
+alter table videos add column loudness_true_peak real;
+alter table videos add column loudness_range real;
+alter table videos add column loudness_measured_at timestamp;
+
+commit;
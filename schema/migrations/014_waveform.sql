@@ -0,0 +1,7 @@
+-- note when a video's waveform peaks were last computed
+
+begin;
+
+alter table videos add column waveform_updated_at timestamp;
+
+commit;
@@ -0,0 +1,7 @@
+-- note when a video's transcodes were last packaged for HLS streaming
+
+begin;
+
+alter table videos add column hls_packaged_at timestamp;
+
+commit;
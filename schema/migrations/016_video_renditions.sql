@@ -0,0 +1,59 @@
+-- keep track of transcoded copies of videos in a table, one row for each
+-- configured rendition, instead of a column for each size.
+--
+-- copies made before this were scaled to 360 and 720 pixels wide, but
+-- renditions are scaled to a height, so the old copies aren't recorded.
+-- queue transcodes for the configured renditions with tools/jobs.sql. the
+-- old files stay at videos/<video_external_id>_360.mp4 and _720.mp4, where
+-- library_verify lists them as orphaned, and can be deleted. a rendition
+-- named "360" or "720" replaces them as it's made.
+--
+-- the views refer to the old columns, so they and the search indexes built on
+-- them go first, and searching finds nothing until they're back. afterwards,
+-- rebuild the views and search indexes with views-and-indexes.sql, which
+-- fills the indexes in again from the tables.
+
+begin;
+
+drop trigger if exists channels__update_search_before_insert;
+drop trigger if exists channels__update_search_on_insert;
+drop trigger if exists channels__update_search_before_update;
+drop trigger if exists channels__update_search_on_update;
+drop trigger if exists channels__update_search_on_delete;
+drop trigger if exists playlists__update_search_on_insert;
+drop trigger if exists playlists__update_search_before_update;
+drop trigger if exists playlists__update_search_on_update;
+drop trigger if exists playlists__update_search_on_delete;
+drop trigger if exists videos__update_search_on_insert;
+drop trigger if exists videos__update_search_before_update;
+drop trigger if exists videos__update_search_on_update;
+drop trigger if exists videos__update_search_on_delete;
+drop trigger if exists video_subtitle_cues__update_search_on_insert;
+drop trigger if exists video_subtitle_cues__update_search_on_delete;
+
+drop table if exists channel_search;
+drop table if exists playlist_search;
+drop table if exists video_search;
+drop table if exists caption_search;
+
+drop view if exists channel_search_view;
+drop view if exists playlist_search_view;
+drop view if exists video_search_view;
+drop view if exists video_in_playlist_view;
+drop view if exists caption_search_view;
+
+create table video_renditions (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  name              text not null,
+  file              text not null,
+  transcoded_at     timestamp not null,
+  unique (video_external_id, name)
+);
+
+alter table videos drop column transcoded_360_at;
+alter table videos drop column transcoded_720_at;
+
+commit;
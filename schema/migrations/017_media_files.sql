@@ -0,0 +1,33 @@
+-- record what ffprobe finds in each file made for a video: codecs, bit rate,
+-- resolution, duration, sample rate and size. the search views sum these up
+-- per video.
+--
+-- afterwards, rebuild the views and search indexes with views-and-indexes.sql,
+-- and queue video_probe jobs (see tools/jobs.sql) to fill the table in.
+
+begin;
+
+create table media_files (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  directory         text not null,
+  file              text not null,
+  format            text not null,
+  size              integer not null,
+  duration_ms       integer not null,
+  bit_rate          integer not null,
+  video_codec       text not null,
+  width             integer not null,
+  height            integer not null,
+  audio_codec       text not null,
+  sample_rate       integer not null,
+  channels          integer not null,
+  probed_at         timestamp not null,
+  unique (directory, file)
+);
+
+create index media_files__video_external_id on media_files (video_external_id);
+
+commit;
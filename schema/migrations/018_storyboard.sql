@@ -0,0 +1,7 @@
+-- note when a video's seek preview sprites were last made
+
+begin;
+
+alter table videos add column storyboard_made_at timestamp;
+
+commit;
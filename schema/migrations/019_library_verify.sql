@@ -0,0 +1,29 @@
+-- record what library_verify finds: files that are missing, that ffprobe
+-- can't read, or that nothing in the database refers to
+
+begin;
+
+create table library_verifications (
+  id            integer not null primary key,
+  created_at    timestamp not null,
+  files_checked integer not null,
+  finished_at   timestamp not null
+);
+
+create table library_issues (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  verification_id   integer not null references library_verifications (id),
+  kind              text not null,
+  directory         text not null,
+  file              text not null,
+  video_external_id text not null,
+  detail            text not null,
+  queue_name        text not null,
+  payload           text not null,
+  requeued_at       timestamp
+);
+
+create index library_issues__verification_id on library_issues (verification_id);
+
+commit;
@@ -0,0 +1,12 @@
+-- record the codec profiles and video level ffprobe finds, so the hls master
+-- playlist can say which codecs each rendition needs. files probed before
+-- this have them empty; video_package_hls probes its renditions again when
+-- it finds them that way.
+
+begin;
+
+alter table media_files add column video_profile text not null default '';
+alter table media_files add column video_level integer not null default 0;
+alter table media_files add column audio_profile text not null default '';
+
+commit;
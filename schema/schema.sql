@@ -27,10 +27,13 @@ create table channels (
   created_at           timestamp not null,
   external_id          text not null unique,
   title                text not null,
+  handle               text not null default '',
   metadata_updated_at  timestamp,
   thumbnail_updated_at timestamp,
   playlists_updated_at timestamp,
-  videos_updated_at    timestamp
+  videos_updated_at    timestamp,
+  banner_updated_at    timestamp,
+  download_profile     text not null default ''
 );
 
 create table playlists (
@@ -41,7 +44,8 @@ create table playlists (
   channel_external_id  text not null,
   title                text not null,
   metadata_updated_at  timestamp,
-  thumbnail_updated_at timestamp
+  thumbnail_updated_at timestamp,
+  download_profile     text not null default ''
 );
 
 create table videos (
@@ -54,12 +58,21 @@ create table videos (
   description          text not null,
   publish_date         timestamp,
   upload_date          timestamp,
+  availability         text not null default 'available',
+  availability_reason  text not null default '',
   metadata_updated_at  timestamp,
   downloaded_at        timestamp,
   thumbnail_updated_at timestamp,
-  transcoded_360_at    timestamp,
-  transcoded_720_at    timestamp,
-  audio_extracted_at   timestamp
+  audio_extracted_at   timestamp,
+  subtitles_updated_at timestamp,
+  audio_tagged_at      timestamp,
+  loudness_integrated  real,
+  loudness_true_peak   real,
+  loudness_range       real,
+  loudness_measured_at timestamp,
+  waveform_updated_at  timestamp,
+  hls_packaged_at      timestamp,
+  storyboard_made_at   timestamp
 );
 
 create table playlist_videos (
@@ -69,9 +82,158 @@ create table playlist_videos (
   playlist_external_id text not null,
   video_id             integer references videos (id),
   video_external_id    text not null,
-  position             integer not null
+  position             integer not null,
+  removed_at           timestamp,
+  set_video_id         text not null default ''
+);
+
+-- caption tracks downloaded for a video, stored as
+-- subtitles/<video_external_id>.<language>.vtt
+
+create table video_subtitles (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  language          text not null,
+  name              text not null,
+  automatic         boolean not null,
+  unique (video_external_id, language)
+);
+
+-- one row per cue in a downloaded caption track, kept so that what's said in
+-- a video can be searched
+
+create table video_subtitle_cues (
+  id                integer not null primary key,
+  video_subtitle_id integer not null references video_subtitles (id),
+  video_external_id text not null,
+  language          text not null,
+  start_ms          integer not null,
+  end_ms            integer not null,
+  text              text not null
+);
+
+create index video_subtitle_cues__video_subtitle_id on video_subtitle_cues (video_subtitle_id);
+
+-- sections of a video, from YouTube or the description's tracklist. each can
+-- be split out of the video's default audio into chapters/<audio_file>, and
+-- end_ms is null for the last one, which runs to the end of the video
+
+create table video_chapters (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  position          integer not null,
+  title             text not null,
+  start_ms          integer not null,
+  end_ms            integer,
+  source            text not null,
+  audio_split_at    timestamp,
+  audio_file        text not null default '',
+  unique (video_external_id, position)
+);
+
+-- audio files made for a video, one for each audio profile, at audio/<file>.
+-- the "original" profile is audio that was downloaded without the video
+
+create table video_audio (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  profile           text not null,
+  file              text not null,
+  extracted_at      timestamp not null,
+  unique (video_external_id, profile)
+);
+
+-- smaller copies of a video for streaming, one for each rendition, at
+-- videos/<file>
+
+create table video_renditions (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  name              text not null,
+  file              text not null,
+  transcoded_at     timestamp not null,
+  unique (video_external_id, name)
+);
+
+-- what ffprobe found in each file made for a video, at <directory>/<file>.
+-- size is in bytes, and the numbers are zero when ffprobe couldn't tell
+
+create table media_files (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  video_id          integer not null references videos (id),
+  video_external_id text not null,
+  directory         text not null,
+  file              text not null,
+  format            text not null,
+  size              integer not null,
+  duration_ms       integer not null,
+  bit_rate          integer not null,
+  video_codec       text not null,
+  video_profile     text not null default '',
+  video_level       integer not null default 0,
+  width             integer not null,
+  height            integer not null,
+  audio_codec       text not null,
+  audio_profile     text not null default '',
+  sample_rate       integer not null,
+  channels          integer not null,
+  probed_at         timestamp not null,
+  unique (directory, file)
+);
+
+create index media_files__video_external_id on media_files (video_external_id);
+
+-- each run of library_verify, and the problems the latest one found with the
+-- files in the data directory. kind is missing, corrupt or orphaned, and
+-- queue_name and payload are the job that makes the file again, if any.
+
+create table library_verifications (
+  id            integer not null primary key,
+  created_at    timestamp not null,
+  files_checked integer not null,
+  finished_at   timestamp not null
 );
 
+create table library_issues (
+  id                integer not null primary key,
+  created_at        timestamp not null,
+  verification_id   integer not null references library_verifications (id),
+  kind              text not null,
+  directory         text not null,
+  file              text not null,
+  video_external_id text not null,
+  detail            text not null,
+  queue_name        text not null,
+  payload           text not null,
+  requeued_at       timestamp
+);
+
+create index library_issues__verification_id on library_issues (verification_id);
+
+-- old values of metadata that a refresh changed
+
+create table revisions (
+  id                 integer not null primary key,
+  created_at         timestamp not null,
+  job_id             integer,
+  object_type        text not null,
+  object_external_id text not null,
+  field              text not null,
+  old_value          text not null,
+  new_value          text not null
+);
+
+create index revisions__object on revisions (object_type, object_external_id, created_at);
+
 -- copy ids to remote objects on insert
 
 create trigger channels__propagate_id_after_insert after insert on channels
@@ -97,8 +259,10 @@ create view channel_search_view as select
   c.created_at as channel_created_at,
   c.external_id as channel_external_id,
   c.title as channel_title,
+  c.handle as channel_handle,
   c.metadata_updated_at as channel_metadata_updated_at,
-  c.thumbnail_updated_at as channel_thumbnail_updated_at
+  c.thumbnail_updated_at as channel_thumbnail_updated_at,
+  c.banner_updated_at as channel_banner_updated_at
 from channels c;
 
 create view playlist_search_view as select
@@ -130,12 +294,14 @@ create view video_search_view as select
   v.external_id as video_external_id,
   v.title as video_title,
   v.description as video_description,
+  v.availability as video_availability,
+  v.availability_reason as video_availability_reason,
   v.metadata_updated_at as video_metadata_updated_at,
   v.thumbnail_updated_at as video_thumbnail_updated_at,
   v.downloaded_at as video_downloaded_at,
-  v.transcoded_360_at as video_transcoded_360_at,
-  v.transcoded_720_at as video_transcoded_720_at,
-  v.audio_extracted_at as video_audio_extracted_at
+  v.audio_extracted_at as video_audio_extracted_at,
+  (select coalesce(max(m.duration_ms), 0) from media_files m where m.video_external_id = v.external_id) as video_duration_ms,
+  (select coalesce(sum(m.size), 0) from media_files m where m.video_external_id = v.external_id) as video_size
 from videos v
 left join channels c
   on c.id = v.channel_id or c.external_id = v.channel_external_id;
@@ -156,17 +322,21 @@ create view video_in_playlist_view as select
   pv.id as playlist_video_id,
   pv.created_at as playlist_video_created_at,
   pv.position as playlist_video_position,
+  pv.removed_at as playlist_video_removed_at,
+  pv.set_video_id as playlist_video_set_video_id,
   v.id as video_id,
   v.created_at as video_created_at,
   coalesce(v.external_id, pv.video_external_id) as video_external_id,
   coalesce(v.title, '') as video_title,
   coalesce(v.description, '') as video_description,
+  coalesce(v.availability, '') as video_availability,
+  coalesce(v.availability_reason, '') as video_availability_reason,
   v.metadata_updated_at as video_metadata_updated_at,
   v.thumbnail_updated_at as video_thumbnail_updated_at,
   v.downloaded_at as video_downloaded_at,
-  v.transcoded_360_at as video_transcoded_360_at,
-  v.transcoded_720_at as video_transcoded_720_at,
-  v.audio_extracted_at as video_audio_extracted_at
+  v.audio_extracted_at as video_audio_extracted_at,
+  (select coalesce(max(m.duration_ms), 0) from media_files m where m.video_external_id = coalesce(v.external_id, pv.video_external_id)) as video_duration_ms,
+  (select coalesce(sum(m.size), 0) from media_files m where m.video_external_id = coalesce(v.external_id, pv.video_external_id)) as video_size
 from playlist_videos pv
 left join playlists p
   on p.id = pv.playlist_id or p.external_id = pv.playlist_external_id
@@ -175,13 +345,30 @@ left join videos v
 left join channels c
   on c.id = v.channel_id or c.external_id = v.channel_external_id;
 
+create view caption_search_view as select
+  v.id as video_id,
+  v.external_id as video_external_id,
+  v.title as video_title,
+  coalesce(c.title, '') as channel_title,
+  cu.id as cue_id,
+  cu.language as cue_language,
+  cu.start_ms as cue_start_ms,
+  cu.end_ms as cue_end_ms,
+  cu.text as cue_text
+from video_subtitle_cues cu
+join videos v
+  on v.external_id = cu.video_external_id
+left join channels c
+  on c.id = v.channel_id or c.external_id = v.channel_external_id;
+
 -- indexes for search pages
 
 create virtual table channel_search using fts5(
   content='channel_search_view', content_rowid='channel_id',
   channel_id unindexed, channel_created_at unindexed, channel_external_id,
-  channel_title,
-  channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed
+  channel_title, channel_handle,
+  channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed,
+  channel_banner_updated_at unindexed
 );
 
 create virtual table playlist_search using fts5(
@@ -201,38 +388,123 @@ create virtual table video_search using fts5(
   channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed,
   video_id unindexed, video_created_at unindexed, video_external_id,
   video_title, video_description,
-  video_metadata_updated_at unindexed, video_thumbnail_updated_at unindexed, video_downloaded_at unindexed, video_transcoded_360_at unindexed, video_transcoded_720_at unindexed, video_audio_extracted_at unindexed
+  video_availability unindexed, video_availability_reason unindexed,
+  video_metadata_updated_at unindexed, video_thumbnail_updated_at unindexed, video_downloaded_at unindexed, video_audio_extracted_at unindexed,
+  video_duration_ms unindexed, video_size unindexed
+);
+
+create virtual table caption_search using fts5(
+  content='caption_search_view', content_rowid='cue_id',
+  video_id unindexed, video_external_id unindexed, video_title unindexed,
+  channel_title unindexed,
+  cue_id unindexed, cue_language unindexed, cue_start_ms unindexed, cue_end_ms unindexed,
+  cue_text
 );
 
 -- keep the search indexes updated when the source data changes
+--
+-- the search tables index the views, so an index entry can only be removed by
+-- handing back exactly what was indexed. that means taking entries out before
+-- a change, while the views still show the old values, and putting them back
+-- afterwards.
+
+create trigger channels__update_search_before_insert before insert on channels
+begin
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_external_id = new.external_id;
+
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_external_id = new.external_id;
+end;
 
 create trigger channels__update_search_on_insert after insert on channels
 begin
-  insert into channel_search (rowid, channel_external_id, channel_title)
+  insert into channel_search (rowid, channel_external_id, channel_title, channel_handle)
     select
       channel_id,
-      channel_external_id, channel_title
+      channel_external_id, channel_title, channel_handle
     from channel_search_view
     where channel_id = new.id;
 
-  update playlist_search set channel_title = new.title where channel_external_id = new.external_id;
-  update video_search set channel_title = new.title where channel_external_id = new.external_id;
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = new.id;
+
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = new.id;
 end;
 
-create trigger channels__update_search_on_update after update of external_id, title on channels
+create trigger channels__update_search_before_update before update of external_id, title, handle on channels
 begin
-  update channel_search
-    set
-      channel_external_id = new.external_id, channel_title = new.title
-    where rowid = new.id;
+  insert into channel_search (channel_search, rowid, channel_external_id, channel_title, channel_handle)
+    select
+      'delete', channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = old.id;
 
-  update playlist_search set channel_title = new.title where channel_external_id = new.external_id;
-  update video_search set channel_title = new.title where channel_external_id = new.external_id;
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = old.id;
+
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = old.id;
 end;
 
-create trigger channels__update_search_on_delete after delete on playlists
+create trigger channels__update_search_on_update after update of external_id, title, handle on channels
 begin
-  insert into channel_search (channel_search, rowid) values ('delete', old.id);
+  insert into channel_search (rowid, channel_external_id, channel_title, channel_handle)
+    select
+      channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = new.id;
+
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = new.id;
+
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = new.id;
+end;
+
+create trigger channels__update_search_on_delete before delete on channels
+begin
+  insert into channel_search (channel_search, rowid, channel_external_id, channel_title, channel_handle)
+    select
+      'delete', channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = old.id;
 end;
 
 create trigger playlists__update_search_on_insert after insert on playlists
@@ -245,18 +517,34 @@ begin
     where playlist_id = new.id;
 end;
 
-create trigger playlists__update_search_on_update after update of external_id, title, description on playlists
+create trigger playlists__update_search_before_update before update of external_id, title, channel_external_id on playlists
 begin
-  update playlist_search
-    set
-      playlist_external_id = new.external_id, playlist_title = new.title,
-      channel_external_id = new.channel_external_id
-    where rowid = new.id;
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = old.id;
 end;
 
-create trigger playlists__update_search_on_delete after delete on playlists
+create trigger playlists__update_search_on_update after update of external_id, title, channel_external_id on playlists
 begin
-  insert into playlist_search (playlist_search, rowid) values ('delete', old.id);
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = new.id;
+end;
+
+create trigger playlists__update_search_on_delete before delete on playlists
+begin
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = old.id;
 end;
 
 create trigger videos__update_search_on_insert after insert on videos
@@ -269,16 +557,45 @@ begin
     where video_id = new.id;
 end;
 
-create trigger videos__update_search_on_update after update of external_id, title, description on videos
+create trigger videos__update_search_before_update before update of external_id, title, description, channel_external_id on videos
+begin
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = old.id;
+end;
+
+create trigger videos__update_search_on_update after update of external_id, title, description, channel_external_id on videos
+begin
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = new.id;
+end;
+
+create trigger videos__update_search_on_delete before delete on videos
+begin
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = old.id;
+end;
+
+-- only the cue text is indexed, and cues are never updated, so they can be
+-- indexed straight from the table
+
+create trigger video_subtitle_cues__update_search_on_insert after insert on video_subtitle_cues
 begin
-  update video_search
-    set
-      video_external_id = new.external_id, video_title = new.title, video_description = new.description,
-      channel_external_id = new.channel_external_id
-    where rowid = new.id;
+  insert into caption_search (rowid, cue_text) values (new.id, new.text);
 end;
 
-create trigger videos__update_search_on_delete after delete on videos
+create trigger video_subtitle_cues__update_search_on_delete before delete on video_subtitle_cues
 begin
-  insert into video_search (video_search, rowid) values ('delete', old.id);
+  insert into caption_search (caption_search, rowid, cue_text) values ('delete', old.id, old.text);
 end;
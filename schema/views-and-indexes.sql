@@ -1,23 +1,31 @@
 begin;
 
+drop trigger if exists channels__update_search_before_insert;
 drop trigger if exists channels__update_search_on_insert;
+drop trigger if exists channels__update_search_before_update;
 drop trigger if exists channels__update_search_on_update;
 drop trigger if exists channels__update_search_on_delete;
 drop trigger if exists playlists__update_search_on_insert;
+drop trigger if exists playlists__update_search_before_update;
 drop trigger if exists playlists__update_search_on_update;
 drop trigger if exists playlists__update_search_on_delete;
 drop trigger if exists videos__update_search_on_insert;
+drop trigger if exists videos__update_search_before_update;
 drop trigger if exists videos__update_search_on_update;
 drop trigger if exists videos__update_search_on_delete;
+drop trigger if exists video_subtitle_cues__update_search_on_insert;
+drop trigger if exists video_subtitle_cues__update_search_on_delete;
 
 drop table if exists channel_search;
 drop table if exists playlist_search;
 drop table if exists video_search;
+drop table if exists caption_search;
 
 drop view if exists channel_search_view;
 drop view if exists playlist_search_view;
 drop view if exists video_search_view;
 drop view if exists video_in_playlist_view;
+drop view if exists caption_search_view;
 
 -- views for search/list pages
 
@@ -26,8 +34,10 @@ create view channel_search_view as select
   c.created_at as channel_created_at,
   c.external_id as channel_external_id,
   c.title as channel_title,
+  c.handle as channel_handle,
   c.metadata_updated_at as channel_metadata_updated_at,
-  c.thumbnail_updated_at as channel_thumbnail_updated_at
+  c.thumbnail_updated_at as channel_thumbnail_updated_at,
+  c.banner_updated_at as channel_banner_updated_at
 from channels c;
 
 create view playlist_search_view as select
@@ -59,12 +69,14 @@ create view video_search_view as select
   v.external_id as video_external_id,
   v.title as video_title,
   v.description as video_description,
+  v.availability as video_availability,
+  v.availability_reason as video_availability_reason,
   v.metadata_updated_at as video_metadata_updated_at,
   v.thumbnail_updated_at as video_thumbnail_updated_at,
   v.downloaded_at as video_downloaded_at,
-  v.transcoded_360_at as video_transcoded_360_at,
-  v.transcoded_720_at as video_transcoded_720_at,
-  v.audio_extracted_at as video_audio_extracted_at
+  v.audio_extracted_at as video_audio_extracted_at,
+  (select coalesce(max(m.duration_ms), 0) from media_files m where m.video_external_id = v.external_id) as video_duration_ms,
+  (select coalesce(sum(m.size), 0) from media_files m where m.video_external_id = v.external_id) as video_size
 from videos v
 left join channels c
   on c.id = v.channel_id or c.external_id = v.channel_external_id;
@@ -85,17 +97,21 @@ create view video_in_playlist_view as select
   pv.id as playlist_video_id,
   pv.created_at as playlist_video_created_at,
   pv.position as playlist_video_position,
+  pv.removed_at as playlist_video_removed_at,
+  pv.set_video_id as playlist_video_set_video_id,
   v.id as video_id,
   v.created_at as video_created_at,
   coalesce(v.external_id, pv.video_external_id) as video_external_id,
   coalesce(v.title, '') as video_title,
   coalesce(v.description, '') as video_description,
+  coalesce(v.availability, '') as video_availability,
+  coalesce(v.availability_reason, '') as video_availability_reason,
   v.metadata_updated_at as video_metadata_updated_at,
   v.thumbnail_updated_at as video_thumbnail_updated_at,
   v.downloaded_at as video_downloaded_at,
-  v.transcoded_360_at as video_transcoded_360_at,
-  v.transcoded_720_at as video_transcoded_720_at,
-  v.audio_extracted_at as video_audio_extracted_at
+  v.audio_extracted_at as video_audio_extracted_at,
+  (select coalesce(max(m.duration_ms), 0) from media_files m where m.video_external_id = coalesce(v.external_id, pv.video_external_id)) as video_duration_ms,
+  (select coalesce(sum(m.size), 0) from media_files m where m.video_external_id = coalesce(v.external_id, pv.video_external_id)) as video_size
 from playlist_videos pv
 left join playlists p
   on p.id = pv.playlist_id or p.external_id = pv.playlist_external_id
@@ -104,13 +120,30 @@ left join videos v
 left join channels c
   on c.id = v.channel_id or c.external_id = v.channel_external_id;
 
+create view caption_search_view as select
+  v.id as video_id,
+  v.external_id as video_external_id,
+  v.title as video_title,
+  coalesce(c.title, '') as channel_title,
+  cu.id as cue_id,
+  cu.language as cue_language,
+  cu.start_ms as cue_start_ms,
+  cu.end_ms as cue_end_ms,
+  cu.text as cue_text
+from video_subtitle_cues cu
+join videos v
+  on v.external_id = cu.video_external_id
+left join channels c
+  on c.id = v.channel_id or c.external_id = v.channel_external_id;
+
 -- indexes for search pages
 
 create virtual table channel_search using fts5(
   content='channel_search_view', content_rowid='channel_id',
   channel_id unindexed, channel_created_at unindexed, channel_external_id,
-  channel_title,
-  channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed
+  channel_title, channel_handle,
+  channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed,
+  channel_banner_updated_at unindexed
 );
 
 create virtual table playlist_search using fts5(
@@ -130,38 +163,123 @@ create virtual table video_search using fts5(
   channel_metadata_updated_at unindexed, channel_thumbnail_updated_at unindexed,
   video_id unindexed, video_created_at unindexed, video_external_id,
   video_title, video_description,
-  video_metadata_updated_at unindexed, video_thumbnail_updated_at unindexed, video_downloaded_at unindexed, video_transcoded_360_at unindexed, video_transcoded_720_at unindexed, video_audio_extracted_at unindexed
+  video_availability unindexed, video_availability_reason unindexed,
+  video_metadata_updated_at unindexed, video_thumbnail_updated_at unindexed, video_downloaded_at unindexed, video_audio_extracted_at unindexed,
+  video_duration_ms unindexed, video_size unindexed
+);
+
+create virtual table caption_search using fts5(
+  content='caption_search_view', content_rowid='cue_id',
+  video_id unindexed, video_external_id unindexed, video_title unindexed,
+  channel_title unindexed,
+  cue_id unindexed, cue_language unindexed, cue_start_ms unindexed, cue_end_ms unindexed,
+  cue_text
 );
 
 -- keep the search indexes updated when the source data changes
+--
+-- the search tables index the views, so an index entry can only be removed by
+-- handing back exactly what was indexed. that means taking entries out before
+-- a change, while the views still show the old values, and putting them back
+-- afterwards.
+
+create trigger channels__update_search_before_insert before insert on channels
+begin
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_external_id = new.external_id;
+
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_external_id = new.external_id;
+end;
 
 create trigger channels__update_search_on_insert after insert on channels
 begin
-  insert into channel_search (rowid, channel_external_id, channel_title)
+  insert into channel_search (rowid, channel_external_id, channel_title, channel_handle)
     select
       channel_id,
-      channel_external_id, channel_title
+      channel_external_id, channel_title, channel_handle
     from channel_search_view
     where channel_id = new.id;
 
-  update playlist_search set channel_title = new.title where channel_external_id = new.external_id;
-  update video_search set channel_title = new.title where channel_external_id = new.external_id;
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = new.id;
+
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = new.id;
 end;
 
-create trigger channels__update_search_on_update after update of external_id, title on channels
+create trigger channels__update_search_before_update before update of external_id, title, handle on channels
 begin
-  update channel_search
-    set
-      channel_external_id = new.external_id, channel_title = new.title
-    where rowid = new.id;
+  insert into channel_search (channel_search, rowid, channel_external_id, channel_title, channel_handle)
+    select
+      'delete', channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = old.id;
+
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = old.id;
 
-  update playlist_search set channel_title = new.title where channel_external_id = new.external_id;
-  update video_search set channel_title = new.title where channel_external_id = new.external_id;
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = old.id;
 end;
 
-create trigger channels__update_search_on_delete after delete on playlists
+create trigger channels__update_search_on_update after update of external_id, title, handle on channels
 begin
-  insert into channel_search (channel_search, rowid) values ('delete', old.id);
+  insert into channel_search (rowid, channel_external_id, channel_title, channel_handle)
+    select
+      channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = new.id;
+
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where channel_id = new.id;
+
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where channel_id = new.id;
+end;
+
+create trigger channels__update_search_on_delete before delete on channels
+begin
+  insert into channel_search (channel_search, rowid, channel_external_id, channel_title, channel_handle)
+    select
+      'delete', channel_id,
+      channel_external_id, channel_title, channel_handle
+    from channel_search_view
+    where channel_id = old.id;
 end;
 
 create trigger playlists__update_search_on_insert after insert on playlists
@@ -174,18 +292,34 @@ begin
     where playlist_id = new.id;
 end;
 
-create trigger playlists__update_search_on_update after update of external_id, title, description on playlists
+create trigger playlists__update_search_before_update before update of external_id, title, channel_external_id on playlists
 begin
-  update playlist_search
-    set
-      playlist_external_id = new.external_id, playlist_title = new.title,
-      channel_external_id = new.channel_external_id
-    where rowid = new.id;
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = old.id;
 end;
 
-create trigger playlists__update_search_on_delete after delete on playlists
+create trigger playlists__update_search_on_update after update of external_id, title, channel_external_id on playlists
 begin
-  insert into playlist_search (playlist_search, rowid) values ('delete', old.id);
+  insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = new.id;
+end;
+
+create trigger playlists__update_search_on_delete before delete on playlists
+begin
+  insert into playlist_search (playlist_search, rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
+    select
+      'delete', playlist_id, playlist_external_id, playlist_title,
+      channel_external_id, channel_title
+    from playlist_search_view
+    where playlist_id = old.id;
 end;
 
 create trigger videos__update_search_on_insert after insert on videos
@@ -198,26 +332,55 @@ begin
     where video_id = new.id;
 end;
 
-create trigger videos__update_search_on_update after update of external_id, title, description on videos
+create trigger videos__update_search_before_update before update of external_id, title, description, channel_external_id on videos
 begin
-  update video_search
-    set
-      video_external_id = new.external_id, video_title = new.title, video_description = new.description,
-      channel_external_id = new.channel_external_id
-    where rowid = new.id;
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = old.id;
 end;
 
-create trigger videos__update_search_on_delete after delete on videos
+create trigger videos__update_search_on_update after update of external_id, title, description, channel_external_id on videos
 begin
-  insert into video_search (video_search, rowid) values ('delete', old.id);
+  insert into video_search (rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = new.id;
 end;
 
--- populate indexes and test queries
+create trigger videos__update_search_on_delete before delete on videos
+begin
+  insert into video_search (video_search, rowid, video_external_id, video_title, video_description, channel_external_id, channel_title)
+    select
+      'delete', video_id, video_external_id, video_title, video_description,
+      channel_external_id, channel_title
+    from video_search_view
+    where video_id = old.id;
+end;
+
+-- only the cue text is indexed, and cues are never updated, so they can be
+-- indexed straight from the table
 
-insert into channel_search (rowid, channel_external_id, channel_title)
+create trigger video_subtitle_cues__update_search_on_insert after insert on video_subtitle_cues
+begin
+  insert into caption_search (rowid, cue_text) values (new.id, new.text);
+end;
+
+create trigger video_subtitle_cues__update_search_on_delete before delete on video_subtitle_cues
+begin
+  insert into caption_search (caption_search, rowid, cue_text) values ('delete', old.id, old.text);
+end;
+
+-- populate indexes
+
+insert into channel_search (rowid, channel_external_id, channel_title, channel_handle)
   select
     channel_id,
-    channel_external_id, channel_title
+    channel_external_id, channel_title, channel_handle
   from channel_search_view;
 
 insert into playlist_search (rowid, playlist_external_id, playlist_title, channel_external_id, channel_title)
@@ -234,21 +397,9 @@ insert into video_search (rowid, video_external_id, video_title, video_descripti
     channel_external_id, channel_title
   from video_search_view;
 
-select 'channels';
-select
-  channel_id, channel_title
-from channel_search where channel_search match 'grymm';
-
-select 'videos';
-select
-  video_id, video_title,
-  channel_id, channel_title
-from video_search where video_search match 'grymm';
-
-select 'playlists';
-select
-  playlist_id, playlist_title,
-  channel_id, channel_title
-from playlist_search where playlist_search match 'grymm';
+insert into caption_search (rowid, cue_text)
+  select
+    cue_id, cue_text
+  from caption_search_view;
 
-rollback;
+commit;
@@ -66,6 +66,246 @@ behavior VideoInPlaylist
   end
 end
 
+-- waveforms are drawn from /videos/{id}/waveform, which has a min and max
+-- peak for each tenth of a second. startMs and endMs are for showing just a
+-- chapter.
+
+js
+  function waveformLoad(canvas, src, startMs, endMs) {
+    canvas.waveformSrc = src
+    canvas.waveformPeaks = null
+    waveformDraw(canvas, 0)
+
+    if (!src) {
+      return
+    }
+
+    fetch(src).then(function (res) {
+      if (!res.ok) {
+        throw new Error(res.statusText)
+      }
+
+      return res.json()
+    }).then(function (w) {
+      if (canvas.waveformSrc !== src) {
+        return
+      }
+
+      var perSecond = w.sample_rate / w.samples_per_pixel
+      var from = startMs ? Math.floor(startMs / 1000 * perSecond) : 0
+      var to = endMs ? Math.ceil(endMs / 1000 * perSecond) : w.length
+
+      canvas.waveformPeaks = w.data.slice(from * 2, to * 2)
+      waveformDraw(canvas, 0)
+    }).catch(function (err) {
+      console.warn('couldn\'t load waveform', src, err)
+    })
+  }
+
+  function waveformDraw(canvas, position) {
+    var ctx = canvas.getContext('2d')
+    var width = canvas.width, height = canvas.height, middle = height / 2
+
+    ctx.clearRect(0, 0, width, height)
+
+    var peaks = canvas.waveformPeaks
+    if (!peaks || !peaks.length) {
+      return
+    }
+
+    // quiet tracks are scaled up to fill the height
+    var scale = 1
+    for (var i = 0; i < peaks.length; i++) {
+      scale = Math.max(scale, Math.abs(peaks[i]))
+    }
+
+    var count = peaks.length / 2
+
+    for (var x = 0; x < width; x++) {
+      var from = Math.floor(x * count / width)
+      var to = Math.max(from + 1, Math.floor((x + 1) * count / width))
+
+      var min = 0, max = 0
+      for (var j = from; j < to && j < count; j++) {
+        min = Math.min(min, peaks[j * 2])
+        max = Math.max(max, peaks[j * 2 + 1])
+      }
+
+      var top = middle - max / scale * middle
+      var bottom = middle - min / scale * middle
+
+      ctx.fillStyle = x < position * width ? '#333' : '#bbb'
+      ctx.fillRect(x, top, 1, Math.max(1, bottom - top))
+    }
+  }
+end
+
+behavior Audio
+  on timeupdate
+    if the next .waveform exists
+      send progress(position: my currentTime / my duration) to the next .waveform
+    end
+  end
+end
+
+behavior Waveform
+  init
+    call waveformLoad(me, my dataset's src)
+  end
+
+  on load(src, startMs, endMs)
+    call waveformLoad(me, src, startMs, endMs)
+  end
+
+  on progress(position)
+    call waveformDraw(me, position)
+  end
+
+  on click
+    get the previous <audio/>
+    if its duration
+      set its currentTime to (event's offsetX / my offsetWidth) * its duration
+    end
+  end
+end
+
+-- browsers don't draw subtitles over audio, so the cues of the chosen track
+-- are shown in a line of text under it instead. tracks are picked by their
+-- place in the <audio/>, which is the order the <select/> lists them in.
+
+js
+  function subtitleShow(el, media, index) {
+    var cue = el.querySelector('.cue')
+    var tracks = media.textTracks
+
+    cue.textContent = ''
+
+    for (var i = 0; i < tracks.length; i++) {
+      tracks[i].oncuechange = null
+      tracks[i].mode = i === index ? 'hidden' : 'disabled'
+    }
+
+    if (index < 0 || index >= tracks.length) {
+      return
+    }
+
+    tracks[index].oncuechange = function () {
+      var lines = []
+      for (var j = 0; j < this.activeCues.length; j++) {
+        lines.push(this.activeCues[j].text.replace(/<[^>]*>/g, ''))
+      }
+      cue.textContent = lines.join('\n')
+    }
+  }
+end
+
+behavior SubtitleDisplay
+  init
+    get the first <select/> in me
+    call subtitleShow(me, the previous <audio/>, parseInt(its value))
+  end
+
+  on change
+    call subtitleShow(me, the previous <audio/>, parseInt(the event's target's value))
+  end
+end
+
+-- storyboards are a WebVTT thumbnails track, where each cue's text is a
+-- sprite sheet with the frame's place in it, like 000.jpg#xywh=0,0,160,90.
+-- the preview shows over the seek bar, which is along the bottom of the
+-- video's native controls.
+
+js
+  function storyboardParseTime(s) {
+    var parts = s.split(':'), seconds = 0
+    for (var i = 0; i < parts.length; i++) {
+      seconds = seconds * 60 + parseFloat(parts[i])
+    }
+    return seconds
+  }
+
+  function storyboardLoad(el, src) {
+    el.storyboardCues = []
+
+    fetch(src).then(function (res) {
+      if (!res.ok) {
+        throw new Error(res.statusText)
+      }
+
+      return res.text()
+    }).then(function (text) {
+      var base = new URL(src, window.location.href)
+      var lines = text.split(/\r?\n/)
+      var cues = []
+
+      for (var i = 0; i < lines.length - 1; i++) {
+        var times = lines[i].split(' --> ')
+        var m = times.length === 2 && /^(.*)#xywh=(\d+),(\d+),(\d+),(\d+)$/.exec(lines[i + 1])
+        if (!m) {
+          continue
+        }
+
+        cues.push({
+          from: storyboardParseTime(times[0]),
+          to: storyboardParseTime(times[1]),
+          url: new URL(m[1], base).href,
+          x: +m[2], y: +m[3], w: +m[4], h: +m[5]
+        })
+      }
+
+      el.storyboardCues = cues
+    }).catch(function (err) {
+      console.warn('couldn\'t load storyboard', src, err)
+    })
+  }
+
+  function storyboardShow(el, video, clientX, clientY) {
+    var rect = video.getBoundingClientRect()
+    var cues = el.storyboardCues || []
+
+    if (!video.duration || clientY < rect.bottom - 50) {
+      el.style.display = 'none'
+      return
+    }
+
+    var time = (clientX - rect.left) / rect.width * video.duration
+
+    var cue = null
+    for (var i = 0; i < cues.length; i++) {
+      if (cues[i].from <= time && time < cues[i].to) {
+        cue = cues[i]
+        break
+      }
+    }
+
+    if (!cue) {
+      el.style.display = 'none'
+      return
+    }
+
+    el.style.display = 'block'
+    el.style.width = cue.w + 'px'
+    el.style.height = cue.h + 'px'
+    el.style.backgroundImage = 'url("' + cue.url + '")'
+    el.style.backgroundPosition = '-' + cue.x + 'px -' + cue.y + 'px'
+    el.style.left = Math.max(0, Math.min(rect.width - cue.w, clientX - rect.left - cue.w / 2)) + 'px'
+  }
+end
+
+behavior Storyboard
+  init
+    call storyboardLoad(me, my dataset's src)
+  end
+
+  on mousemove from the previous <video/>
+    call storyboardShow(me, the previous <video/>, event's clientX, event's clientY)
+  end
+
+  on mouseleave from the previous <video/>
+    set my style's display to 'none'
+  end
+end
+
 behavior Player
   init
     set :current to null
@@ -79,7 +319,9 @@ behavior Player
       if item is not :current then
         take .current from .ready
         get the first .title then put item's innerText into it
-        get the first <audio/> then set its src to `/data/audio/${item's dataset's id}.mp3`
+        get the first <audio/> then set its src to item's dataset's src
+        get the first <audio/> then set its volume to c_volume(item's dataset's gain)
+        send load(src: item's dataset's waveform, startMs: item's dataset's startMs, endMs: item's dataset's endMs) to the first .waveform
         add .current to item
         set :current to item
       end
@@ -90,10 +332,21 @@ behavior Player
     else
       get the first title then put null into it
       get the first <audio/> then set its src to null
+      send load() to the first .waveform
       set :current to null
     end
   end
 
+  -- audio can't be played louder than full volume, so ReplayGain only ever
+  -- turns tracks down; most are louder than the reference level anyway
+  def c_volume(gain)
+    js(gain)
+      var db = parseFloat(gain)
+      return isNaN(db) ? 1 : Math.min(1, Math.pow(10, db / 20))
+    end
+    return it
+  end
+
   def c_pause()
     get the first <audio/> then call its pause()
   end
@@ -117,6 +370,11 @@ behavior Player
     call c_next()
   end
 
+  on timeupdate from <audio/>
+    get the event's target
+    send progress(position: its currentTime / its duration) to the first .waveform
+  end
+
   -- input events
 
   on click from .play
@@ -1,16 +1,33 @@
-update videos set thumbnail_updated_at = null, transcoded_360_at = null, transcoded_720_at = null, audio_extracted_at = null;
+update videos set thumbnail_updated_at = null, audio_extracted_at = null;
+delete from video_audio where profile != 'original';
+delete from video_renditions;
 
 insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
-  select current_timestamp, 'video_download', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is null;
+  select current_timestamp, 'video_download', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is null and availability = 'available';
 
 insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
   select current_timestamp, 'video_update_thumbnail', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null and thumbnail_updated_at is null;
 
 insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
-  select current_timestamp, 'video_transcode', external_id || '?size=360', current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null and transcoded_360_at is null;
+  select current_timestamp, 'video_transcode', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null;
 
 insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
-  select current_timestamp, 'video_transcode', external_id || '?size=720', current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null and transcoded_720_at is null;
+  select current_timestamp, 'video_extract_audio', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where (downloaded_at is not null or external_id in (select video_external_id from video_audio where profile = 'original')) and audio_extracted_at is null;
 
 insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
-  select current_timestamp, 'video_extract_audio', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null and audio_extracted_at is null;
+  select current_timestamp, 'video_retag', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where audio_extracted_at is not null and audio_tagged_at is null;
+
+insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
+  select current_timestamp, 'video_analyze_loudness', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where audio_extracted_at is not null and loudness_measured_at is null;
+
+insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
+  select current_timestamp, 'video_waveform', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where audio_extracted_at is not null and waveform_updated_at is null;
+
+insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
+  select current_timestamp, 'video_package_hls', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where external_id in (select video_external_id from video_renditions) and hls_packaged_at is null;
+
+insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
+  select current_timestamp, 'video_probe', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where (downloaded_at is not null or external_id in (select video_external_id from video_audio)) and external_id not in (select video_external_id from media_files);
+
+insert into jobs (created_at, queue_name, payload, run_after, failure_delay, attempts_remaining, error_messages, output_messages)
+  select current_timestamp, 'video_storyboard', external_id, current_timestamp, 5000000000, 5, json_array(), json_array() from videos where downloaded_at is not null and storyboard_made_at is null;